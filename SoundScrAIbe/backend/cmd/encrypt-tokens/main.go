@@ -0,0 +1,66 @@
+// Command encrypt-tokens brings stored Spotify tokens under the current
+// primary token encryption key: rows still holding plaintext tokens (from
+// before migration 000015) are encrypted in place, and rows wrapped by an
+// older key are re-wrapped. Run it after adding a new key to the front of
+// TOKEN_ENCRYPTION_KEYS; once it reports nothing left under the old key, the
+// old key can be removed.
+//
+// Usage:
+//
+//	go run ./cmd/encrypt-tokens
+//	go run ./cmd/encrypt-tokens -decrypt   # before rolling back migration 000015
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"soundscraibe/internal/config"
+	"soundscraibe/internal/database"
+	"soundscraibe/internal/tokencrypt"
+	"soundscraibe/internal/user"
+	"soundscraibe/migrations"
+)
+
+func main() {
+	decrypt := flag.Bool("decrypt", false, "write tokens back as plaintext instead")
+	flag.Parse()
+
+	cfg := config.Load()
+	ctx := context.Background()
+
+	db, err := database.Connect(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	if err := database.RunMigrations(migrations.FS, cfg.DatabaseURL); err != nil {
+		log.Fatalf("failed to run database migrations: %v", err)
+	}
+
+	if err := cfg.CheckTokenKeys(); err != nil {
+		log.Fatal(err)
+	}
+	keys, err := tokencrypt.NewKeyring(cfg.TokenEncryptionKeys, cfg.SessionSecret)
+	if err != nil {
+		log.Fatalf("invalid token encryption keys: %v", err)
+	}
+
+	if *decrypt {
+		n, err := user.DecryptTokens(ctx, db, keys)
+		if err != nil {
+			log.Fatalf("decrypting tokens (%d rows done): %v", n, err)
+		}
+		log.Printf("decrypted tokens for %d users", n)
+		return
+	}
+
+	summary, err := user.RotateTokenKeys(ctx, db, keys)
+	if err != nil {
+		log.Fatalf("encrypting tokens (%d encrypted, %d re-wrapped so far): %v", summary.Encrypted, summary.Rewrapped, err)
+	}
+	log.Printf("primary key %q: encrypted %d users, re-wrapped %d, skipped %d changed concurrently",
+		keys.PrimaryID(), summary.Encrypted, summary.Rewrapped, summary.Skipped)
+}
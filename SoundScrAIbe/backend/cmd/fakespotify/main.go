@@ -0,0 +1,41 @@
+// Command fakespotify runs a fake Spotify accounts service and Web API backed
+// by fixture data, so the backend can be developed and exercised without a
+// Spotify account or network access.
+//
+// Usage:
+//
+//	go run ./cmd/fakespotify -addr :8090
+//
+// Then start the server with
+//
+//	SPOTIFY_API_BASE_URL=http://localhost:8090/v1
+//	SPOTIFY_ACCOUNTS_BASE_URL=http://localhost:8090
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"os"
+
+	"soundscraibe/internal/fakespotify"
+)
+
+func main() {
+	defaultAddr := ":8090"
+	if v := os.Getenv("FAKE_SPOTIFY_ADDR"); v != "" {
+		defaultAddr = v
+	}
+	addr := flag.String("addr", defaultAddr, "listen address")
+	flag.Parse()
+
+	srv, err := fakespotify.New()
+	if err != nil {
+		log.Fatalf("Failed to load fake Spotify fixtures: %v", err)
+	}
+
+	log.Printf("Fake Spotify listening on %s (API at /v1)", *addr)
+	if err := http.ListenAndServe(*addr, srv); err != nil {
+		log.Fatalf("Fake Spotify failed: %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+// Command import-history loads listening history exports into a user's
+// plays: Spotify Extended Streaming History files
+// (Streaming_History_Audio_*.json from the Spotify data export), Last.fm
+// CSV/JSON exports and ListenBrainz JSONL dumps.
+//
+// Usage:
+//
+//	go run ./cmd/import-history -user <spotify_id> Streaming_History_Audio_*.json
+//	go run ./cmd/import-history -user <spotify_id> -format lastfm-csv scrobbles.csv
+//	go run ./cmd/import-history -user <spotify_id> -format listenbrainz listens/*/*.jsonl
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"soundscraibe/internal/auth"
+	"soundscraibe/internal/config"
+	"soundscraibe/internal/database"
+	"soundscraibe/internal/importer"
+	"soundscraibe/internal/spotify"
+	"soundscraibe/internal/tokencrypt"
+	"soundscraibe/internal/user"
+	"soundscraibe/migrations"
+)
+
+func main() {
+	spotifyID := flag.String("user", "", "Spotify user ID of the account to import into (required)")
+	format := flag.String("format", "spotify", "export format: spotify, lastfm-csv, lastfm-json or listenbrainz")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s -user <spotify_id> [-format FORMAT] FILE...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *spotifyID == "" || flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var (
+		source        string
+		parseScrobble func(io.Reader) ([]importer.Scrobble, error)
+	)
+	switch *format {
+	case "spotify":
+		source = importer.SourceSpotifyExport
+	case "lastfm-csv":
+		source, parseScrobble = importer.SourceLastFM, importer.ParseLastFMCSV
+	case "lastfm-json":
+		source, parseScrobble = importer.SourceLastFM, importer.ParseLastFMJSON
+	case "listenbrainz":
+		source, parseScrobble = importer.SourceListenBrainz, importer.ParseListenBrainz
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg := config.Load()
+	ctx := context.Background()
+
+	db, err := database.Connect(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	if err := database.RunMigrations(migrations.FS, cfg.DatabaseURL); err != nil {
+		log.Fatalf("failed to run database migrations: %v", err)
+	}
+
+	if err := cfg.CheckTokenKeys(); err != nil {
+		log.Fatal(err)
+	}
+	keys, err := tokencrypt.NewKeyring(cfg.TokenEncryptionKeys, cfg.SessionSecret)
+	if err != nil {
+		log.Fatalf("invalid token encryption keys: %v", err)
+	}
+
+	u, err := user.GetBySpotifyID(ctx, db, keys, *spotifyID)
+	if err != nil {
+		log.Fatalf("user %q not found (log in through the app once first): %v", *spotifyID, err)
+	}
+
+	// Track lookups and name searches need a live access token.
+	sp := spotify.NewClient(spotify.ClientOptions{
+		APIBaseURL:      cfg.SpotifyAPIBaseURL,
+		AccountsBaseURL: cfg.SpotifyAccountsBaseURL,
+		RateLimit:       cfg.SpotifyRateLimit,
+		RateBurst:       cfg.SpotifyRateBurst,
+	})
+	oauth := &spotify.Config{
+		ClientID:     cfg.SpotifyClientID,
+		ClientSecret: cfg.SpotifyClientSecret,
+		RedirectURI:  cfg.SpotifyRedirectURI,
+		Client:       sp,
+	}
+	if err := auth.EnsureFreshToken(ctx, db, keys, oauth, u); err != nil {
+		log.Fatalf("failed to refresh spotify token: %v", err)
+	}
+
+	var (
+		records   []importer.StreamRecord
+		scrobbles []importer.Scrobble
+	)
+	for _, path := range flag.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Fatalf("failed to open %s: %v", path, err)
+		}
+		if parseScrobble != nil {
+			parsed, err := parseScrobble(f)
+			if err != nil {
+				log.Fatalf("%s: %v", path, err)
+			}
+			fmt.Printf("%s: %d scrobbles\n", path, len(parsed))
+			scrobbles = append(scrobbles, parsed...)
+		} else {
+			recs, err := importer.ParseSpotifyExport(f)
+			if err != nil {
+				log.Fatalf("%s: %v", path, err)
+			}
+			fmt.Printf("%s: %d records\n", path, len(recs))
+			records = append(records, recs...)
+		}
+		f.Close()
+	}
+
+	total := len(records) + len(scrobbles)
+	jobID, err := importer.CreateJob(ctx, db, u.ID, source, total)
+	if err != nil {
+		log.Fatalf("failed to create import job: %v", err)
+	}
+
+	progress := func(s importer.Summary) {
+		fmt.Printf("\rprocessed %d/%d (added %d, skipped %d, unresolved %d)",
+			s.Processed, s.Total, s.Added, s.Skipped, s.Unresolved)
+		if err := importer.UpdateJob(ctx, db, jobID, s); err != nil {
+			log.Printf("failed to record progress: %v", err)
+		}
+	}
+
+	var (
+		summary   *importer.Summary
+		importErr error
+	)
+	token := auth.TokenSource(db, keys, oauth, u)
+	if parseScrobble != nil {
+		summary, importErr = importer.ImportScrobbles(ctx, db, sp, token, u.ID, source, scrobbles, progress)
+	} else {
+		summary, importErr = importer.ImportSpotifyExport(ctx, db, sp, token, u.ID, records, progress)
+	}
+	fmt.Println()
+
+	if err := importer.FinishJob(ctx, db, jobID, *summary, importErr); err != nil {
+		log.Printf("failed to record import result: %v", err)
+	}
+	if importErr != nil {
+		log.Fatalf("import failed: %v", importErr)
+	}
+
+	fmt.Printf("import %d complete: %d records, %d added, %d skipped, %d unresolved\n",
+		jobID, summary.Total, summary.Added, summary.Skipped, summary.Unresolved)
+}
@@ -1,16 +1,42 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+	_ "time/tzdata" // user timezones must load on hosts without a zoneinfo database
 
+	"soundscraibe/internal/ai"
+	"soundscraibe/internal/auth"
 	"soundscraibe/internal/config"
 	"soundscraibe/internal/database"
+	"soundscraibe/internal/entitymeta"
+	"soundscraibe/internal/history"
+	"soundscraibe/internal/importer"
+	"soundscraibe/internal/logging"
+	"soundscraibe/internal/metrics"
 	"soundscraibe/internal/server"
+	"soundscraibe/internal/session"
+	"soundscraibe/internal/spotify"
+	"soundscraibe/internal/tokencrypt"
+	"soundscraibe/internal/user"
 	"soundscraibe/migrations"
 )
 
 func main() {
 	cfg := config.Load()
+	logging.Setup(cfg.LogLevel)
+
+	// SIGINT/SIGTERM stop the background workers and start a graceful shutdown.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	db, err := database.Connect(cfg.DatabaseURL)
 	if err != nil {
@@ -22,10 +48,113 @@ func main() {
 		log.Fatalf("failed to run database migrations: %v", err)
 	}
 	log.Println("database migrations applied successfully")
+	metrics.RegisterDB(db)
+
+	if n, err := importer.FailInterruptedJobs(ctx, db); err != nil {
+		log.Printf("failed to clean up interrupted imports: %v", err)
+	} else if n > 0 {
+		log.Printf("marked %d imports interrupted by the last shutdown as failed", n)
+	}
+
+	if err := cfg.CheckTokenKeys(); err != nil {
+		log.Fatal(err)
+	}
+	keys, err := tokencrypt.NewKeyring(cfg.TokenEncryptionKeys, cfg.SessionSecret)
+	if err != nil {
+		log.Fatalf("invalid token encryption keys: %v", err)
+	}
+	if cfg.TokenEncryptionKeys == "" {
+		log.Println("TOKEN_ENCRYPTION_KEYS not set; encrypting Spotify tokens with a key derived from SESSION_SECRET")
+	}
+
+	sp := spotify.NewClient(spotify.ClientOptions{
+		APIBaseURL:      cfg.SpotifyAPIBaseURL,
+		AccountsBaseURL: cfg.SpotifyAccountsBaseURL,
+		RateLimit:       cfg.SpotifyRateLimit,
+		RateBurst:       cfg.SpotifyRateBurst,
+	})
+	if cfg.SpotifyAPIBaseURL != "" {
+		log.Printf("using Spotify API at %s", cfg.SpotifyAPIBaseURL)
+	}
+
+	oauth := &spotify.Config{
+		ClientID:     cfg.SpotifyClientID,
+		ClientSecret: cfg.SpotifyClientSecret,
+		RedirectURI:  cfg.SpotifyRedirectURI,
+		Client:       sp,
+	}
 
-	srv := server.New(db, cfg)
-	log.Printf("SoundScrAIbe server starting on :%s", cfg.Port)
-	if err := srv.Run(":" + cfg.Port); err != nil {
+	var workers sync.WaitGroup
+	syncer := history.NewWorker(db, cfg, sp, keys)
+	// Listens submitted while the server was restarting or the user's Spotify
+	// token was unusable are retried once a sync shows the token works again.
+	syncer.AfterSync = func(ctx context.Context, u *user.User) {
+		summary, err := importer.ImportPendingListens(ctx, db, sp, auth.TokenSource(db, keys, oauth, u), u.ID)
+		if err != nil {
+			slog.ErrorContext(ctx, "sync: importing queued listens failed", "error", err)
+		} else if summary.Total > 0 {
+			slog.InfoContext(ctx, "sync: imported queued listens", "total", summary.Total, "added", summary.Added, "skipped", summary.Skipped, "unresolved", summary.Unresolved)
+		}
+	}
+	workers.Go(func() { syncer.Run(ctx) })
+	workers.Go(func() { session.RunPurger(ctx, db, cfg.SessionPurgeInterval) })
+	if cfg.SpotifyClientSecret != "" {
+		auth := &spotify.Config{ClientID: cfg.SpotifyClientID, ClientSecret: cfg.SpotifyClientSecret, Client: sp}
+		workers.Go(func() {
+			entitymeta.RunRefresher(ctx, db, sp, auth, cfg.MetadataInterval, cfg.MetadataMaxAge)
+		})
+	} else {
+		log.Println("SPOTIFY_CLIENT_SECRET not set; stale entity metadata won't be refreshed in the background")
+	}
+
+	llm, err := ai.NewProvider(cfg.AIProvider, ai.Options{
+		Model:       cfg.AIModel,
+		BaseURL:     cfg.AIBaseURL,
+		APIKey:      cfg.AIAPIKey,
+		Temperature: cfg.AITemperature,
+		MaxTokens:   cfg.AIMaxTokens,
+	})
+	switch {
+	case errors.Is(err, ai.ErrNotConfigured):
+		log.Printf("AI recommendations disabled: %v", err)
+	case err != nil:
+		log.Fatalf("invalid AI provider configuration: %v", err)
+	default:
+		log.Printf("AI recommendations using %s", llm.Name())
+	}
+
+	background := server.NewBackground()
+	srv := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           server.New(db, cfg, syncer, llm, sp, keys, background),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Printf("SoundScrAIbe server starting on :%s", cfg.Port)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
 		log.Fatalf("server failed: %v", err)
+	case <-ctx.Done():
+	}
+	stop() // a second signal kills the process
+
+	// Stop accepting connections and let in-flight requests (AI calls in
+	// particular) and background imports finish, up to ShutdownTimeout.
+	// Imports still running then are cancelled and recorded as failed.
+	log.Printf("shutting down, waiting up to %s for in-flight requests and imports", cfg.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("graceful shutdown incomplete: %v", err)
+	}
+	if err := background.Shutdown(shutdownCtx); err != nil {
+		log.Printf("cancelled background imports still running: %v", err)
 	}
+	workers.Wait()
+	log.Println("server stopped")
 }
@@ -0,0 +1,150 @@
+package accesstoken
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Scopes a personal access token can have.
+const (
+	ScopeRead  = "read"  // endpoints that don't change anything
+	ScopeWrite = "write" // every endpoint open to access tokens
+)
+
+// tokenPrefix marks personal access tokens so they're recognizable in
+// scripts and secret scanners.
+const tokenPrefix = "ssb_pat_"
+
+// displayPrefixLen is how much of a token is kept in clear for listing.
+const displayPrefixLen = len(tokenPrefix) + 4
+
+// MaxPerUser caps how many tokens a user can hold.
+const MaxPerUser = 50
+
+// ErrTooMany is returned by Create when the user already has MaxPerUser tokens.
+var ErrTooMany = errors.New("too many personal access tokens")
+
+// Token describes a personal access token without revealing it.
+type Token struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scope      string     `json:"scope"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Expired reports whether the token has passed its expiry.
+func (t *Token) Expired() bool {
+	return t.ExpiresAt != nil && !t.ExpiresAt.After(time.Now())
+}
+
+// ValidScope reports whether s is a known scope.
+func ValidScope(s string) bool {
+	return s == ScopeRead || s == ScopeWrite
+}
+
+// Create issues a new token for the user and returns it with its plain value.
+// The plain token is never stored, so this is the only chance to show it.
+// A nil expiresAt means the token doesn't expire.
+func Create(ctx context.Context, db *sql.DB, userID int64, name, scope string, expiresAt *time.Time) (*Token, string, error) {
+	plain, err := generate()
+	if err != nil {
+		return nil, "", err
+	}
+
+	t := &Token{Name: name, Prefix: plain[:displayPrefixLen], Scope: scope, ExpiresAt: expiresAt}
+	err = db.QueryRowContext(ctx, `
+		INSERT INTO personal_access_tokens (user_id, name, token_hash, token_prefix, scope, expires_at)
+		SELECT $1, $2, $3, $4, $5, $6
+		WHERE (SELECT count(*) FROM personal_access_tokens WHERE user_id = $1) < $7
+		RETURNING id, created_at`,
+		userID, name, hash(plain), t.Prefix, scope, expiresAt, MaxPerUser,
+	).Scan(&t.ID, &t.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, "", ErrTooMany
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("creating access token: %w", err)
+	}
+	return t, plain, nil
+}
+
+// List returns the user's tokens, newest first, including expired ones.
+func List(ctx context.Context, db *sql.DB, userID int64) ([]Token, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, name, token_prefix, scope, expires_at, last_used_at, created_at
+		FROM personal_access_tokens
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("listing access tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []Token{}
+	for rows.Next() {
+		var t Token
+		if err := rows.Scan(&t.ID, &t.Name, &t.Prefix, &t.Scope, &t.ExpiresAt, &t.LastUsedAt, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scanning access token: %w", err)
+		}
+		tokens = append(tokens, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating access tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// Delete revokes one of the user's tokens. It reports false if the user has
+// no token with that ID.
+func Delete(ctx context.Context, db *sql.DB, userID, id int64) (bool, error) {
+	res, err := db.ExecContext(ctx, `DELETE FROM personal_access_tokens WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("deleting access token: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("deleting access token: %w", err)
+	}
+	return n > 0, nil
+}
+
+// Authenticate returns the ID of the user owning an unexpired token and the
+// token's scope, and records the use. It returns sql.ErrNoRows (wrapped) when
+// the token is unknown or expired.
+func Authenticate(ctx context.Context, db *sql.DB, token string) (int64, string, error) {
+	var (
+		userID int64
+		scope  string
+	)
+	err := db.QueryRowContext(ctx, `
+		UPDATE personal_access_tokens SET last_used_at = now()
+		WHERE token_hash = $1 AND (expires_at IS NULL OR expires_at > now())
+		RETURNING user_id, scope`, hash(token),
+	).Scan(&userID, &scope)
+	if err != nil {
+		return 0, "", fmt.Errorf("authenticating access token: %w", err)
+	}
+	return userID, scope, nil
+}
+
+func generate() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating access token: %w", err)
+	}
+	return tokenPrefix + hex.EncodeToString(b), nil
+}
+
+func hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,89 +1,141 @@
 package ai
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
-)
 
-const (
-	groqURL     = "https://api.groq.com/openai/v1/chat/completions"
-	groqModel   = "llama-3.3-70b-versatile"
-	maxTokens   = 4096
-	temperature = 0.9
+	"soundscraibe/internal/metrics"
 )
 
 // ---------------------------------------------------------------------------
-// Request types (OpenAI-compatible)
+// Provider
 // ---------------------------------------------------------------------------
 
-type message struct {
-	Role    string `json:"role"`
+// Message is a single chat message sent to a provider.
+type Message struct {
+	Role    string `json:"role"` // "system", "user", "assistant"
 	Content string `json:"content"`
 }
 
-type responseFormat struct {
-	Type string `json:"type"`
+// Request is a chat completion request, independent of the backing API.
+type Request struct {
+	Messages []Message
+	JSON     bool // ask the model for a JSON object
 }
 
-type request struct {
-	Model          string          `json:"model"`
-	Messages       []message       `json:"messages"`
-	Temperature    float64         `json:"temperature"`
-	MaxTokens      int             `json:"max_tokens"`
-	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+// Provider is an LLM backend. Implementations hold their own model and
+// sampling settings; callers only supply messages.
+type Provider interface {
+	// Name identifies the provider in logs and errors (e.g. "groq").
+	Name() string
+	// Chat sends a blocking request and returns the assistant's text.
+	Chat(ctx context.Context, req Request) (string, error)
 }
 
-// ---------------------------------------------------------------------------
-// Response types (OpenAI-compatible)
-// ---------------------------------------------------------------------------
+// Options configures a provider. Zero values fall back to per-provider
+// defaults, except Temperature, where 0 (greedy sampling) is a valid choice.
+type Options struct {
+	Model       string
+	BaseURL     string
+	APIKey      string
+	Temperature float64
+	MaxTokens   int
+}
 
-type choiceMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+const defaultMaxTokens = 4096
+
+// logCall logs an outbound provider request against ctx, so it carries the
+// originating request ID. status is 0 when the request failed outright.
+func logCall(ctx context.Context, provider, model string, status int, err error, start time.Time) {
+	attrs := []slog.Attr{
+		slog.String("provider", provider),
+		slog.String("model", model),
+		slog.Int64("duration_ms", time.Since(start).Milliseconds()),
+	}
+	level := slog.LevelInfo
+	if err != nil {
+		level = slog.LevelWarn
+		attrs = append(attrs, slog.String("error", err.Error()))
+	} else {
+		if status >= 400 {
+			level = slog.LevelWarn
+		}
+		attrs = append(attrs, slog.Int("status", status))
+	}
+	slog.LogAttrs(ctx, level, "ai request", attrs...)
 }
 
-type choice struct {
-	Message choiceMessage `json:"message"`
+// ErrNotConfigured is returned by NewProvider when a hosted provider has no API key.
+var ErrNotConfigured = errors.New("ai provider not configured")
+
+// NewProvider builds the named provider: "groq", "openai" (any
+// OpenAI-compatible server, e.g. OpenAI, OpenRouter, llama.cpp, vLLM),
+// "ollama", or "fake" (canned responses, for development and tests).
+func NewProvider(name string, opts Options) (Provider, error) {
+	if opts.MaxTokens == 0 {
+		opts.MaxTokens = defaultMaxTokens
+	}
+
+	switch strings.ToLower(name) {
+	case "groq", "":
+		if opts.APIKey == "" {
+			return nil, fmt.Errorf("groq: %w", ErrNotConfigured)
+		}
+		return NewGroq(opts), nil
+	case "openai":
+		if opts.BaseURL == "" && opts.APIKey == "" {
+			return nil, fmt.Errorf("openai: %w", ErrNotConfigured)
+		}
+		return NewOpenAICompatible("openai", opts), nil
+	case "ollama":
+		return NewOllama(opts), nil
+	case "fake":
+		return &FakeProvider{}, nil
+	default:
+		return nil, fmt.Errorf("unknown ai provider %q", name)
+	}
+}
+
+// APIError is a non-2xx response from a provider's HTTP API.
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Body       string
 }
 
-type response struct {
-	Choices []choice `json:"choices"`
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s API error (status %d): %s", e.Provider, e.StatusCode, e.Body)
 }
 
-type apiErrorBody struct {
-	Error *struct {
-		Message string `json:"message"`
-		Type    string `json:"type"`
-		Code    string `json:"code"`
-	} `json:"error,omitempty"`
+// IsRateLimited reports whether err is a provider rate-limit (HTTP 429) response.
+func IsRateLimited(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests
 }
 
 // ---------------------------------------------------------------------------
 // Public API
 // ---------------------------------------------------------------------------
 
-// Complete sends a blocking request to the Groq API and returns the text response.
-// It takes context, API key, system prompt, and user message.
-func Complete(ctx context.Context, apiKey, system, userMessage string) (string, error) {
-	msgs := buildMessages(system, userMessage)
-	return complete(ctx, apiKey, msgs, false)
+// Complete sends a blocking request to the provider and returns the text response.
+// It takes context, provider, system prompt, and user message.
+func Complete(ctx context.Context, p Provider, system, userMessage string) (string, error) {
+	return chat(ctx, p, Request{Messages: buildMessages(system, userMessage)})
 }
 
-// CompleteJSON is like Complete but requests JSON output via response_format
-// and validates the response. If not valid JSON, it retries once with a
-// correction prompt.
-func CompleteJSON(ctx context.Context, apiKey, system, userMessage string) (string, error) {
+// CompleteJSON is like Complete but requests JSON output and validates the
+// response. If not valid JSON, it retries once with a correction prompt.
+func CompleteJSON(ctx context.Context, p Provider, system, userMessage string) (string, error) {
 	msgs := buildMessages(system, userMessage)
 
-	text, err := complete(ctx, apiKey, msgs, true)
+	text, err := chat(ctx, p, Request{Messages: msgs, JSON: true})
 	if err != nil {
 		return "", err
 	}
@@ -91,20 +143,21 @@ func CompleteJSON(ctx context.Context, apiKey, system, userMessage string) (stri
 	if json.Valid([]byte(text)) {
 		return text, nil
 	}
+	metrics.AIRetries.WithLabelValues(p.Name(), "invalid_json").Inc()
 
 	// Retry with correction: append the bad response and a correction message.
 	retryMsgs := append(msgs,
-		message{Role: "assistant", Content: text},
-		message{Role: "user", Content: "Your previous response was not valid JSON. Please respond with ONLY a valid JSON object."},
+		Message{Role: "assistant", Content: text},
+		Message{Role: "user", Content: "Your previous response was not valid JSON. Please respond with ONLY a valid JSON object."},
 	)
 
-	text, err = complete(ctx, apiKey, retryMsgs, true)
+	text, err = chat(ctx, p, Request{Messages: retryMsgs, JSON: true})
 	if err != nil {
-		return "", fmt.Errorf("groq JSON retry: %w", err)
+		return "", fmt.Errorf("%s JSON retry: %w", p.Name(), err)
 	}
 
 	if !json.Valid([]byte(text)) {
-		return "", fmt.Errorf("groq response is not valid JSON after retry")
+		return "", fmt.Errorf("%s response is not valid JSON after retry", p.Name())
 	}
 
 	return text, nil
@@ -114,100 +167,31 @@ func CompleteJSON(ctx context.Context, apiKey, system, userMessage string) (stri
 // Internal
 // ---------------------------------------------------------------------------
 
-// buildMessages constructs the message list with an optional system message.
-func buildMessages(system, userMessage string) []message {
-	var msgs []message
-	if system != "" {
-		msgs = append(msgs, message{Role: "system", Content: system})
-	}
-	msgs = append(msgs, message{Role: "user", Content: userMessage})
-	return msgs
+// chat calls p.Chat and records its latency.
+func chat(ctx context.Context, p Provider, req Request) (string, error) {
+	start := time.Now()
+	text, err := p.Chat(ctx, req)
+	observeCall(p.Name(), start, err)
+	return text, err
 }
 
-// complete sends a request to the Groq API and returns the text content.
-func complete(ctx context.Context, apiKey string, msgs []message, jsonMode bool) (string, error) {
-	reqBody := request{
-		Model:       groqModel,
-		Messages:    msgs,
-		Temperature: temperature,
-		MaxTokens:   maxTokens,
-	}
-
-	if jsonMode {
-		reqBody.ResponseFormat = &responseFormat{Type: "json_object"}
-	}
-
-	payload, err := json.Marshal(reqBody)
+// observeCall records an LLM call's latency by provider and outcome.
+func observeCall(provider string, start time.Time, err error) {
+	outcome := "ok"
 	if err != nil {
-		return "", fmt.Errorf("marshalling groq request: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, groqURL, bytes.NewReader(payload))
-	if err != nil {
-		return "", fmt.Errorf("creating groq request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-
-	client := &http.Client{Timeout: 60 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("sending groq request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("reading groq response: %w", err)
-	}
-
-	// Retry once on 429 rate limit.
-	if resp.StatusCode == http.StatusTooManyRequests {
-		delay := parseRetryAfter(resp.Header.Get("Retry-After"))
-		if delay > 0 && delay <= 60*time.Second {
-			log.Printf("groq rate limited, retrying in %s...", delay)
-			time.Sleep(delay)
-		} else {
-			log.Printf("groq rate limited, retrying in 5s...")
-			time.Sleep(5 * time.Second)
-		}
-
-		// Rebuild the request (body reader is consumed).
-		retryReq, err := http.NewRequestWithContext(ctx, http.MethodPost, groqURL, bytes.NewReader(payload))
-		if err != nil {
-			return "", fmt.Errorf("creating groq retry request: %w", err)
-		}
-		retryReq.Header.Set("Content-Type", "application/json")
-		retryReq.Header.Set("Authorization", "Bearer "+apiKey)
-
-		resp2, err := client.Do(retryReq)
-		if err != nil {
-			return "", fmt.Errorf("sending groq retry request: %w", err)
-		}
-		defer resp2.Body.Close()
-
-		body, err = io.ReadAll(resp2.Body)
-		if err != nil {
-			return "", fmt.Errorf("reading groq retry response: %w", err)
-		}
-
-		if resp2.StatusCode != http.StatusOK {
-			return "", fmt.Errorf("groq API error (status %d): %s", resp2.StatusCode, string(body))
-		}
-	} else if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("groq API error (status %d): %s", resp.StatusCode, string(body))
-	}
-
-	var result response
-	if err := json.Unmarshal(body, &result); err != nil {
-		return "", fmt.Errorf("parsing groq response: %w", err)
+		outcome = "error"
 	}
+	metrics.AIRequestDuration.WithLabelValues(provider, outcome).Observe(time.Since(start).Seconds())
+}
 
-	if len(result.Choices) == 0 {
-		return "", fmt.Errorf("groq response contained no choices")
+// buildMessages constructs the message list with an optional system message.
+func buildMessages(system, userMessage string) []Message {
+	var msgs []Message
+	if system != "" {
+		msgs = append(msgs, Message{Role: "system", Content: system})
 	}
-
-	return result.Choices[0].Message.Content, nil
+	msgs = append(msgs, Message{Role: "user", Content: userMessage})
+	return msgs
 }
 
 // parseRetryAfter parses the Retry-After header value.
@@ -0,0 +1,63 @@
+package ai
+
+import (
+	"context"
+	"sync"
+)
+
+// fakeResponse is a small but valid recommendation payload, so the whole
+// recommendation flow can run without an LLM.
+const fakeResponse = `{
+  "taste_summary": "A fake taste summary from the development AI provider.",
+  "recommendations": [
+    {"type": "track", "title": "Strobe", "artist": "deadmau5", "year": 2009, "why": "Placeholder recommendation.", "discovery_angle": "deep_cut", "mood_tags": ["hypnotic"]},
+    {"type": "album", "title": "In Rainbows", "artist": "Radiohead", "year": 2007, "why": "Placeholder recommendation.", "discovery_angle": "era_bridge", "mood_tags": ["warm"]},
+    {"type": "artist", "title": "Khruangbin", "artist": "Khruangbin", "why": "Placeholder recommendation.", "discovery_angle": "cross_genre", "mood_tags": ["groovy"]}
+  ]
+}`
+
+// FakeProvider is an in-process Provider for development and tests. It
+// returns Responses in order (repeating the last one), or a built-in
+// recommendation payload if none are set, and records every request.
+type FakeProvider struct {
+	Responses []string
+	Err       error
+
+	mu       sync.Mutex
+	Requests []Request
+}
+
+// Name implements Provider.
+func (p *FakeProvider) Name() string { return "fake" }
+
+// ChatStream implements StreamingProvider by replaying the Chat response in
+// small chunks.
+func (p *FakeProvider) ChatStream(ctx context.Context, req Request, onToken func(string)) (string, error) {
+	text, err := p.Chat(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	const chunkSize = 16
+	for start := 0; start < len(text); start += chunkSize {
+		onToken(text[start:min(start+chunkSize, len(text))])
+	}
+	return text, nil
+}
+
+// Chat implements Provider.
+func (p *FakeProvider) Chat(ctx context.Context, req Request) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.Requests = append(p.Requests, req)
+	if p.Err != nil {
+		return "", p.Err
+	}
+	if len(p.Responses) == 0 {
+		return fakeResponse, nil
+	}
+
+	i := min(len(p.Requests)-1, len(p.Responses)-1)
+	return p.Responses[i], nil
+}
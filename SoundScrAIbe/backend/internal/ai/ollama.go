@@ -0,0 +1,171 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	ollamaBaseURL = "http://localhost:11434"
+	ollamaModel   = "llama3.1"
+)
+
+type ollamaOptions struct {
+	Temperature float64 `json:"temperature"`
+	NumPredict  int     `json:"num_predict"`
+}
+
+type ollamaRequest struct {
+	Model    string        `json:"model"`
+	Messages []Message     `json:"messages"`
+	Stream   bool          `json:"stream"`
+	Format   string        `json:"format,omitempty"`
+	Options  ollamaOptions `json:"options"`
+}
+
+type ollamaResponse struct {
+	Message Message `json:"message"`
+	Done    bool    `json:"done"`
+	Error   string  `json:"error"`
+}
+
+// Ollama talks to a local Ollama server through its native /api/chat endpoint.
+type Ollama struct {
+	baseURL     string
+	model       string
+	temperature float64
+	maxTokens   int
+	client      *http.Client
+}
+
+// NewOllama returns a provider for the Ollama server at opts.BaseURL
+// (default http://localhost:11434).
+func NewOllama(opts Options) *Ollama {
+	if opts.BaseURL == "" {
+		opts.BaseURL = ollamaBaseURL
+	}
+	if opts.Model == "" {
+		opts.Model = ollamaModel
+	}
+	return &Ollama{
+		baseURL:     strings.TrimRight(opts.BaseURL, "/"),
+		model:       opts.Model,
+		temperature: opts.Temperature,
+		maxTokens:   opts.MaxTokens,
+		// Local models on modest hardware can take minutes for a full answer.
+		client: &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+// Name implements Provider.
+func (p *Ollama) Name() string { return "ollama" }
+
+// Chat implements Provider.
+func (p *Ollama) Chat(ctx context.Context, req Request) (string, error) {
+	resp, err := p.send(ctx, req, false)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("reading ollama response: %w", err)
+	}
+
+	var result ollamaResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("parsing ollama response: %w", err)
+	}
+	if result.Error != "" {
+		return "", fmt.Errorf("ollama error: %s", result.Error)
+	}
+
+	return result.Message.Content, nil
+}
+
+// ChatStream implements StreamingProvider. Ollama streams newline-delimited
+// JSON objects, each carrying the next piece of the message.
+func (p *Ollama) ChatStream(ctx context.Context, req Request, onToken func(string)) (string, error) {
+	resp, err := p.send(ctx, req, true)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var full strings.Builder
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var chunk ollamaResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return full.String(), fmt.Errorf("parsing ollama stream chunk: %w", err)
+		}
+		if chunk.Error != "" {
+			return full.String(), fmt.Errorf("ollama error: %s", chunk.Error)
+		}
+		if chunk.Message.Content != "" {
+			full.WriteString(chunk.Message.Content)
+			onToken(chunk.Message.Content)
+		}
+		if chunk.Done {
+			break
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return full.String(), fmt.Errorf("reading ollama stream: %w", err)
+	}
+
+	return full.String(), nil
+}
+
+// send posts a /api/chat request and returns the open 200 response.
+func (p *Ollama) send(ctx context.Context, req Request, stream bool) (*http.Response, error) {
+	reqBody := ollamaRequest{
+		Model:    p.model,
+		Messages: req.Messages,
+		Stream:   stream,
+		Options:  ollamaOptions{Temperature: p.temperature, NumPredict: p.maxTokens},
+	}
+	if req.JSON {
+		reqBody.Format = "json"
+	}
+
+	payload, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling ollama request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/api/chat", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("creating ollama request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	start := time.Now()
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		logCall(ctx, "ollama", p.model, 0, err, start)
+		return nil, fmt.Errorf("sending ollama request: %w", err)
+	}
+	logCall(ctx, "ollama", p.model, resp.StatusCode, nil, start)
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, &APIError{Provider: "ollama", StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,258 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"soundscraibe/internal/metrics"
+)
+
+const (
+	groqBaseURL   = "https://api.groq.com/openai/v1"
+	groqModel     = "llama-3.3-70b-versatile"
+	openAIBaseURL = "https://api.openai.com/v1"
+	openAIModel   = "gpt-4o-mini"
+)
+
+// ---------------------------------------------------------------------------
+// Request types (OpenAI-compatible)
+// ---------------------------------------------------------------------------
+
+type responseFormat struct {
+	Type string `json:"type"`
+}
+
+type chatRequest struct {
+	Model          string          `json:"model"`
+	Messages       []Message       `json:"messages"`
+	Temperature    float64         `json:"temperature"`
+	MaxTokens      int             `json:"max_tokens"`
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+	Stream         bool            `json:"stream,omitempty"`
+}
+
+// ---------------------------------------------------------------------------
+// Response types (OpenAI-compatible)
+// ---------------------------------------------------------------------------
+
+type choice struct {
+	Message Message `json:"message"`
+}
+
+type chatResponse struct {
+	Choices []choice `json:"choices"`
+}
+
+type streamChoice struct {
+	Delta Message `json:"delta"`
+}
+
+type streamChunk struct {
+	Choices []streamChoice `json:"choices"`
+}
+
+// ---------------------------------------------------------------------------
+// OpenAICompatible
+// ---------------------------------------------------------------------------
+
+// OpenAICompatible talks to any server implementing the OpenAI
+// /chat/completions API: Groq, OpenAI, OpenRouter, llama.cpp, vLLM, etc.
+type OpenAICompatible struct {
+	name        string
+	baseURL     string
+	apiKey      string
+	model       string
+	temperature float64
+	maxTokens   int
+	client      *http.Client
+	// streamClient has a longer timeout, since the body stays open while
+	// the model generates.
+	streamClient *http.Client
+}
+
+// NewGroq returns an OpenAI-compatible provider preconfigured for Groq.
+func NewGroq(opts Options) *OpenAICompatible {
+	if opts.BaseURL == "" {
+		opts.BaseURL = groqBaseURL
+	}
+	if opts.Model == "" {
+		opts.Model = groqModel
+	}
+	return NewOpenAICompatible("groq", opts)
+}
+
+// NewOpenAICompatible returns a provider for the server at opts.BaseURL
+// (defaulting to OpenAI). The API key is optional for self-hosted servers.
+func NewOpenAICompatible(name string, opts Options) *OpenAICompatible {
+	if opts.BaseURL == "" {
+		opts.BaseURL = openAIBaseURL
+	}
+	if opts.Model == "" {
+		opts.Model = openAIModel
+	}
+	return &OpenAICompatible{
+		name:         name,
+		baseURL:      strings.TrimRight(opts.BaseURL, "/"),
+		apiKey:       opts.APIKey,
+		model:        opts.Model,
+		temperature:  opts.Temperature,
+		maxTokens:    opts.MaxTokens,
+		client:       &http.Client{Timeout: 60 * time.Second},
+		streamClient: &http.Client{Timeout: 3 * time.Minute},
+	}
+}
+
+// Name implements Provider.
+func (p *OpenAICompatible) Name() string { return p.name }
+
+// Chat implements Provider.
+func (p *OpenAICompatible) Chat(ctx context.Context, req Request) (string, error) {
+	payload, err := p.payload(req, false)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := p.send(ctx, p.client, payload)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("reading %s response: %w", p.name, err)
+	}
+
+	var result chatResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("parsing %s response: %w", p.name, err)
+	}
+
+	if len(result.Choices) == 0 {
+		return "", fmt.Errorf("%s response contained no choices", p.name)
+	}
+
+	return result.Choices[0].Message.Content, nil
+}
+
+// ChatStream implements StreamingProvider using server-sent events
+// ("stream": true).
+func (p *OpenAICompatible) ChatStream(ctx context.Context, req Request, onToken func(string)) (string, error) {
+	payload, err := p.payload(req, true)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := p.send(ctx, p.streamClient, payload)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var full strings.Builder
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		data, ok := strings.CutPrefix(sc.Text(), "data:")
+		if !ok {
+			continue // blank separators, comments, event names
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk streamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return full.String(), fmt.Errorf("parsing %s stream chunk: %w", p.name, err)
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+		text := chunk.Choices[0].Delta.Content
+		full.WriteString(text)
+		onToken(text)
+	}
+	if err := sc.Err(); err != nil {
+		return full.String(), fmt.Errorf("reading %s stream: %w", p.name, err)
+	}
+
+	return full.String(), nil
+}
+
+// payload marshals a /chat/completions request body.
+func (p *OpenAICompatible) payload(req Request, stream bool) ([]byte, error) {
+	reqBody := chatRequest{
+		Model:       p.model,
+		Messages:    req.Messages,
+		Temperature: p.temperature,
+		MaxTokens:   p.maxTokens,
+		Stream:      stream,
+	}
+
+	if req.JSON {
+		reqBody.ResponseFormat = &responseFormat{Type: "json_object"}
+	}
+
+	payload, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling %s request: %w", p.name, err)
+	}
+	return payload, nil
+}
+
+// send posts a /chat/completions request and returns the open 200 response.
+// A 429 is retried once after the Retry-After delay; any other non-200
+// status becomes an *APIError.
+func (p *OpenAICompatible) send(ctx context.Context, client *http.Client, payload []byte) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/chat/completions", bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf("creating %s request: %w", p.name, err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if p.apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+p.apiKey)
+		}
+
+		start := time.Now()
+		resp, err := client.Do(req)
+		if err != nil {
+			logCall(ctx, p.name, p.model, 0, err, start)
+			return nil, fmt.Errorf("sending %s request: %w", p.name, err)
+		}
+		logCall(ctx, p.name, p.model, resp.StatusCode, nil, start)
+		if resp.StatusCode == http.StatusOK {
+			return resp, nil
+		}
+
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		// Retry once on 429 rate limit.
+		if resp.StatusCode == http.StatusTooManyRequests && attempt == 0 {
+			delay := parseRetryAfter(resp.Header.Get("Retry-After"))
+			if delay <= 0 || delay > 60*time.Second {
+				delay = 5 * time.Second
+			}
+			slog.WarnContext(ctx, "ai provider rate limited, retrying", "provider", p.name, "wait", delay.String())
+			metrics.AIRetries.WithLabelValues(p.name, "rate_limited").Inc()
+			select {
+			case <-ctx.Done():
+				return nil, fmt.Errorf("waiting to retry %s request: %w", p.name, ctx.Err())
+			case <-time.After(delay):
+			}
+			continue
+		}
+
+		return nil, &APIError{Provider: p.name, StatusCode: resp.StatusCode, Body: string(body)}
+	}
+}
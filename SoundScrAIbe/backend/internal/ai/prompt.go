@@ -20,13 +20,16 @@ type TasteProfile struct {
 	UserTags       []string
 	TopGenres      []string
 	ListeningHours []HourEntry
+	LikedRecs      []FeedbackEntry // past recommendations rated "up"
+	RejectedRecs   []FeedbackEntry // past recommendations rated "down" or "not_for_me"
+	KnownRecs      []FeedbackEntry // past recommendations the user already knew
 }
 
 // ArtistEntry represents a top artist with genre and play count information.
 type ArtistEntry struct {
 	Name      string
 	Genres    []string
-	PlayCount int // from listening_history if available, else 0
+	PlayCount int // from plays if available, else 0
 }
 
 // TrackEntry represents a top track.
@@ -56,6 +59,14 @@ type ShelfEntry struct {
 	Artist     string
 }
 
+// FeedbackEntry represents a past recommendation the user gave feedback on.
+type FeedbackEntry struct {
+	EntityType string
+	Name       string
+	Artist     string
+	Verdict    string // "up", "down", "known", "not_for_me"
+}
+
 // HourEntry represents listening activity for a specific hour of the day.
 type HourEntry struct {
 	Hour  int // 0-23
@@ -141,6 +152,7 @@ Rules:
 - Generate exactly 10 recommendations: aim for 6 tracks, 2 albums, and 2 artists.
 - The "why" field MUST reference something specific from the user's data (a genre they listen to, an artist they like, a rating they gave, their listening time patterns, etc.).
 - Do NOT recommend anything that already appears in the user's top tracks, top artists, recently played, or highly rated lists.
+- Do NOT recommend anything from the user's past recommendation feedback. Lean towards the directions of recommendations they liked, and steer away from the artists, genres, and moods of ones they rejected.
 - Prioritize cross-genre discoveries that will surprise the user while still connecting to their taste.
 - The "discovery_angle" must be one of: cross_genre, deep_cut, era_bridge, mood_match, artist_evolution.
 - Return ONLY the JSON object.`
@@ -239,6 +251,23 @@ func FormatTasteProfile(profile *TasteProfile, userPrompt string) string {
 		b.WriteString("\n\n")
 	}
 
+	// Recommendation feedback
+	if len(profile.LikedRecs) > 0 {
+		b.WriteString("### Past Recommendations I Liked\n")
+		writeFeedbackEntries(&b, profile.LikedRecs)
+		b.WriteByte('\n')
+	}
+	if len(profile.RejectedRecs) > 0 {
+		b.WriteString("### Past Recommendations I Rejected (avoid similar directions)\n")
+		writeFeedbackEntries(&b, profile.RejectedRecs)
+		b.WriteByte('\n')
+	}
+	if len(profile.KnownRecs) > 0 {
+		b.WriteString("### Past Recommendations I Already Knew\n")
+		writeFeedbackEntries(&b, profile.KnownRecs)
+		b.WriteByte('\n')
+	}
+
 	// User prompt (prompt mode)
 	if userPrompt != "" {
 		b.WriteString("---\n\n")
@@ -252,6 +281,37 @@ func FormatTasteProfile(profile *TasteProfile, userPrompt string) string {
 	return b.String()
 }
 
+// FormatBackfillRequest returns a follow-up instruction, appended to the
+// original taste profile message, asking for count replacement
+// recommendations that avoid everything in exclude.
+func FormatBackfillRequest(exclude []string, count int) string {
+	var b strings.Builder
+
+	b.WriteString("\n---\n\n")
+	b.WriteString("## FOLLOW-UP: REPLACEMENTS NEEDED\n\n")
+	b.WriteString("Some earlier suggestions were already known to the user or already suggested. Do NOT recommend any of these again:\n")
+	for _, e := range exclude {
+		b.WriteString("- " + e + "\n")
+	}
+	b.WriteString(fmt.Sprintf("\nGenerate exactly %d NEW recommendations that follow all the rules above. Prefer lesser-known artists and releases. The taste_summary may be an empty string.\n", count))
+
+	return b.String()
+}
+
+// writeFeedbackEntries writes one "- [type] ..." line per feedback entry.
+func writeFeedbackEntries(b *strings.Builder, entries []FeedbackEntry) {
+	for _, e := range entries {
+		line := fmt.Sprintf("- [%s] \"%s\" by %s", e.EntityType, e.Name, e.Artist)
+		if e.EntityType == "artist" || e.Artist == "" {
+			line = fmt.Sprintf("- [%s] %s", e.EntityType, e.Name)
+		}
+		if e.Verdict == "not_for_me" {
+			line += " (not my taste)"
+		}
+		b.WriteString(line + "\n")
+	}
+}
+
 // formatHourRange formats an hour (0-23) as a human-readable range like "10pm-11pm".
 func formatHourRange(hour int) string {
 	start := formatHour(hour)
@@ -0,0 +1,160 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"time"
+)
+
+// ---------------------------------------------------------------------------
+// Token streaming
+// ---------------------------------------------------------------------------
+
+// StreamingProvider is implemented by providers that can deliver the response
+// incrementally as it's generated.
+type StreamingProvider interface {
+	Provider
+	// ChatStream is like Chat but calls onToken with each chunk of text as it
+	// arrives. It returns the full response once the stream ends.
+	ChatStream(ctx context.Context, req Request, onToken func(string)) (string, error)
+}
+
+// StreamJSON sends a JSON-mode request and calls onToken with each chunk of
+// the response as it arrives, returning the full text. Providers without
+// streaming support deliver the whole response as one chunk. Unlike
+// CompleteJSON there's no corrective retry, since chunks have already been
+// handed to the caller.
+func StreamJSON(ctx context.Context, p Provider, system, userMessage string, onToken func(string)) (string, error) {
+	req := Request{Messages: buildMessages(system, userMessage), JSON: true}
+
+	if sp, ok := p.(StreamingProvider); ok {
+		start := time.Now()
+		text, err := sp.ChatStream(ctx, req, onToken)
+		observeCall(p.Name(), start, err)
+		return text, err
+	}
+
+	text, err := chat(ctx, p, req)
+	if err != nil {
+		return "", err
+	}
+	onToken(text)
+	return text, nil
+}
+
+// ---------------------------------------------------------------------------
+// Incremental response parsing
+// ---------------------------------------------------------------------------
+
+var (
+	summaryStartRe = regexp.MustCompile(`"taste_summary"\s*:\s*"`)
+	recsStartRe    = regexp.MustCompile(`"recommendations"\s*:\s*\[`)
+)
+
+// ResponseParser incrementally extracts the taste summary and each
+// recommendation from an AIResponse JSON document while it's still being
+// streamed, so work can start on early items before the model finishes.
+type ResponseParser struct {
+	buf []byte
+
+	summaryDone bool
+
+	// Scanner state inside the recommendations array.
+	inArray  bool
+	done     bool
+	pos      int
+	depth    int
+	inString bool
+	escaped  bool
+	objStart int
+}
+
+// Feed appends a chunk of streamed text and returns anything newly complete:
+// the taste summary (once, when its string closes) and any recommendation
+// objects that closed in this chunk.
+func (p *ResponseParser) Feed(chunk string) (summary string, recs []RawRecommendation) {
+	p.buf = append(p.buf, chunk...)
+
+	if !p.summaryDone {
+		if s, ok := p.summary(); ok {
+			p.summaryDone = true
+			summary = s
+		}
+	}
+
+	if !p.inArray && !p.done {
+		loc := recsStartRe.FindIndex(p.buf)
+		if loc == nil {
+			return summary, nil
+		}
+		p.inArray = true
+		p.pos = loc[1]
+	}
+
+	for ; p.inArray && p.pos < len(p.buf); p.pos++ {
+		b := p.buf[p.pos]
+		if p.inString {
+			switch {
+			case p.escaped:
+				p.escaped = false
+			case b == '\\':
+				p.escaped = true
+			case b == '"':
+				p.inString = false
+			}
+			continue
+		}
+
+		switch b {
+		case '"':
+			p.inString = true
+		case '{', '[':
+			if p.depth == 0 {
+				p.objStart = p.pos
+			}
+			p.depth++
+		case '}', ']':
+			if p.depth == 0 { // end of the recommendations array
+				p.inArray = false
+				p.done = true
+				break
+			}
+			p.depth--
+			if p.depth == 0 && b == '}' {
+				var rec RawRecommendation
+				if err := json.Unmarshal(p.buf[p.objStart:p.pos+1], &rec); err == nil {
+					recs = append(recs, rec)
+				}
+			}
+		}
+	}
+
+	return summary, recs
+}
+
+// summary returns the taste_summary value once its closing quote has arrived.
+func (p *ResponseParser) summary() (string, bool) {
+	loc := summaryStartRe.FindIndex(p.buf)
+	if loc == nil {
+		return "", false
+	}
+
+	start := loc[1] - 1 // include the opening quote
+	escaped := false
+	for i := loc[1]; i < len(p.buf); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case p.buf[i] == '\\':
+			escaped = true
+		case p.buf[i] == '"':
+			var s string
+			if err := json.Unmarshal(p.buf[start:i+1], &s); err != nil {
+				return "", false
+			}
+			return s, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"soundscraibe/internal/spotify"
+	"soundscraibe/internal/tokencrypt"
+	"soundscraibe/internal/user"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// refreshWindow is how close to expiry a token may get before it is refreshed.
+const refreshWindow = 5 * time.Minute
+
+// refreshTimeout bounds a shared refresh, which outlives any single caller's
+// context.
+const refreshTimeout = 15 * time.Second
+
+// ErrReauthRequired is returned (wrapped) by EnsureFreshToken when Spotify has
+// rejected the user's refresh token. The user is flagged and has to go through
+// OAuth again.
+var ErrReauthRequired = errors.New("spotify re-authorization required")
+
+// refreshes dedupes concurrent refreshes of the same user's token, e.g. from
+// parallel page loads, keyed by user ID.
+var refreshes singleflight.Group
+
+// tokens is the outcome of a refresh, shared by every caller waiting on it.
+type tokens struct {
+	access  string
+	refresh string
+	expiry  time.Time
+}
+
+// EnsureFreshToken refreshes the user's Spotify access token if it is expired or
+// expiring within refreshWindow, persists the new tokens, and updates u in place.
+// Concurrent calls for the same user share one refresh. If Spotify rejects the
+// refresh token, the user is marked as needing re-auth and ErrReauthRequired is
+// returned, as it is for users already marked.
+func EnsureFreshToken(ctx context.Context, db *sql.DB, keys *tokencrypt.Keyring, sp *spotify.Config, u *user.User) error {
+	if u.NeedsReauth {
+		return fmt.Errorf("user %d: %w", u.ID, ErrReauthRequired)
+	}
+	if time.Until(u.TokenExpiry) >= refreshWindow {
+		return nil
+	}
+
+	v, err, _ := refreshes.Do(strconv.FormatInt(u.ID, 10), func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), refreshTimeout)
+		defer cancel()
+		return refresh(ctx, db, keys, sp, u.ID)
+	})
+	if err != nil {
+		if errors.Is(err, ErrReauthRequired) {
+			u.NeedsReauth = true
+		}
+		return err
+	}
+
+	t := v.(*tokens)
+	u.AccessToken = t.access
+	u.RefreshToken = t.refresh
+	u.TokenExpiry = t.expiry
+	return nil
+}
+
+// TokenSource returns a function that yields a valid access token for u,
+// refreshing it through EnsureFreshToken when it nears expiry, for work that
+// outlives a single token such as long imports. It works on its own copy of u.
+func TokenSource(db *sql.DB, keys *tokencrypt.Keyring, sp *spotify.Config, u *user.User) func(ctx context.Context) (string, error) {
+	var mu sync.Mutex
+	cp := *u
+	return func(ctx context.Context) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err := EnsureFreshToken(ctx, db, keys, sp, &cp); err != nil {
+			return "", err
+		}
+		return cp.AccessToken, nil
+	}
+}
+
+// refresh reloads the user's tokens and, unless another request or instance
+// has refreshed them in the meantime, exchanges the refresh token for new ones.
+func refresh(ctx context.Context, db *sql.DB, keys *tokencrypt.Keyring, sp *spotify.Config, userID int64) (*tokens, error) {
+	u, err := user.GetByID(ctx, db, keys, userID)
+	if err != nil {
+		return nil, fmt.Errorf("reloading user %d for token refresh: %w", userID, err)
+	}
+	if u.NeedsReauth {
+		return nil, fmt.Errorf("user %d: %w", userID, ErrReauthRequired)
+	}
+	if time.Until(u.TokenExpiry) >= refreshWindow {
+		return &tokens{access: u.AccessToken, refresh: u.RefreshToken, expiry: u.TokenExpiry}, nil
+	}
+
+	tokenResp, err := sp.RefreshAccessToken(ctx, u.RefreshToken)
+	if errors.Is(err, spotify.ErrInvalidGrant) {
+		if markErr := user.MarkNeedsReauth(ctx, db, userID); markErr != nil {
+			slog.ErrorContext(ctx, "failed to flag user for re-auth", "error", markErr)
+		}
+		return nil, fmt.Errorf("refreshing spotify token for user %d: %w: %w", userID, ErrReauthRequired, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("refreshing spotify token for user %d: %w", userID, err)
+	}
+
+	// Spotify may or may not rotate the refresh token.
+	refreshToken := u.RefreshToken
+	if tokenResp.RefreshToken != "" {
+		refreshToken = tokenResp.RefreshToken
+	}
+	expiry := time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+
+	if err := user.UpdateTokens(ctx, db, keys, userID, tokenResp.AccessToken, refreshToken, expiry); err != nil {
+		return nil, fmt.Errorf("saving refreshed tokens for user %d: %w", userID, err)
+	}
+
+	return &tokens{access: tokenResp.AccessToken, refresh: refreshToken, expiry: expiry}, nil
+}
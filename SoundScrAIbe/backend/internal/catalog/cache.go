@@ -0,0 +1,276 @@
+package catalog
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"soundscraibe/internal/metrics"
+	"soundscraibe/internal/spotify"
+)
+
+// Entity kinds, used as the cache key prefix, the kind column and the metrics
+// label.
+const (
+	kindArtist        = "artist"
+	kindAlbum         = "album"
+	kindTrack         = "track"
+	kindAudioFeatures = "audio_features"
+)
+
+// Options configures a Cache.
+type Options struct {
+	// TTL is how long a fetched entity is served before Spotify is asked again.
+	TTL time.Duration
+	// Size is the number of entities kept in memory; 0 disables the memory tier.
+	Size int
+	// DB, if set, adds a Postgres tier shared by every instance and kept
+	// across restarts.
+	DB *sql.DB
+}
+
+// Cache wraps a spotify.API and caches catalog lookups (artists, albums,
+// tracks and audio features) for every user, since catalog data isn't
+// user-specific. Lookups try memory, then Postgres, then Spotify; batch
+// lookups fetch whatever is missing with as few Spotify calls as possible.
+// Every other call goes straight through to the wrapped API.
+type Cache struct {
+	spotify.API
+
+	ttl time.Duration
+	mem *lru
+	db  *sql.DB
+}
+
+// New returns a Cache in front of api.
+func New(api spotify.API, opts Options) *Cache {
+	return &Cache{
+		API: api,
+		ttl: opts.TTL,
+		mem: newLRU(opts.Size),
+		db:  opts.DB,
+	}
+}
+
+// GetArtist returns the artist from the cache or Spotify.
+func (c *Cache) GetArtist(ctx context.Context, accessToken, artistID string) (*spotify.TopArtist, error) {
+	return getOne(ctx, c, kindArtist, artistID, func() (*spotify.TopArtist, error) {
+		return c.API.GetArtist(ctx, accessToken, artistID)
+	})
+}
+
+// GetArtists returns the artists in the order requested, with nil entries for
+// unknown IDs. Unlike the Spotify call, any number of IDs may be passed.
+func (c *Cache) GetArtists(ctx context.Context, accessToken string, artistIDs []string) ([]*spotify.TopArtist, error) {
+	return getMany(ctx, c, kindArtist, artistIDs, spotify.MaxBatchIDs, func(ids []string) ([]*spotify.TopArtist, error) {
+		return c.API.GetArtists(ctx, accessToken, ids)
+	})
+}
+
+// GetAlbum returns the album from the cache or Spotify.
+func (c *Cache) GetAlbum(ctx context.Context, accessToken, albumID string) (*spotify.FullAlbum, error) {
+	return getOne(ctx, c, kindAlbum, albumID, func() (*spotify.FullAlbum, error) {
+		return c.API.GetAlbum(ctx, accessToken, albumID)
+	})
+}
+
+// GetAlbums returns the albums in the order requested, with nil entries for
+// unknown IDs. Unlike the Spotify call, any number of IDs may be passed.
+func (c *Cache) GetAlbums(ctx context.Context, accessToken string, albumIDs []string) ([]*spotify.FullAlbum, error) {
+	return getMany(ctx, c, kindAlbum, albumIDs, spotify.MaxBatchAlbumIDs, func(ids []string) ([]*spotify.FullAlbum, error) {
+		return c.API.GetAlbums(ctx, accessToken, ids)
+	})
+}
+
+// GetTrack returns the track from the cache or Spotify.
+func (c *Cache) GetTrack(ctx context.Context, accessToken, trackID string) (*spotify.FullTrack, error) {
+	return getOne(ctx, c, kindTrack, trackID, func() (*spotify.FullTrack, error) {
+		return c.API.GetTrack(ctx, accessToken, trackID)
+	})
+}
+
+// GetTracks returns the tracks in the order requested, with nil entries for
+// unknown IDs. Unlike the Spotify call, any number of IDs may be passed.
+func (c *Cache) GetTracks(ctx context.Context, accessToken string, trackIDs []string) ([]*spotify.FullTrack, error) {
+	return getMany(ctx, c, kindTrack, trackIDs, spotify.MaxBatchIDs, func(ids []string) ([]*spotify.FullTrack, error) {
+		return c.API.GetTracks(ctx, accessToken, ids)
+	})
+}
+
+// GetAudioFeatures returns the track's audio features from the cache or Spotify.
+func (c *Cache) GetAudioFeatures(ctx context.Context, accessToken, trackID string) (*spotify.AudioFeatures, error) {
+	return getOne(ctx, c, kindAudioFeatures, trackID, func() (*spotify.AudioFeatures, error) {
+		return c.API.GetAudioFeatures(ctx, accessToken, trackID)
+	})
+}
+
+// getOne looks up a single entity, calling fetch on a miss. Errors from fetch
+// (e.g. a 404) are returned as is and not cached.
+func getOne[T any](ctx context.Context, c *Cache, kind, id string, fetch func() (*T, error)) (*T, error) {
+	items, err := getMany(ctx, c, kind, []string{id}, 1, func([]string) ([]*T, error) {
+		v, err := fetch()
+		if err != nil {
+			return nil, err
+		}
+		return []*T{v}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items[0], nil
+}
+
+// getMany looks up ids of one kind, fetching the ones not cached in chunks of
+// at most batch IDs. fetch must return one entry per requested ID, in order,
+// with nil for unknown IDs; those aren't cached. Entities fetched before a
+// failing chunk are still cached.
+func getMany[T any](ctx context.Context, c *Cache, kind string, ids []string, batch int, fetch func(ids []string) ([]*T, error)) ([]*T, error) {
+	out := make([]*T, len(ids))
+	cached := c.lookup(ctx, kind, ids)
+
+	var missing []string
+	seen := make(map[string]bool)
+	for i, id := range ids {
+		if body, ok := cached[id]; ok {
+			var v T
+			if err := json.Unmarshal(body, &v); err == nil {
+				out[i] = &v
+				continue
+			}
+		}
+		if !seen[id] {
+			seen[id] = true
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return out, nil
+	}
+	metrics.CatalogCacheLookups.WithLabelValues(kind, "miss").Add(float64(len(missing)))
+
+	fetched := make(map[string]*T, len(missing))
+	bodies := make(map[string][]byte, len(missing))
+	var fetchErr error
+	for chunk := range slices.Chunk(missing, batch) {
+		items, err := fetch(chunk)
+		if err != nil {
+			fetchErr = err
+			break
+		}
+		if len(items) != len(chunk) {
+			fetchErr = fmt.Errorf("spotify returned %d entries for %d %s ids", len(items), len(chunk), kind)
+			break
+		}
+		for j, v := range items {
+			if v == nil {
+				continue
+			}
+			fetched[chunk[j]] = v
+			if body, err := json.Marshal(v); err == nil {
+				bodies[chunk[j]] = body
+			}
+		}
+	}
+	c.store(ctx, kind, bodies)
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+
+	for i, id := range ids {
+		if out[i] == nil {
+			out[i] = fetched[id]
+		}
+	}
+	return out, nil
+}
+
+// lookup returns the cached bodies for ids, from memory and then Postgres.
+// IDs not found or expired are absent from the result. Database errors are
+// logged and treated as misses.
+func (c *Cache) lookup(ctx context.Context, kind string, ids []string) map[string][]byte {
+	found := make(map[string][]byte, len(ids))
+	now := time.Now()
+
+	var rest []string
+	queued := make(map[string]bool)
+	for _, id := range ids {
+		if _, ok := found[id]; ok || queued[id] {
+			continue
+		}
+		if body, ok := c.mem.get(kind+":"+id, now); ok {
+			found[id] = body
+			metrics.CatalogCacheLookups.WithLabelValues(kind, "memory").Inc()
+			continue
+		}
+		queued[id] = true
+		rest = append(rest, id)
+	}
+	if c.db == nil || len(rest) == 0 {
+		return found
+	}
+
+	rows, err := c.db.QueryContext(ctx,
+		`SELECT id, body, fetched_at FROM spotify_catalog_cache
+		 WHERE kind = $1 AND id = ANY($2) AND fetched_at > $3`,
+		kind, rest, now.Add(-c.ttl),
+	)
+	if err != nil {
+		slog.WarnContext(ctx, "catalog cache read failed", "kind", kind, "error", err)
+		return found
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id        string
+			body      []byte
+			fetchedAt time.Time
+		)
+		if err := rows.Scan(&id, &body, &fetchedAt); err != nil {
+			slog.WarnContext(ctx, "catalog cache read failed", "kind", kind, "error", err)
+			return found
+		}
+		found[id] = body
+		c.mem.add(kind+":"+id, body, fetchedAt.Add(c.ttl))
+		metrics.CatalogCacheLookups.WithLabelValues(kind, "database").Inc()
+	}
+	if err := rows.Err(); err != nil {
+		slog.WarnContext(ctx, "catalog cache read failed", "kind", kind, "error", err)
+	}
+	return found
+}
+
+// store caches freshly fetched bodies in memory and Postgres. Database errors
+// are logged; the entities are simply fetched again next time.
+func (c *Cache) store(ctx context.Context, kind string, bodies map[string][]byte) {
+	if len(bodies) == 0 {
+		return
+	}
+
+	expires := time.Now().Add(c.ttl)
+	ids := make([]string, 0, len(bodies))
+	docs := make([]string, 0, len(bodies))
+	for id, body := range bodies {
+		c.mem.add(kind+":"+id, body, expires)
+		ids = append(ids, id)
+		docs = append(docs, string(body))
+	}
+	if c.db == nil {
+		return
+	}
+
+	_, err := c.db.ExecContext(ctx,
+		`INSERT INTO spotify_catalog_cache (kind, id, body, fetched_at)
+		 SELECT $1, t.id, t.body::jsonb, now()
+		 FROM unnest($2::text[], $3::text[]) AS t(id, body)
+		 ON CONFLICT (kind, id) DO UPDATE SET body = EXCLUDED.body, fetched_at = EXCLUDED.fetched_at`,
+		kind, ids, docs,
+	)
+	if err != nil {
+		slog.WarnContext(ctx, "catalog cache write failed", "kind", kind, "error", err)
+	}
+}
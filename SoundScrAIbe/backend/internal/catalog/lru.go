@@ -0,0 +1,74 @@
+package catalog
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// lru is a fixed-size in-memory cache of JSON bodies with per-entry expiry.
+// A nil lru stores nothing.
+type lru struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // front is most recently used
+	items map[string]*list.Element
+}
+
+type lruEntry struct {
+	key     string
+	body    []byte
+	expires time.Time
+}
+
+func newLRU(size int) *lru {
+	if size <= 0 {
+		return nil
+	}
+	return &lru{size: size, order: list.New(), items: make(map[string]*list.Element, size)}
+}
+
+// get returns the body stored under key unless it has expired by now.
+func (l *lru) get(key string, now time.Time) ([]byte, bool) {
+	if l == nil {
+		return nil, false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	el, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*lruEntry)
+	if !now.Before(e.expires) {
+		l.order.Remove(el)
+		delete(l.items, key)
+		return nil, false
+	}
+	l.order.MoveToFront(el)
+	return e.body, true
+}
+
+// add stores body under key until expires, evicting the least recently used
+// entry when full.
+func (l *lru) add(key string, body []byte, expires time.Time) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if el, ok := l.items[key]; ok {
+		e := el.Value.(*lruEntry)
+		e.body, e.expires = body, expires
+		l.order.MoveToFront(el)
+		return
+	}
+	l.items[key] = l.order.PushFront(&lruEntry{key: key, body: body, expires: expires})
+	if l.order.Len() > l.size {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.items, oldest.Value.(*lruEntry).key)
+	}
+}
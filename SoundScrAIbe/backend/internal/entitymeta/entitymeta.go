@@ -0,0 +1,307 @@
+package entitymeta
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"soundscraibe/internal/spotify"
+)
+
+// ErrNotFound is returned by Ensure when Spotify doesn't know the entity.
+var ErrNotFound = errors.New("entity not found on spotify")
+
+// Extra is the schema of entity_metadata.extra_json. Fields that don't apply
+// to an entity type are omitted.
+//
+//	artist_name   tracks, albums  first credited artist (kept for older readers)
+//	artists       tracks, albums  credited artists, in order
+//	album         tracks          the track's album
+//	release_date  tracks, albums  Spotify release date: YYYY, YYYY-MM or YYYY-MM-DD
+//	genres        all             the artist's genres; for tracks and albums,
+//	                              those of the credited artists
+//	popularity    all             Spotify popularity, 0-100
+type Extra struct {
+	ArtistName  string   `json:"artist_name,omitempty"`
+	Artists     []Ref    `json:"artists,omitempty"`
+	Album       *Ref     `json:"album,omitempty"`
+	ReleaseDate string   `json:"release_date,omitempty"`
+	Genres      []string `json:"genres,omitempty"`
+	Popularity  int      `json:"popularity"`
+}
+
+// Ref identifies a related Spotify entity by ID and name.
+type Ref struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Metadata is one entity_metadata row as fetched from Spotify.
+type Metadata struct {
+	EntityType string
+	EntityID   string
+	Name       string
+	ImageURL   string
+	Extra      Extra
+}
+
+// Ensure makes sure the entity has a metadata row fetched from Spotify and
+// touches it. Rows already fetched are left to the refresher. If Spotify
+// can't be reached, a blank placeholder is stored for the refresher to fill
+// in; if Spotify doesn't know the entity, ErrNotFound is returned and nothing
+// is stored.
+func Ensure(ctx context.Context, db *sql.DB, sp spotify.API, accessToken, entityType, entityID string) error {
+	var fetched bool
+	err := db.QueryRowContext(ctx,
+		`SELECT refreshed_at IS NOT NULL FROM entity_metadata WHERE entity_type = $1 AND entity_id = $2`,
+		entityType, entityID,
+	).Scan(&fetched)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("checking metadata for %s/%s: %w", entityType, entityID, err)
+	}
+
+	if !fetched {
+		missing, err := Refresh(ctx, db, sp, accessToken, entityType, []string{entityID})
+		switch {
+		case err != nil:
+			if _, placeholderErr := db.ExecContext(ctx,
+				`INSERT INTO entity_metadata (entity_type, entity_id, name)
+				 VALUES ($1, $2, '')
+				 ON CONFLICT ON CONSTRAINT uq_entity_meta DO NOTHING`,
+				entityType, entityID,
+			); placeholderErr != nil {
+				return fmt.Errorf("storing placeholder metadata for %s/%s: %w", entityType, entityID, placeholderErr)
+			}
+			return fmt.Errorf("fetching metadata for %s/%s: %w", entityType, entityID, err)
+		case len(missing) > 0:
+			return fmt.Errorf("%s/%s: %w", entityType, entityID, ErrNotFound)
+		}
+	}
+
+	return Touch(ctx, db, entityType, entityID)
+}
+
+// Touch marks the entity's metadata row as recently used, which orders the
+// library's "recent" view.
+func Touch(ctx context.Context, db *sql.DB, entityType, entityID string) error {
+	_, err := db.ExecContext(ctx,
+		`UPDATE entity_metadata SET updated_at = now() WHERE entity_type = $1 AND entity_id = $2`,
+		entityType, entityID,
+	)
+	if err != nil {
+		return fmt.Errorf("touching metadata for %s/%s: %w", entityType, entityID, err)
+	}
+	return nil
+}
+
+// Refresh fetches canonical metadata for ids of one entity type from Spotify
+// and stores it. It returns the IDs Spotify doesn't know, which are left
+// untouched.
+func Refresh(ctx context.Context, db *sql.DB, sp spotify.API, accessToken, entityType string, ids []string) ([]string, error) {
+	metas, missing, err := Fetch(ctx, sp, accessToken, entityType, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range metas {
+		if err := Save(ctx, db, m); err != nil {
+			return nil, err
+		}
+	}
+	return missing, nil
+}
+
+// Save upserts m and marks it as fetched now. It doesn't count as a touch:
+// updated_at is only set for new rows.
+func Save(ctx context.Context, db *sql.DB, m Metadata) error {
+	extra, err := json.Marshal(m.Extra)
+	if err != nil {
+		return fmt.Errorf("encoding metadata for %s/%s: %w", m.EntityType, m.EntityID, err)
+	}
+	_, err = db.ExecContext(ctx,
+		`INSERT INTO entity_metadata (entity_type, entity_id, name, image_url, extra_json, refreshed_at)
+		 VALUES ($1, $2, $3, $4, $5, now())
+		 ON CONFLICT ON CONSTRAINT uq_entity_meta
+		 DO UPDATE SET name = $3, image_url = $4, extra_json = $5, refreshed_at = now()`,
+		m.EntityType, m.EntityID, m.Name, m.ImageURL, extra,
+	)
+	if err != nil {
+		return fmt.Errorf("saving metadata for %s/%s: %w", m.EntityType, m.EntityID, err)
+	}
+	return nil
+}
+
+// Fetch looks up ids of one entity type ("track", "album" or "artist") with
+// Spotify's batch endpoints, plus one batch of artist lookups for the genres
+// of tracks and albums. IDs Spotify doesn't know are returned as missing.
+func Fetch(ctx context.Context, sp spotify.API, accessToken, entityType string, ids []string) ([]Metadata, []string, error) {
+	var (
+		metas   []Metadata
+		missing []string
+		artists []string // credited artists whose genres are needed
+	)
+
+	switch entityType {
+	case "track":
+		for chunk := range slices.Chunk(ids, spotify.MaxBatchIDs) {
+			tracks, err := sp.GetTracks(ctx, accessToken, chunk)
+			if err != nil {
+				return nil, nil, fmt.Errorf("fetching tracks: %w", err)
+			}
+			for i, id := range chunk {
+				if i >= len(tracks) || tracks[i] == nil {
+					missing = append(missing, id)
+					continue
+				}
+				m := fromTrack(tracks[i])
+				m.EntityID = id // Spotify may relink to another ID
+				metas = append(metas, m)
+				artists = appendRefIDs(artists, m.Extra.Artists)
+			}
+		}
+	case "album":
+		for chunk := range slices.Chunk(ids, spotify.MaxBatchAlbumIDs) {
+			albums, err := sp.GetAlbums(ctx, accessToken, chunk)
+			if err != nil {
+				return nil, nil, fmt.Errorf("fetching albums: %w", err)
+			}
+			for i, id := range chunk {
+				if i >= len(albums) || albums[i] == nil {
+					missing = append(missing, id)
+					continue
+				}
+				m := fromAlbum(albums[i])
+				m.EntityID = id
+				metas = append(metas, m)
+				if len(m.Extra.Genres) == 0 {
+					artists = appendRefIDs(artists, m.Extra.Artists)
+				}
+			}
+		}
+	case "artist":
+		for chunk := range slices.Chunk(ids, spotify.MaxBatchIDs) {
+			found, err := sp.GetArtists(ctx, accessToken, chunk)
+			if err != nil {
+				return nil, nil, fmt.Errorf("fetching artists: %w", err)
+			}
+			for i, id := range chunk {
+				if i >= len(found) || found[i] == nil {
+					missing = append(missing, id)
+					continue
+				}
+				m := fromArtist(found[i])
+				m.EntityID = id
+				metas = append(metas, m)
+			}
+		}
+		return metas, missing, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown entity type %q", entityType)
+	}
+
+	genres, err := artistGenres(ctx, sp, accessToken, artists)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range metas {
+		if len(metas[i].Extra.Genres) > 0 {
+			continue
+		}
+		for _, a := range metas[i].Extra.Artists {
+			for _, g := range genres[a.ID] {
+				if !slices.Contains(metas[i].Extra.Genres, g) {
+					metas[i].Extra.Genres = append(metas[i].Extra.Genres, g)
+				}
+			}
+		}
+	}
+	return metas, missing, nil
+}
+
+// artistGenres returns the genres of each artist, keyed by ID.
+func artistGenres(ctx context.Context, sp spotify.API, accessToken string, ids []string) (map[string][]string, error) {
+	genres := make(map[string][]string, len(ids))
+	for chunk := range slices.Chunk(ids, spotify.MaxBatchIDs) {
+		artists, err := sp.GetArtists(ctx, accessToken, chunk)
+		if err != nil {
+			return nil, fmt.Errorf("fetching artist genres: %w", err)
+		}
+		for _, a := range artists {
+			if a != nil {
+				genres[a.ID] = a.Genres
+			}
+		}
+	}
+	return genres, nil
+}
+
+// appendRefIDs appends the IDs of refs not already in ids.
+func appendRefIDs(ids []string, refs []Ref) []string {
+	for _, r := range refs {
+		if r.ID != "" && !slices.Contains(ids, r.ID) {
+			ids = append(ids, r.ID)
+		}
+	}
+	return ids
+}
+
+func fromTrack(t *spotify.FullTrack) Metadata {
+	m := Metadata{
+		EntityType: "track",
+		EntityID:   t.ID,
+		Name:       t.Name,
+		Extra: Extra{
+			Album:       &Ref{ID: t.Album.ID, Name: t.Album.Name},
+			ReleaseDate: t.Album.ReleaseDate,
+			Popularity:  t.Popularity,
+		},
+	}
+	if len(t.Album.Images) > 0 {
+		m.ImageURL = t.Album.Images[0].URL
+	}
+	for _, a := range t.Artists {
+		m.Extra.Artists = append(m.Extra.Artists, Ref{ID: a.ID, Name: a.Name})
+	}
+	if len(t.Artists) > 0 {
+		m.Extra.ArtistName = t.Artists[0].Name
+	}
+	return m
+}
+
+func fromAlbum(a *spotify.FullAlbum) Metadata {
+	m := Metadata{
+		EntityType: "album",
+		EntityID:   a.ID,
+		Name:       a.Name,
+		Extra: Extra{
+			ReleaseDate: a.ReleaseDate,
+			Genres:      a.Genres,
+			Popularity:  a.Popularity,
+		},
+	}
+	if len(a.Images) > 0 {
+		m.ImageURL = a.Images[0].URL
+	}
+	for _, ar := range a.Artists {
+		m.Extra.Artists = append(m.Extra.Artists, Ref{ID: ar.ID, Name: ar.Name})
+	}
+	if len(a.Artists) > 0 {
+		m.Extra.ArtistName = a.Artists[0].Name
+	}
+	return m
+}
+
+func fromArtist(a *spotify.TopArtist) Metadata {
+	return Metadata{
+		EntityType: "artist",
+		EntityID:   a.ID,
+		Name:       a.Name,
+		ImageURL:   a.ImageURL(),
+		Extra: Extra{
+			Genres:     a.Genres,
+			Popularity: a.Popularity,
+		},
+	}
+}
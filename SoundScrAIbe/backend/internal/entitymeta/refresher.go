@@ -0,0 +1,110 @@
+package entitymeta
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"soundscraibe/internal/spotify"
+)
+
+// refreshBatch is the most rows of each entity type refreshed per run.
+const refreshBatch = 200
+
+// RunRefresher re-fetches metadata rows older than maxAge, and placeholders
+// that were never fetched, every interval until ctx is cancelled. It uses an
+// app token from the client credentials flow, so it needs a client secret.
+func RunRefresher(ctx context.Context, db *sql.DB, sp spotify.API, auth *spotify.Config, interval, maxAge time.Duration) {
+	slog.InfoContext(ctx, "metadata refresher started", "interval", interval, "max_age", maxAge)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := RefreshStale(ctx, db, sp, auth, maxAge); err != nil {
+			slog.ErrorContext(ctx, "metadata refresh failed", "error", err)
+		} else if n > 0 {
+			slog.InfoContext(ctx, "refreshed metadata", "count", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "metadata refresher stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RefreshStale refreshes up to refreshBatch stale rows of each entity type,
+// never-fetched ones first, and returns how many were updated. Rows Spotify no
+// longer knows are marked as checked so they aren't retried every run.
+func RefreshStale(ctx context.Context, db *sql.DB, sp spotify.API, auth *spotify.Config, maxAge time.Duration) (int, error) {
+	var token string
+	refreshed := 0
+
+	for _, entityType := range []string{"track", "album", "artist"} {
+		ids, err := staleIDs(ctx, db, entityType, time.Now().Add(-maxAge))
+		if err != nil {
+			return refreshed, err
+		}
+		if len(ids) == 0 {
+			continue
+		}
+
+		// Only ask for an app token once there's something to refresh.
+		if token == "" {
+			tok, err := auth.ClientCredentialsToken(ctx)
+			if err != nil {
+				return refreshed, fmt.Errorf("getting app token: %w", err)
+			}
+			token = tok.AccessToken
+		}
+
+		missing, err := Refresh(ctx, db, sp, token, entityType, ids)
+		if err != nil {
+			return refreshed, fmt.Errorf("refreshing %ss: %w", entityType, err)
+		}
+		refreshed += len(ids) - len(missing)
+
+		if len(missing) > 0 {
+			_, err := db.ExecContext(ctx,
+				`UPDATE entity_metadata SET refreshed_at = now()
+				 WHERE entity_type = $1 AND entity_id = ANY($2)`,
+				entityType, missing,
+			)
+			if err != nil {
+				return refreshed, fmt.Errorf("marking unknown %ss: %w", entityType, err)
+			}
+		}
+	}
+	return refreshed, nil
+}
+
+// staleIDs returns the IDs of rows of entityType fetched before cutoff or
+// never fetched at all.
+func staleIDs(ctx context.Context, db *sql.DB, entityType string, cutoff time.Time) ([]string, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT entity_id FROM entity_metadata
+		 WHERE entity_type = $1 AND (refreshed_at IS NULL OR refreshed_at < $2)
+		 ORDER BY refreshed_at NULLS FIRST
+		 LIMIT $3`,
+		entityType, cutoff, refreshBatch,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying stale %ss: %w", entityType, err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scanning stale %s: %w", entityType, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,260 @@
+package fakespotify
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+
+	"soundscraibe/internal/spotify"
+)
+
+//go:embed fixtures/catalog.json
+var fixtures embed.FS
+
+// catalog is the fixture data the fake server answers from.
+type catalog struct {
+	User struct {
+		ID          string `json:"id"`
+		DisplayName string `json:"display_name"`
+		Email       string `json:"email"`
+		Country     string `json:"country"`
+		Product     string `json:"product"`
+		Followers   int    `json:"followers"`
+	} `json:"user"`
+	Artists        []fixtureArtist `json:"artists"`
+	Albums         []fixtureAlbum  `json:"albums"`
+	Tracks         []fixtureTrack  `json:"tracks"`
+	RecentlyPlayed []string        `json:"recently_played"` // track IDs cycled through the synthetic history
+	Library        struct {
+		Tracks  []string `json:"tracks"`
+		Albums  []string `json:"albums"`
+		Artists []string `json:"artists"`
+	} `json:"library"`
+
+	artists map[string]*fixtureArtist
+	albums  map[string]*fixtureAlbum
+	tracks  map[string]*fixtureTrack
+}
+
+type fixtureArtist struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Genres     []string `json:"genres"`
+	Popularity int      `json:"popularity"`
+	Followers  int      `json:"followers"`
+}
+
+type fixtureAlbum struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	AlbumType   string   `json:"album_type"`
+	ReleaseDate string   `json:"release_date"`
+	Label       string   `json:"label"`
+	Popularity  int      `json:"popularity"`
+	ArtistIDs   []string `json:"artist_ids"`
+}
+
+type fixtureTrack struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	DurationMs  int      `json:"duration_ms"`
+	Explicit    bool     `json:"explicit"`
+	TrackNumber int      `json:"track_number"`
+	AlbumID     string   `json:"album_id"`
+	ArtistIDs   []string `json:"artist_ids"`
+}
+
+// loadCatalog parses the embedded fixtures and checks that every reference
+// points at something that exists.
+func loadCatalog() (*catalog, error) {
+	data, err := fixtures.ReadFile("fixtures/catalog.json")
+	if err != nil {
+		return nil, fmt.Errorf("reading fixtures: %w", err)
+	}
+	var c catalog
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("parsing fixtures: %w", err)
+	}
+
+	c.artists = make(map[string]*fixtureArtist, len(c.Artists))
+	for i := range c.Artists {
+		c.artists[c.Artists[i].ID] = &c.Artists[i]
+	}
+	c.albums = make(map[string]*fixtureAlbum, len(c.Albums))
+	for i := range c.Albums {
+		a := &c.Albums[i]
+		if err := c.checkArtists(a.ID, a.ArtistIDs); err != nil {
+			return nil, err
+		}
+		c.albums[a.ID] = a
+	}
+	c.tracks = make(map[string]*fixtureTrack, len(c.Tracks))
+	for i := range c.Tracks {
+		t := &c.Tracks[i]
+		if c.albums[t.AlbumID] == nil {
+			return nil, fmt.Errorf("fixture track %s: unknown album %s", t.ID, t.AlbumID)
+		}
+		if err := c.checkArtists(t.ID, t.ArtistIDs); err != nil {
+			return nil, err
+		}
+		c.tracks[t.ID] = t
+	}
+
+	for _, id := range append(append([]string{}, c.RecentlyPlayed...), c.Library.Tracks...) {
+		if c.tracks[id] == nil {
+			return nil, fmt.Errorf("fixture references unknown track %s", id)
+		}
+	}
+	for _, id := range c.Library.Albums {
+		if c.albums[id] == nil {
+			return nil, fmt.Errorf("fixture library references unknown album %s", id)
+		}
+	}
+	if err := c.checkArtists("library", c.Library.Artists); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (c *catalog) checkArtists(owner string, ids []string) error {
+	for _, id := range ids {
+		if c.artists[id] == nil {
+			return fmt.Errorf("fixture %s: unknown artist %s", owner, id)
+		}
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// Fixture → Spotify response shapes
+// ---------------------------------------------------------------------------
+//
+// Responses reuse the spotify package's types so the fake can't drift from
+// what the client parses.
+
+func (c *catalog) artistRefs(ids []string) []spotify.Artist {
+	refs := make([]spotify.Artist, len(ids))
+	for i, id := range ids {
+		refs[i] = spotify.Artist{ID: id, Name: c.artists[id].Name}
+	}
+	return refs
+}
+
+func (c *catalog) fullArtist(a *fixtureArtist, base string) spotify.TopArtist {
+	return spotify.TopArtist{
+		ID:           a.ID,
+		Name:         a.Name,
+		Genres:       a.Genres,
+		Popularity:   a.Popularity,
+		Followers:    spotify.Followers{Total: a.Followers},
+		Images:       images(base, a.ID),
+		ExternalURLs: externalURLs("artist", a.ID),
+	}
+}
+
+func (c *catalog) albumRef(a *fixtureAlbum, base string) spotify.Album {
+	return spotify.Album{ID: a.ID, Name: a.Name, ReleaseDate: a.ReleaseDate, Images: images(base, a.ID)}
+}
+
+func (c *catalog) fullAlbum(a *fixtureAlbum, base string) spotify.FullAlbum {
+	total := 0
+	for _, t := range c.Tracks {
+		if t.AlbumID == a.ID {
+			total++
+		}
+	}
+	var genres []string
+	for _, id := range a.ArtistIDs {
+		genres = append(genres, c.artists[id].Genres...)
+	}
+	return spotify.FullAlbum{
+		ID:           a.ID,
+		Name:         a.Name,
+		AlbumType:    a.AlbumType,
+		ReleaseDate:  a.ReleaseDate,
+		TotalTracks:  total,
+		Label:        a.Label,
+		Popularity:   a.Popularity,
+		Genres:       genres,
+		Artists:      c.artistRefs(a.ArtistIDs),
+		Images:       images(base, a.ID),
+		ExternalURLs: externalURLs("album", a.ID),
+	}
+}
+
+func (c *catalog) searchAlbum(a *fixtureAlbum, base string) spotify.SearchAlbum {
+	return spotify.SearchAlbum{
+		ID:           a.ID,
+		Name:         a.Name,
+		AlbumType:    a.AlbumType,
+		ReleaseDate:  a.ReleaseDate,
+		Artists:      c.artistRefs(a.ArtistIDs),
+		Images:       images(base, a.ID),
+		ExternalURLs: externalURLs("album", a.ID),
+	}
+}
+
+func (c *catalog) track(t *fixtureTrack, base string) spotify.Track {
+	return spotify.Track{
+		ID:         t.ID,
+		Name:       t.Name,
+		DurationMs: t.DurationMs,
+		Artists:    c.artistRefs(t.ArtistIDs),
+		Album:      c.albumRef(c.albums[t.AlbumID], base),
+	}
+}
+
+func (c *catalog) fullTrack(t *fixtureTrack, base string) spotify.FullTrack {
+	artists := make([]spotify.FullArtist, len(t.ArtistIDs))
+	for i, id := range t.ArtistIDs {
+		artists[i] = spotify.FullArtist{ID: id, Name: c.artists[id].Name}
+	}
+	return spotify.FullTrack{
+		ID:           t.ID,
+		Name:         t.Name,
+		DurationMs:   t.DurationMs,
+		Explicit:     t.Explicit,
+		TrackNumber:  t.TrackNumber,
+		DiscNumber:   1,
+		Popularity:   c.albums[t.AlbumID].Popularity,
+		Artists:      artists,
+		Album:        c.fullAlbum(c.albums[t.AlbumID], base),
+		ExternalURLs: externalURLs("track", t.ID),
+	}
+}
+
+// audioFeatures derives stable pseudo-random features from the track ID.
+func audioFeatures(id string) spotify.AudioFeatures {
+	h := fnv.New64a()
+	h.Write([]byte(id))
+	seed := h.Sum64()
+	next := func() float64 {
+		seed = seed*6364136223846793005 + 1442695040888963407
+		return float64(seed>>11) / float64(1<<53)
+	}
+	return spotify.AudioFeatures{
+		Danceability:     next(),
+		Energy:           next(),
+		Acousticness:     next(),
+		Instrumentalness: next(),
+		Liveness:         next() * 0.4,
+		Speechiness:      next() * 0.3,
+		Valence:          next(),
+		Tempo:            70 + next()*110,
+		Key:              int(next() * 12),
+		Mode:             int(next() * 2),
+		Loudness:         -14 + next()*10,
+		TimeSignature:    4,
+	}
+}
+
+// images points at the fake server's own placeholder artwork, so pages render
+// without reaching the internet.
+func images(base, id string) []spotify.Image {
+	return []spotify.Image{{URL: base + "/images/" + id + ".svg", Height: 300, Width: 300}}
+}
+
+func externalURLs(kind, id string) spotify.ExternalURLs {
+	return spotify.ExternalURLs{Spotify: "https://open.spotify.com/" + kind + "/" + id}
+}
@@ -0,0 +1,735 @@
+package fakespotify
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"soundscraibe/internal/spotify"
+)
+
+// ---------------------------------------------------------------------------
+// Fake Spotify server
+// ---------------------------------------------------------------------------
+//
+// Server answers the accounts-service and Web API endpoints the app uses from
+// the embedded fixture catalog, so the backend can run end-to-end offline.
+// Point SPOTIFY_ACCOUNTS_BASE_URL at the server root and SPOTIFY_API_BASE_URL
+// at <root>/v1. Any bearer token is accepted; liked songs live in memory.
+
+// playInterval is the spacing of the synthetic listening history.
+const playInterval = 4 * time.Minute
+
+// historyWindow is how far back the synthetic listening history goes.
+const historyWindow = 24 * time.Hour
+
+// Server is the fake Spotify HTTP server.
+type Server struct {
+	cat *catalog
+	mux *http.ServeMux
+	now func() time.Time
+
+	mu         sync.Mutex
+	savedTrack map[string]time.Time // track ID → added at
+}
+
+// New returns a fake Spotify server loaded with the embedded fixtures.
+func New() (*Server, error) {
+	cat, err := loadCatalog()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		cat:        cat,
+		mux:        http.NewServeMux(),
+		now:        time.Now,
+		savedTrack: make(map[string]time.Time),
+	}
+	added := s.now().Add(-time.Duration(len(cat.Library.Tracks)) * time.Hour)
+	for i, id := range cat.Library.Tracks {
+		s.savedTrack[id] = added.Add(time.Duration(i) * time.Hour)
+	}
+
+	// Accounts service
+	s.mux.HandleFunc("GET /authorize", s.authorize)
+	s.mux.HandleFunc("POST /api/token", s.token)
+	s.mux.HandleFunc("GET /images/{file}", s.image)
+
+	// Web API
+	s.mux.HandleFunc("GET /v1/me", s.authed(s.profile))
+	s.mux.HandleFunc("GET /v1/me/top/artists", s.authed(s.topArtists))
+	s.mux.HandleFunc("GET /v1/me/top/tracks", s.authed(s.topTracks))
+	s.mux.HandleFunc("GET /v1/me/player/recently-played", s.authed(s.recentlyPlayed))
+	s.mux.HandleFunc("GET /v1/artists/{id}", s.authed(s.artist))
+	s.mux.HandleFunc("GET /v1/artists", s.authed(s.artists))
+	s.mux.HandleFunc("GET /v1/tracks/{id}", s.authed(s.track))
+	s.mux.HandleFunc("GET /v1/tracks", s.authed(s.tracks))
+	s.mux.HandleFunc("GET /v1/albums/{id}", s.authed(s.album))
+	s.mux.HandleFunc("GET /v1/albums", s.authed(s.albums))
+	s.mux.HandleFunc("GET /v1/audio-features/{id}", s.authed(s.audioFeatures))
+	s.mux.HandleFunc("GET /v1/search", s.authed(s.search))
+	s.mux.HandleFunc("GET /v1/me/library/contains", s.authed(s.libraryContains))
+	s.mux.HandleFunc("PUT /v1/me/library", s.authed(s.librarySave))
+	s.mux.HandleFunc("DELETE /v1/me/library", s.authed(s.libraryRemove))
+	s.mux.HandleFunc("GET /v1/me/tracks", s.authed(s.savedTracks))
+	s.mux.HandleFunc("GET /v1/me/albums", s.authed(s.savedAlbums))
+	s.mux.HandleFunc("GET /v1/me/following", s.authed(s.followedArtists))
+
+	return s, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Printf("fakespotify: %s %s", r.Method, r.URL.RequestURI())
+	s.mux.ServeHTTP(w, r)
+}
+
+// authed rejects requests without a bearer token, like the real API.
+func (s *Server) authed(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || strings.TrimSpace(token) == "" {
+			writeError(w, http.StatusUnauthorized, "No token provided")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Accounts service
+// ---------------------------------------------------------------------------
+
+// authorize skips the login page and sends the browser straight back to the
+// app with a code.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || redirect.Scheme == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	params := redirect.Query()
+	params.Set("code", "fake-code-"+randomHex(8))
+	if state := q.Get("state"); state != "" {
+		params.Set("state", state)
+	}
+	redirect.RawQuery = params.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+// token issues fresh tokens for both the authorization_code and
+// refresh_token grants.
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	resp := spotify.TokenResponse{
+		AccessToken: "fake-access-" + randomHex(16),
+		ExpiresIn:   3600,
+		TokenType:   "Bearer",
+	}
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		if r.PostForm.Get("code") == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		resp.RefreshToken = "fake-refresh-" + randomHex(16)
+	case "refresh_token":
+		// Unknown refresh tokens get the same error Spotify returns for a
+		// revoked grant.
+		if !strings.HasPrefix(r.PostForm.Get("refresh_token"), "fake-refresh-") {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "Refresh token revoked"})
+			return
+		}
+		// Spotify may omit the refresh token when it doesn't rotate it.
+	case "client_credentials":
+		// App tokens come without a refresh token.
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// image serves a flat placeholder cover, coloured by ID.
+func (s *Server) image(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSuffix(r.PathValue("file"), ".svg")
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="300" height="300"><rect width="300" height="300" fill="hsl(%d,45%%,35%%)"/></svg>`, h.Sum32()%360)
+}
+
+// ---------------------------------------------------------------------------
+// Web API
+// ---------------------------------------------------------------------------
+
+func (s *Server) profile(w http.ResponseWriter, r *http.Request) {
+	u := s.cat.User
+	writeJSON(w, http.StatusOK, spotify.Profile{
+		ID:          u.ID,
+		DisplayName: u.DisplayName,
+		Email:       u.Email,
+		Country:     u.Country,
+		Product:     u.Product,
+		Followers:   spotify.Followers{Total: u.Followers},
+		Images:      images(baseURL(r), u.ID),
+	})
+}
+
+// topArtists ranks artists by plays in the synthetic history, then popularity.
+func (s *Server) topArtists(w http.ResponseWriter, r *http.Request) {
+	plays := s.playCounts()
+	counts := make(map[string]int)
+	for id, n := range plays {
+		for _, a := range s.cat.tracks[id].ArtistIDs {
+			counts[a] += n
+		}
+	}
+
+	artists := make([]*fixtureArtist, len(s.cat.Artists))
+	for i := range s.cat.Artists {
+		artists[i] = &s.cat.Artists[i]
+	}
+	sort.SliceStable(artists, func(i, j int) bool {
+		if counts[artists[i].ID] != counts[artists[j].ID] {
+			return counts[artists[i].ID] > counts[artists[j].ID]
+		}
+		return artists[i].Popularity > artists[j].Popularity
+	})
+
+	base := baseURL(r)
+	items := []spotify.TopArtist{}
+	for _, a := range artists[:min(len(artists), queryInt(r, "limit", 20))] {
+		items = append(items, s.cat.fullArtist(a, base))
+	}
+	writeJSON(w, http.StatusOK, spotify.TopArtistsResponse{Items: items})
+}
+
+// topTracks ranks tracks by plays in the synthetic history.
+func (s *Server) topTracks(w http.ResponseWriter, r *http.Request) {
+	plays := s.playCounts()
+	ids := make([]string, 0, len(plays))
+	for id := range plays {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if plays[ids[i]] != plays[ids[j]] {
+			return plays[ids[i]] > plays[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+
+	base := baseURL(r)
+	items := []spotify.TopTrack{}
+	for _, id := range ids[:min(len(ids), queryInt(r, "limit", 20))] {
+		t := s.cat.track(s.cat.tracks[id], base)
+		items = append(items, spotify.TopTrack{
+			ID:           t.ID,
+			Name:         t.Name,
+			DurationMs:   t.DurationMs,
+			Artists:      t.Artists,
+			Album:        t.Album,
+			ExternalURLs: externalURLs("track", t.ID),
+		})
+	}
+	writeJSON(w, http.StatusOK, spotify.TopTracksResponse{Items: items})
+}
+
+// recentlyPlayed serves a synthetic history: one play every playInterval,
+// cycling through the fixture's recently_played tracks. Slots sit on a fixed
+// grid, so repeated syncs see the same plays. With after, it returns the
+// oldest page of plays newer than the cursor, so paging forward catches up.
+func (s *Server) recentlyPlayed(w http.ResponseWriter, r *http.Request) {
+	limit := min(queryInt(r, "limit", 20), 50)
+	after := int64(queryInt(r, "after", 0))
+
+	slots := s.playSlots()
+	if after > 0 {
+		var newer []int64
+		for _, slot := range slots {
+			if slot*playInterval.Milliseconds() > after {
+				newer = append(newer, slot)
+			}
+		}
+		// slots are newest first; keep the oldest page after the cursor.
+		slots = newer[max(0, len(newer)-limit):]
+	} else {
+		slots = slots[:min(len(slots), limit)]
+	}
+
+	base := baseURL(r)
+	items := []spotify.PlayHistoryItem{}
+	for _, slot := range slots {
+		t := s.cat.tracks[s.slotTrack(slot)]
+		items = append(items, spotify.PlayHistoryItem{
+			Track:    s.cat.track(t, base),
+			PlayedAt: time.UnixMilli(slot * playInterval.Milliseconds()).UTC().Format(time.RFC3339),
+		})
+	}
+
+	resp := spotify.RecentlyPlayedResponse{Items: items}
+	if len(items) > 0 {
+		resp.Cursors = &struct {
+			After  string `json:"after"`
+			Before string `json:"before"`
+		}{
+			After:  strconv.FormatInt(slots[0]*playInterval.Milliseconds(), 10),
+			Before: strconv.FormatInt(slots[len(slots)-1]*playInterval.Milliseconds(), 10),
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) artist(w http.ResponseWriter, r *http.Request) {
+	a := s.cat.artists[r.PathValue("id")]
+	if a == nil {
+		writeError(w, http.StatusNotFound, "Resource not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.cat.fullArtist(a, baseURL(r)))
+}
+
+// artists answers a batch lookup; unknown IDs come back as null.
+func (s *Server) artists(w http.ResponseWriter, r *http.Request) {
+	ids := splitList(r.URL.Query().Get("ids"))
+	if len(ids) > spotify.MaxBatchIDs {
+		writeError(w, http.StatusBadRequest, "Too many ids requested")
+		return
+	}
+
+	base := baseURL(r)
+	result := struct {
+		Artists []*spotify.TopArtist `json:"artists"`
+	}{Artists: make([]*spotify.TopArtist, len(ids))}
+	for i, id := range ids {
+		if a := s.cat.artists[id]; a != nil {
+			full := s.cat.fullArtist(a, base)
+			result.Artists[i] = &full
+		}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) track(w http.ResponseWriter, r *http.Request) {
+	t := s.cat.tracks[r.PathValue("id")]
+	if t == nil {
+		writeError(w, http.StatusNotFound, "Resource not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.cat.fullTrack(t, baseURL(r)))
+}
+
+// tracks answers a batch lookup; unknown IDs come back as null, as on Spotify.
+func (s *Server) tracks(w http.ResponseWriter, r *http.Request) {
+	ids := splitList(r.URL.Query().Get("ids"))
+	if len(ids) > spotify.MaxBatchIDs {
+		writeError(w, http.StatusBadRequest, "Too many ids requested")
+		return
+	}
+
+	base := baseURL(r)
+	result := struct {
+		Tracks []*spotify.FullTrack `json:"tracks"`
+	}{Tracks: make([]*spotify.FullTrack, len(ids))}
+	for i, id := range ids {
+		if t := s.cat.tracks[id]; t != nil {
+			full := s.cat.fullTrack(t, base)
+			result.Tracks[i] = &full
+		}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) album(w http.ResponseWriter, r *http.Request) {
+	a := s.cat.albums[r.PathValue("id")]
+	if a == nil {
+		writeError(w, http.StatusNotFound, "Resource not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.cat.fullAlbum(a, baseURL(r)))
+}
+
+// albums answers a batch lookup; unknown IDs come back as null.
+func (s *Server) albums(w http.ResponseWriter, r *http.Request) {
+	ids := splitList(r.URL.Query().Get("ids"))
+	if len(ids) > spotify.MaxBatchAlbumIDs {
+		writeError(w, http.StatusBadRequest, "Too many ids requested")
+		return
+	}
+
+	base := baseURL(r)
+	result := struct {
+		Albums []*spotify.FullAlbum `json:"albums"`
+	}{Albums: make([]*spotify.FullAlbum, len(ids))}
+	for i, id := range ids {
+		if a := s.cat.albums[id]; a != nil {
+			full := s.cat.fullAlbum(a, base)
+			result.Albums[i] = &full
+		}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) audioFeatures(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if s.cat.tracks[id] == nil {
+		writeError(w, http.StatusNotFound, "Resource not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, audioFeatures(id))
+}
+
+// search matches every query word (field filters like "artist:" are ignored)
+// against each item's name and its artists' names, best match first.
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	terms := searchTerms(q.Get("q"))
+	if len(terms) == 0 {
+		writeError(w, http.StatusBadRequest, "No search query")
+		return
+	}
+	limit := min(queryInt(r, "limit", 20), 50)
+	base := baseURL(r)
+
+	var resp spotify.SearchResponse
+	for _, kind := range splitList(q.Get("type")) {
+		switch kind {
+		case "track":
+			items := []spotify.SearchTrack{}
+			for _, i := range rank(len(s.cat.Tracks), limit, func(i int) (string, string) {
+				t := &s.cat.Tracks[i]
+				return t.Name, s.cat.artistNames(t.ArtistIDs) + " " + s.cat.albums[t.AlbumID].Name
+			}, terms) {
+				t := s.cat.track(&s.cat.Tracks[i], base)
+				items = append(items, spotify.SearchTrack{ID: t.ID, Name: t.Name, DurationMs: t.DurationMs, Artists: t.Artists, Album: t.Album})
+			}
+			resp.Tracks = &spotify.SearchTracks{Items: items}
+		case "album":
+			items := []spotify.SearchAlbum{}
+			for _, i := range rank(len(s.cat.Albums), limit, func(i int) (string, string) {
+				a := &s.cat.Albums[i]
+				return a.Name, s.cat.artistNames(a.ArtistIDs)
+			}, terms) {
+				items = append(items, s.cat.searchAlbum(&s.cat.Albums[i], base))
+			}
+			resp.Albums = &spotify.SearchAlbums{Items: items}
+		case "artist":
+			items := []spotify.SearchArtist{}
+			for _, i := range rank(len(s.cat.Artists), limit, func(i int) (string, string) {
+				return s.cat.Artists[i].Name, ""
+			}, terms) {
+				a := s.cat.fullArtist(&s.cat.Artists[i], base)
+				items = append(items, spotify.SearchArtist{
+					ID: a.ID, Name: a.Name, Genres: a.Genres, Popularity: a.Popularity,
+					Followers: a.Followers, Images: a.Images, ExternalURLs: a.ExternalURLs,
+				})
+			}
+			resp.Artists = &spotify.SearchArtists{Items: items}
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) libraryContains(w http.ResponseWriter, r *http.Request) {
+	ids := trackURIs(r)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]bool, len(ids))
+	for i, id := range ids {
+		_, result[i] = s.savedTrack[id]
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) librarySave(w http.ResponseWriter, r *http.Request) {
+	ids := trackURIs(r)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		if s.cat.tracks[id] == nil {
+			writeError(w, http.StatusBadRequest, "Invalid id "+id)
+			return
+		}
+	}
+	for _, id := range ids {
+		if _, ok := s.savedTrack[id]; !ok {
+			s.savedTrack[id] = s.now()
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) libraryRemove(w http.ResponseWriter, r *http.Request) {
+	ids := trackURIs(r)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		delete(s.savedTrack, id)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// savedTracks lists liked songs, most recently added first.
+func (s *Server) savedTracks(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	ids := make([]string, 0, len(s.savedTrack))
+	for id := range s.savedTrack {
+		ids = append(ids, id)
+	}
+	added := make(map[string]time.Time, len(ids))
+	for _, id := range ids {
+		added[id] = s.savedTrack[id]
+	}
+	s.mu.Unlock()
+
+	sort.Slice(ids, func(i, j int) bool { return added[ids[i]].After(added[ids[j]]) })
+
+	base := baseURL(r)
+	page, next := paginate(r, len(ids))
+	items := []spotify.SavedTrackItem{}
+	for _, id := range ids[page.start:page.end] {
+		items = append(items, spotify.SavedTrackItem{
+			AddedAt: added[id].UTC().Format(time.RFC3339),
+			Track:   s.cat.fullTrack(s.cat.tracks[id], base),
+		})
+	}
+	writeJSON(w, http.StatusOK, spotify.SavedTracksResponse{Items: items, Total: len(ids), Next: next})
+}
+
+func (s *Server) savedAlbums(w http.ResponseWriter, r *http.Request) {
+	ids := s.cat.Library.Albums
+	base := baseURL(r)
+	page, next := paginate(r, len(ids))
+	items := []spotify.SavedAlbumItem{}
+	for i, id := range ids[page.start:page.end] {
+		items = append(items, spotify.SavedAlbumItem{
+			AddedAt: s.now().Add(-time.Duration(page.start+i+1) * 24 * time.Hour).UTC().Format(time.RFC3339),
+			Album:   s.cat.fullAlbum(s.cat.albums[id], base),
+		})
+	}
+	writeJSON(w, http.StatusOK, spotify.SavedAlbumsResponse{Items: items, Total: len(ids), Next: next})
+}
+
+// followedArtists pages with an artist-ID cursor, like the real endpoint.
+func (s *Server) followedArtists(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("type") != "artist" {
+		writeError(w, http.StatusBadRequest, "Only type=artist is supported")
+		return
+	}
+
+	ids := s.cat.Library.Artists
+	start := 0
+	if after := r.URL.Query().Get("after"); after != "" {
+		for i, id := range ids {
+			if id == after {
+				start = i + 1
+				break
+			}
+		}
+	}
+	end := min(len(ids), start+min(queryInt(r, "limit", 20), 50))
+
+	base := baseURL(r)
+	resp := spotify.FollowedArtistsResponse{Items: []spotify.TopArtist{}, Total: len(ids)}
+	for _, id := range ids[start:end] {
+		resp.Items = append(resp.Items, s.cat.fullArtist(s.cat.artists[id], base))
+	}
+	if end < len(ids) {
+		cursor := ids[end-1]
+		next := base + "/v1/me/following?type=artist&after=" + cursor
+		resp.Next = &next
+		resp.Cursors = &struct {
+			After string `json:"after"`
+		}{After: cursor}
+	}
+	writeJSON(w, http.StatusOK, spotify.FollowedArtistsWrapper{Artists: resp})
+}
+
+// ---------------------------------------------------------------------------
+// Helpers
+// ---------------------------------------------------------------------------
+
+// playSlots returns the grid slots (multiples of playInterval since the epoch)
+// inside the history window, newest first.
+func (s *Server) playSlots() []int64 {
+	step := playInterval.Milliseconds()
+	newest := s.now().UnixMilli() / step
+	n := int64(historyWindow / playInterval)
+	slots := make([]int64, 0, n)
+	for slot := newest; slot > newest-n; slot-- {
+		slots = append(slots, slot)
+	}
+	return slots
+}
+
+// slotTrack picks the track played in a slot. It hashes the slot so the
+// rotation doesn't look mechanical but stays the same across requests.
+func (s *Server) slotTrack(slot int64) string {
+	h := fnv.New64a()
+	fmt.Fprint(h, slot)
+	ids := s.cat.RecentlyPlayed
+	return ids[h.Sum64()%uint64(len(ids))]
+}
+
+// playCounts counts plays per track over the history window.
+func (s *Server) playCounts() map[string]int {
+	counts := make(map[string]int)
+	for _, slot := range s.playSlots() {
+		counts[s.slotTrack(slot)]++
+	}
+	return counts
+}
+
+func (c *catalog) artistNames(ids []string) string {
+	names := make([]string, len(ids))
+	for i, id := range ids {
+		names[i] = c.artists[id].Name
+	}
+	return strings.Join(names, " ")
+}
+
+// searchTerms lowercases the query and splits it into words, dropping
+// Spotify field-filter prefixes ("artist:", "track:").
+func searchTerms(q string) []string {
+	var terms []string
+	for _, f := range strings.Fields(strings.ToLower(q)) {
+		if i := strings.IndexByte(f, ':'); i >= 0 {
+			f = f[i+1:]
+		}
+		f = strings.Trim(f, `"'`)
+		if f != "" {
+			terms = append(terms, f)
+		}
+	}
+	return terms
+}
+
+// rank returns the indexes of items whose name and context together contain
+// every term, preferring items whose name itself matches more terms.
+func rank(n, limit int, fields func(i int) (name, context string), terms []string) []int {
+	type hit struct{ index, score int }
+	var hits []hit
+	for i := 0; i < n; i++ {
+		name, context := fields(i)
+		name = strings.ToLower(name)
+		all := name + " " + strings.ToLower(context)
+
+		score := 0
+		matched := true
+		for _, t := range terms {
+			if !strings.Contains(all, t) {
+				matched = false
+				break
+			}
+			if strings.Contains(name, t) {
+				score++
+			}
+		}
+		if matched {
+			hits = append(hits, hit{i, score})
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].score > hits[j].score })
+
+	out := make([]int, 0, min(len(hits), limit))
+	for _, h := range hits[:min(len(hits), limit)] {
+		out = append(out, h.index)
+	}
+	return out
+}
+
+type pageBounds struct{ start, end int }
+
+// paginate applies limit/offset to a list of total items and builds the next
+// page URL.
+func paginate(r *http.Request, total int) (pageBounds, *string) {
+	limit := min(queryInt(r, "limit", 20), 50)
+	offset := min(max(queryInt(r, "offset", 0), 0), total)
+	end := min(total, offset+limit)
+
+	var next *string
+	if end < total {
+		u := fmt.Sprintf("%s%s?limit=%d&offset=%d", baseURL(r), r.URL.Path, limit, end)
+		next = &u
+	}
+	return pageBounds{offset, end}, next
+}
+
+// trackURIs extracts track IDs from a ?uris=spotify:track:ID,... parameter.
+func trackURIs(r *http.Request) []string {
+	var ids []string
+	for _, uri := range splitList(r.URL.Query().Get("uris")) {
+		if id, ok := strings.CutPrefix(uri, "spotify:track:"); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func queryInt(r *http.Request, key string, def int) int {
+	if n, err := strconv.Atoi(r.URL.Query().Get(key)); err == nil && n > 0 {
+		return n
+	}
+	return def
+}
+
+// baseURL is the scheme and host the request came in on, for self-links.
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("fakespotify: encoding response: %v", err)
+	}
+}
+
+// writeError writes Spotify's error envelope.
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"error": map[string]interface{}{"status": status, "message": message},
+	})
+}
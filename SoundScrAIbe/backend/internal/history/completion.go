@@ -0,0 +1,51 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// UpdateCompletion fills in plays.completion, the estimated share of each
+// track that was played. Sources that report it (ms_played) are used as is;
+// otherwise a play can't have lasted longer than the time since the previous
+// play ended, so a gap shorter than the track marks a skip. Plays are
+// recomputed from the earliest one still missing a value, since a play
+// inserted between two others changes the estimate of the one after it.
+func UpdateCompletion(ctx context.Context, db *sql.DB, userID int64) error {
+	_, err := db.ExecContext(ctx, `
+		WITH pending AS (
+			SELECT MIN(played_at) AS since
+			FROM plays
+			WHERE user_id = $1 AND completion IS NULL AND duration_ms > 0
+		),
+		ordered AS (
+			SELECT p.id, p.duration_ms, p.ms_played, p.played_at,
+				LAG(p.played_at) OVER (ORDER BY p.played_at, p.id) AS prev_played_at
+			FROM plays p, pending
+			WHERE p.user_id = $1 AND p.played_at >= COALESCE(
+				(SELECT MAX(played_at) FROM plays WHERE user_id = $1 AND played_at < pending.since),
+				pending.since
+			)
+		),
+		computed AS (
+			SELECT id, played_at,
+				CASE
+					WHEN duration_ms <= 0 THEN NULL
+					WHEN ms_played IS NOT NULL THEN LEAST(1, ms_played::real / duration_ms)
+					WHEN prev_played_at IS NULL THEN 1
+					ELSE LEAST(1, GREATEST(0, EXTRACT(EPOCH FROM played_at - prev_played_at) * 1000 / duration_ms))
+				END::real AS completion
+			FROM ordered
+		)
+		UPDATE plays p SET completion = c.completion
+		FROM computed c, pending
+		WHERE p.id = c.id AND c.played_at >= pending.since
+			AND p.completion IS DISTINCT FROM c.completion`,
+		userID,
+	)
+	if err != nil {
+		return fmt.Errorf("updating play completion: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SessionGap is the longest silence between two plays of the same listening
+// session. A play covers the length listened (ms_played, else duration_ms)
+// up to its played_at, which Spotify records when the play ended.
+const SessionGap = 20 * time.Minute
+
+// RebuildSessions recomputes the user's listening_sessions from plays whose
+// played_at is at or after since. Sessions those plays could join (ending
+// within SessionGap of the earliest one's start, and every later session)
+// are dropped and rebuilt; a user without any sessions yet is rebuilt from
+// their first play.
+func RebuildSessions(ctx context.Context, db *sql.DB, userID int64, since time.Time) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning session rebuild: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The background sync and imports may rebuild the same user at once.
+	if _, err := tx.ExecContext(ctx,
+		`SELECT pg_advisory_xact_lock(hashtext('listening_sessions'), $1::int)`, userID,
+	); err != nil {
+		return fmt.Errorf("locking sessions: %w", err)
+	}
+
+	var (
+		built    bool
+		reopened sql.NullTime
+	)
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) > 0, MIN(started_at) FILTER (WHERE ended_at >= (
+			SELECT MIN(played_at - make_interval(secs => COALESCE(ms_played, duration_ms) / 1000.0))
+			FROM plays WHERE user_id = $1 AND played_at >= $2
+		) - make_interval(secs => $3))
+		FROM listening_sessions WHERE user_id = $1`,
+		userID, since, SessionGap.Seconds(),
+	).Scan(&built, &reopened)
+	if err != nil {
+		return fmt.Errorf("finding sessions to rebuild: %w", err)
+	}
+	switch {
+	case !built:
+		since = time.Time{}
+	case reopened.Valid && reopened.Time.Before(since):
+		since = reopened.Time
+	}
+
+	// Sessions don't overlap, so everything from the earliest reopened one on
+	// goes, and no play before since belongs to a dropped session.
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM listening_sessions WHERE user_id = $1 AND started_at >= $2`,
+		userID, since,
+	); err != nil {
+		return fmt.Errorf("deleting stale sessions: %w", err)
+	}
+
+	// A play starts a session when it begins more than the gap after every
+	// earlier play has ended. The dominant artist is the most played primary
+	// artist; the dominant genre is the one on most plays, from the genres of
+	// all credited artists whose metadata or catalog entry has been fetched.
+	_, err = tx.ExecContext(ctx, `
+		WITH p AS (
+			SELECT id, played_at,
+				COALESCE(ms_played, duration_ms) AS listened_ms,
+				played_at - make_interval(secs => COALESCE(ms_played, duration_ms) / 1000.0) AS start_at
+			FROM plays
+			WHERE user_id = $1 AND played_at >= $2
+		),
+		marked AS (
+			SELECT p.*,
+				CASE WHEN start_at - MAX(played_at) OVER (ORDER BY played_at, id ROWS BETWEEN UNBOUNDED PRECEDING AND 1 PRECEDING) <= make_interval(secs => $3)
+					THEN 0 ELSE 1 END AS is_start
+			FROM p
+		),
+		grouped AS (
+			SELECT marked.*, SUM(is_start) OVER (ORDER BY played_at, id) AS session
+			FROM marked
+		),
+		sessions AS (
+			SELECT session, MIN(start_at) AS started_at, MAX(played_at) AS ended_at,
+				COUNT(*) AS track_count, SUM(listened_ms) AS listened_ms
+			FROM grouped
+			GROUP BY session
+		),
+		artists AS (
+			SELECT g.session, pa.artist_id, pa.artist_name,
+				ROW_NUMBER() OVER (PARTITION BY g.session ORDER BY COUNT(*) DESC, MAX(g.played_at) DESC) AS rank
+			FROM grouped g
+			JOIN play_artists pa ON pa.play_id = g.id AND pa.position = 0
+			GROUP BY g.session, pa.artist_id, pa.artist_name
+		),
+		genres AS (
+			SELECT g.session, genre,
+				ROW_NUMBER() OVER (PARTITION BY g.session ORDER BY COUNT(DISTINCT g.id) DESC, genre) AS rank
+			FROM grouped g
+			JOIN play_artists pa ON pa.play_id = g.id AND pa.artist_id != ''
+			CROSS JOIN LATERAL jsonb_array_elements_text(COALESCE(
+				(SELECT em.extra_json->'genres' FROM entity_metadata em WHERE em.entity_type = 'artist' AND em.entity_id = pa.artist_id),
+				(SELECT cc.body->'genres' FROM spotify_catalog_cache cc WHERE cc.kind = 'artist' AND cc.id = pa.artist_id),
+				'[]'::jsonb
+			)) AS genre
+			GROUP BY g.session, genre
+		)
+		INSERT INTO listening_sessions (user_id, started_at, ended_at, track_count, listened_ms, dominant_artist_id, dominant_artist_name, dominant_genre)
+		SELECT $1, s.started_at, s.ended_at, s.track_count, s.listened_ms,
+			COALESCE(a.artist_id, ''), COALESCE(a.artist_name, ''), COALESCE(gn.genre, '')
+		FROM sessions s
+		LEFT JOIN artists a ON a.session = s.session AND a.rank = 1
+		LEFT JOIN genres gn ON gn.session = s.session AND gn.rank = 1
+		ON CONFLICT ON CONSTRAINT uq_listening_sessions DO NOTHING`,
+		userID, since, SessionGap.Seconds(),
+	)
+	if err != nil {
+		return fmt.Errorf("building sessions: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing session rebuild: %w", err)
+	}
+	return nil
+}
+
+// UpdateSessions groups the plays since the user's latest session into
+// sessions, extending that session if they continue it. A user without
+// sessions, e.g. on the first sync after sessions were introduced, gets their
+// whole history grouped.
+func UpdateSessions(ctx context.Context, db *sql.DB, userID int64) error {
+	var latest sql.NullTime
+	err := db.QueryRowContext(ctx,
+		`SELECT MAX(ended_at) FROM listening_sessions WHERE user_id = $1`, userID,
+	).Scan(&latest)
+	if err != nil {
+		return fmt.Errorf("finding latest session: %w", err)
+	}
+	return RebuildSessions(ctx, db, userID, latest.Time)
+}
@@ -0,0 +1,188 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"soundscraibe/internal/spotify"
+	"soundscraibe/internal/user"
+)
+
+// maxPagesPerSync caps how many recently-played pages a single sync will walk.
+// Spotify only keeps the last 50 plays, so more than a couple of pages means the
+// cursor is far behind and older plays are already gone.
+const maxPagesPerSync = 10
+
+// State is the per-user sync bookkeeping stored in sync_state.
+type State struct {
+	UserID       int64      `json:"-"`
+	LastCursor   int64      `json:"last_cursor"`
+	LastSyncedAt *time.Time `json:"last_synced_at"`
+	LastError    string     `json:"last_error,omitempty"`
+	LastErrorAt  *time.Time `json:"last_error_at,omitempty"`
+}
+
+// GetState returns the sync state for a user. A user that has never been synced
+// gets a zero State (cursor 0) rather than an error.
+func GetState(ctx context.Context, db *sql.DB, userID int64) (*State, error) {
+	s := &State{UserID: userID}
+	err := db.QueryRowContext(ctx, `
+		SELECT last_cursor, last_synced_at, last_error, last_error_at
+		FROM sync_state WHERE user_id = $1`, userID,
+	).Scan(&s.LastCursor, &s.LastSyncedAt, &s.LastError, &s.LastErrorAt)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("getting sync state: %w", err)
+	}
+	return s, nil
+}
+
+// Sync pulls every play after the user's stored cursor from Spotify's
+// recently-played endpoint and writes them into plays. The new
+// cursor is committed in the same transaction as the rows, so a failed sync
+// never advances past plays it did not store. Play completion estimates and
+// listening sessions are then brought up to date. Returns the number of plays stored.
+func Sync(ctx context.Context, db *sql.DB, sp spotify.API, u *user.User) (int, error) {
+	state, err := GetState(ctx, db, u.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	cursor := state.LastCursor
+	stored := 0
+
+	for page := 0; page < maxPagesPerSync; page++ {
+		result, err := sp.GetRecentlyPlayed(ctx, u.AccessToken, cursor)
+		if err != nil {
+			return stored, fmt.Errorf("fetching recently played: %w", err)
+		}
+		if len(result.Items) == 0 {
+			break
+		}
+
+		next, err := storePage(ctx, db, u.ID, cursor, result.Items)
+		if err != nil {
+			return stored, err
+		}
+		stored += len(result.Items)
+
+		// A short page means we've caught up; an unchanged cursor means
+		// Spotify has nothing newer to give us.
+		if len(result.Items) < 50 || next <= cursor {
+			cursor = next
+			break
+		}
+		cursor = next
+	}
+
+	// Completion and sessions are derived data: a failed update catches up on
+	// the next sync rather than failing this one.
+	if err := UpdateCompletion(ctx, db, u.ID); err != nil {
+		slog.WarnContext(ctx, "sync: estimating play completion failed", "error", err)
+	}
+	if err := UpdateSessions(ctx, db, u.ID); err != nil {
+		slog.WarnContext(ctx, "sync: rebuilding listening sessions failed", "error", err)
+	}
+
+	if stored == 0 {
+		// Still record the successful (empty) sync so last_synced_at is meaningful.
+		_, err = db.ExecContext(ctx, `
+			INSERT INTO sync_state (user_id, last_cursor, last_synced_at, last_error, last_error_at)
+			VALUES ($1, $2, now(), '', NULL)
+			ON CONFLICT (user_id) DO UPDATE SET
+				last_synced_at = now(), last_error = '', last_error_at = NULL, updated_at = now()`,
+			u.ID, cursor,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("updating sync state: %w", err)
+		}
+	}
+
+	return stored, nil
+}
+
+// storePage upserts one page of plays (one row per artist per play) and advances
+// the cursor to the newest played_at in the page. Returns the new cursor.
+func storePage(ctx context.Context, db *sql.DB, userID, cursor int64, items []spotify.PlayHistoryItem) (int64, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return cursor, fmt.Errorf("beginning sync transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	next := cursor
+	for _, item := range items {
+		playedAt, err := time.Parse(time.RFC3339, item.PlayedAt)
+		if err != nil {
+			slog.WarnContext(ctx, "skipping item with unparseable played_at", "played_at", item.PlayedAt, "error", err)
+			continue
+		}
+		if ms := playedAt.UnixMilli(); ms > next {
+			next = ms
+		}
+
+		var playID int64
+		err = tx.QueryRowContext(ctx,
+			`INSERT INTO plays (user_id, track_id, track_name, album_id, album_name, duration_ms, played_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7)
+			 ON CONFLICT (user_id, track_id, played_at) DO UPDATE SET album_id = EXCLUDED.album_id, album_name = EXCLUDED.album_name
+			 RETURNING id`,
+			userID,
+			item.Track.ID,
+			item.Track.Name,
+			item.Track.Album.ID,
+			item.Track.Album.Name,
+			item.Track.DurationMs,
+			playedAt,
+		).Scan(&playID)
+		if err != nil {
+			return cursor, fmt.Errorf("upserting play of track %s: %w", item.Track.ID, err)
+		}
+
+		for i, artist := range item.Track.Artists {
+			_, err := tx.ExecContext(ctx,
+				`INSERT INTO play_artists (play_id, position, artist_id, artist_name)
+				 VALUES ($1, $2, $3, $4)
+				 ON CONFLICT (play_id, position) DO UPDATE SET artist_id = EXCLUDED.artist_id, artist_name = EXCLUDED.artist_name`,
+				playID, i, artist.ID, artist.Name,
+			)
+			if err != nil {
+				return cursor, fmt.Errorf("upserting artist %s of play %d: %w", artist.ID, playID, err)
+			}
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO sync_state (user_id, last_cursor, last_synced_at, last_error, last_error_at)
+		VALUES ($1, $2, now(), '', NULL)
+		ON CONFLICT (user_id) DO UPDATE SET
+			last_cursor = EXCLUDED.last_cursor, last_synced_at = now(),
+			last_error = '', last_error_at = NULL, updated_at = now()`,
+		userID, next,
+	)
+	if err != nil {
+		return cursor, fmt.Errorf("updating sync cursor: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return cursor, fmt.Errorf("committing sync transaction: %w", err)
+	}
+	return next, nil
+}
+
+// recordError stores the most recent sync failure for a user without touching the cursor.
+func recordError(ctx context.Context, db *sql.DB, userID int64, syncErr error) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO sync_state (user_id, last_error, last_error_at)
+		VALUES ($1, $2, now())
+		ON CONFLICT (user_id) DO UPDATE SET
+			last_error = EXCLUDED.last_error, last_error_at = now(), updated_at = now()`,
+		userID, syncErr.Error(),
+	)
+	if err != nil {
+		return fmt.Errorf("recording sync error: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"soundscraibe/internal/auth"
+	"soundscraibe/internal/config"
+	"soundscraibe/internal/logging"
+	"soundscraibe/internal/spotify"
+	"soundscraibe/internal/tokencrypt"
+	"soundscraibe/internal/user"
+)
+
+// perUserTimeout bounds a single user's sync so one slow account can't stall the loop.
+const perUserTimeout = 30 * time.Second
+
+// Worker periodically syncs recently-played data for every user with a
+// refresh token, so plays are captured even when nobody opens the app.
+type Worker struct {
+	db       *sql.DB
+	spotify  *spotify.Config
+	api      spotify.API
+	keys     *tokencrypt.Keyring
+	interval time.Duration
+
+	// AfterSync, if set, runs after each successful sync of a user, with their
+	// freshly refreshed token. Set it before calling Run.
+	AfterSync func(ctx context.Context, u *user.User)
+}
+
+// NewWorker creates a sync worker from the app config, talking to Spotify
+// through sp and decrypting stored tokens with keys.
+func NewWorker(db *sql.DB, cfg *config.Config, sp *spotify.Client, keys *tokencrypt.Keyring) *Worker {
+	return &Worker{
+		db: db,
+		spotify: &spotify.Config{
+			ClientID:     cfg.SpotifyClientID,
+			ClientSecret: cfg.SpotifyClientSecret,
+			RedirectURI:  cfg.SpotifyRedirectURI,
+			Client:       sp,
+		},
+		api:      sp,
+		keys:     keys,
+		interval: cfg.SyncInterval,
+	}
+}
+
+// Run syncs all users immediately and then once per interval until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	slog.InfoContext(ctx, "listening-history sync worker started", "interval", w.interval)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.syncAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "listening-history sync worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SyncUser loads a user by ID and syncs them right away (e.g. right after login).
+func (w *Worker) SyncUser(ctx context.Context, userID int64) {
+	ctx = logging.WithUserID(ctx, userID)
+	u, err := user.GetByID(ctx, w.db, w.keys, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "sync: failed to load user", "error", err)
+		return
+	}
+	w.syncOne(ctx, u)
+}
+
+// syncAll runs one pass over every syncable user, sequentially to stay well
+// under Spotify's rate limits.
+func (w *Worker) syncAll(ctx context.Context) {
+	users, err := user.ListWithRefreshToken(ctx, w.db, w.keys)
+	if err != nil {
+		slog.ErrorContext(ctx, "sync: failed to list users", "error", err)
+		return
+	}
+
+	for _, u := range users {
+		if ctx.Err() != nil {
+			return
+		}
+		w.syncOne(ctx, u)
+	}
+}
+
+// syncOne refreshes the user's token if needed and syncs their plays, recording
+// any failure in sync_state. Everything logged with ctx carries the user's ID.
+func (w *Worker) syncOne(ctx context.Context, u *user.User) {
+	ctx, cancel := context.WithTimeout(logging.WithUserID(ctx, u.ID), perUserTimeout)
+	defer cancel()
+
+	err := auth.EnsureFreshToken(ctx, w.db, w.keys, w.spotify, u)
+	if err == nil {
+		var stored int
+		stored, err = Sync(ctx, w.db, w.api, u)
+		if err == nil {
+			if stored > 0 {
+				slog.InfoContext(ctx, "sync: stored plays", "count", stored)
+			}
+			if w.AfterSync != nil {
+				w.AfterSync(ctx, u)
+			}
+			return
+		}
+	}
+
+	slog.WarnContext(ctx, "sync failed", "error", err)
+	if recErr := recordError(context.WithoutCancel(ctx), w.db, u.ID, err); recErr != nil {
+		slog.ErrorContext(ctx, "sync: failed to record sync error", "error", recErr)
+	}
+}
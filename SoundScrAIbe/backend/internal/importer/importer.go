@@ -0,0 +1,179 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"soundscraibe/internal/history"
+	"soundscraibe/internal/spotify"
+)
+
+// batchSize is how many plays are written per INSERT statement.
+const batchSize = 500
+
+// dedupeWindow is how far apart two plays of the same track may be and still be
+// treated as the same play. Exports and the recently-played API disagree on
+// timestamps by a few seconds (export `ts` is rounded, API `played_at` isn't).
+const dedupeWindow = 60 * time.Second
+
+// Summary reports the outcome of an import.
+type Summary struct {
+	Total      int `json:"total"`      // records read from the input
+	Processed  int `json:"processed"`  // records handled so far
+	Added      int `json:"added"`      // plays written to the plays table, including unresolved ones
+	Skipped    int `json:"skipped"`    // duplicates, too-short plays, non-music entries
+	Unresolved int `json:"unresolved"` // added plays that couldn't be mapped to a Spotify track
+}
+
+// ProgressFunc is called after every batch with the running totals.
+type ProgressFunc func(Summary)
+
+// TokenFunc returns a valid Spotify access token for the importing user.
+// Imports call it before every batch of lookups, since a long import outlives
+// a single token (see auth.TokenSource).
+type TokenFunc func(ctx context.Context) (string, error)
+
+// Play is a single play destined for the plays table, with its credited
+// artists in order. Unresolved plays have empty TrackID/AlbumID and a single
+// artist without an ID, and carry the names from the source instead.
+type Play struct {
+	Source     string
+	TrackID    string
+	TrackName  string
+	Artists    []spotify.Artist
+	AlbumID    string
+	AlbumName  string
+	DurationMs int
+	MsPlayed   *int // nil when the source doesn't say how long was played
+	PlayedAt   time.Time
+}
+
+// primaryArtist returns the name of the play's first credited artist.
+func (p Play) primaryArtist() string {
+	if len(p.Artists) == 0 {
+		return ""
+	}
+	return p.Artists[0].Name
+}
+
+// earliestPlay returns the earliest played_at of plays, or earliest if that
+// comes first (the zero time doesn't).
+func earliestPlay(plays []Play, earliest time.Time) time.Time {
+	for _, p := range plays {
+		if earliest.IsZero() || p.PlayedAt.Before(earliest) {
+			earliest = p.PlayedAt
+		}
+	}
+	return earliest
+}
+
+// afterImport updates play completion estimates and regroups the user's
+// listening sessions after an import that added plays from earliest on, also
+// when the import stopped partway. It runs detached from ctx so a cancelled
+// import still leaves them consistent with the plays it wrote; failures are
+// only logged.
+func afterImport(ctx context.Context, db *sql.DB, userID int64, summary *Summary, earliest time.Time) {
+	if summary.Added == 0 {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	if err := history.UpdateCompletion(ctx, db, userID); err != nil {
+		slog.WarnContext(ctx, "import: estimating play completion failed", "error", err)
+	}
+	if err := history.RebuildSessions(ctx, db, userID, earliest); err != nil {
+		slog.WarnContext(ctx, "import: rebuilding listening sessions failed", "error", err)
+	}
+}
+
+// writeBatch inserts plays and their artists, skipping any play that already
+// exists for the user within dedupeWindow of the same track (matched by track
+// and primary artist name for unresolved plays). Returns the number of plays
+// that were actually added, and how many of those are unresolved.
+func writeBatch(ctx context.Context, db *sql.DB, userID int64, plays []Play) (added, unresolved int, err error) {
+	if len(plays) == 0 {
+		return 0, 0, nil
+	}
+
+	var (
+		sources     = make([]string, len(plays))
+		trackIDs    = make([]string, len(plays))
+		trackNames  = make([]string, len(plays))
+		primaries   = make([]string, len(plays))
+		albumIDs    = make([]string, len(plays))
+		albumNames  = make([]string, len(plays))
+		durations   = make([]int32, len(plays))
+		msPlayed    = make([]int32, len(plays))
+		playedAt    = make([]time.Time, len(plays))
+		artistPlays []int32 // index into the plays arrays, 1-based like WITH ORDINALITY
+		positions   []int32
+		artistIDs   []string
+		artistNames []string
+	)
+	for i, p := range plays {
+		sources[i] = p.Source
+		trackIDs[i] = p.TrackID
+		trackNames[i] = p.TrackName
+		primaries[i] = p.primaryArtist()
+		albumIDs[i] = p.AlbumID
+		albumNames[i] = p.AlbumName
+		durations[i] = int32(p.DurationMs)
+		msPlayed[i] = -1 // sentinel for NULL, arrays of nullable ints don't bind cleanly
+		if p.MsPlayed != nil {
+			msPlayed[i] = int32(*p.MsPlayed)
+		}
+		playedAt[i] = p.PlayedAt
+		for pos, a := range p.Artists {
+			artistPlays = append(artistPlays, int32(i+1))
+			positions = append(positions, int32(pos))
+			artistIDs = append(artistIDs, a.ID)
+			artistNames = append(artistNames, a.Name)
+		}
+	}
+
+	// Inserted plays are matched back to their input row by track and
+	// played_at, which the unique constraint makes unambiguous; input rows that
+	// duplicate each other share one play and their artists collapse onto it.
+	err = db.QueryRowContext(ctx, `
+		WITH input AS (
+			SELECT *
+			FROM unnest($2::text[], $3::text[], $4::text[], $5::text[], $6::text[], $7::text[], $8::int[], $9::int[], $10::timestamptz[])
+				WITH ORDINALITY AS t(source, track_id, track_name, primary_artist, album_id, album_name, duration_ms, ms_played, played_at, idx)
+		),
+		ins AS (
+			INSERT INTO plays (user_id, source, track_id, track_name, album_id, album_name, duration_ms, ms_played, played_at)
+			SELECT $1, i.source, i.track_id, i.track_name, i.album_id, i.album_name,
+			       i.duration_ms, NULLIF(i.ms_played, -1), i.played_at
+			FROM input i
+			WHERE NOT EXISTS (
+				SELECT 1 FROM plays p
+				WHERE p.user_id = $1 AND p.track_id = i.track_id
+				  AND (i.track_id != '' OR (p.track_name = i.track_name AND EXISTS (
+				      SELECT 1 FROM play_artists pa
+				      WHERE pa.play_id = p.id AND pa.position = 0 AND pa.artist_name = i.primary_artist)))
+				  AND p.played_at BETWEEN i.played_at - make_interval(secs => $11) AND i.played_at + make_interval(secs => $11)
+			)
+			ON CONFLICT (user_id, track_id, played_at) DO NOTHING
+			RETURNING id, track_id, track_name, played_at
+		),
+		ins_artists AS (
+			INSERT INTO play_artists (play_id, position, artist_id, artist_name)
+			SELECT ins.id, a.position, a.artist_id, a.artist_name
+			FROM unnest($12::int[], $13::int[], $14::text[], $15::text[]) AS a(idx, position, artist_id, artist_name)
+			JOIN input i ON i.idx = a.idx
+			JOIN ins ON ins.track_id = i.track_id AND ins.track_name = i.track_name AND ins.played_at = i.played_at
+			ON CONFLICT (play_id, position) DO NOTHING
+		)
+		SELECT COUNT(*) FILTER (WHERE track_id != ''), COUNT(*) FILTER (WHERE track_id = '')
+		FROM ins`,
+		userID, sources, trackIDs, trackNames, primaries, albumIDs, albumNames, durations, msPlayed, playedAt,
+		dedupeWindow.Seconds(),
+		artistPlays, positions, artistIDs, artistNames,
+	).Scan(&added, &unresolved)
+	if err != nil {
+		return 0, 0, fmt.Errorf("inserting import batch: %w", err)
+	}
+	return added + unresolved, unresolved, nil
+}
@@ -0,0 +1,139 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Job is a history import tracked in history_imports so the UI can poll progress.
+type Job struct {
+	ID         int64      `json:"id"`
+	Source     string     `json:"source"`
+	Status     string     `json:"status"` // running, completed, failed
+	Summary    Summary    `json:"summary"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+}
+
+// CreateJob records a new running import for the user and returns its ID.
+func CreateJob(ctx context.Context, db *sql.DB, userID int64, source string, total int) (int64, error) {
+	var id int64
+	err := db.QueryRowContext(ctx, `
+		INSERT INTO history_imports (user_id, source, total)
+		VALUES ($1, $2, $3)
+		RETURNING id`,
+		userID, source, total,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("creating import job: %w", err)
+	}
+	return id, nil
+}
+
+// UpdateJob stores the running totals for an import.
+func UpdateJob(ctx context.Context, db *sql.DB, jobID int64, s Summary) error {
+	_, err := db.ExecContext(ctx, `
+		UPDATE history_imports
+		SET total = $2, processed = $3, added = $4, skipped = $5, unresolved = $6
+		WHERE id = $1`,
+		jobID, s.Total, s.Processed, s.Added, s.Skipped, s.Unresolved,
+	)
+	if err != nil {
+		return fmt.Errorf("updating import job: %w", err)
+	}
+	return nil
+}
+
+// FinishJob marks an import completed (or failed, if importErr is non-nil) with its final totals.
+func FinishJob(ctx context.Context, db *sql.DB, jobID int64, s Summary, importErr error) error {
+	status, errMsg := "completed", ""
+	if importErr != nil {
+		status, errMsg = "failed", importErr.Error()
+	}
+	_, err := db.ExecContext(ctx, `
+		UPDATE history_imports
+		SET status = $2, error = $3, total = $4, processed = $5, added = $6, skipped = $7, unresolved = $8, finished_at = now()
+		WHERE id = $1`,
+		jobID, status, errMsg, s.Total, s.Processed, s.Added, s.Skipped, s.Unresolved,
+	)
+	if err != nil {
+		return fmt.Errorf("finishing import job: %w", err)
+	}
+	return nil
+}
+
+// FailInterruptedJobs marks imports still running from before a restart as
+// failed, since nothing will finish them. Call it at startup, before the
+// server accepts new imports. It returns how many imports were marked.
+func FailInterruptedJobs(ctx context.Context, db *sql.DB) (int64, error) {
+	res, err := db.ExecContext(ctx, `
+		UPDATE history_imports
+		SET status = 'failed', error = 'interrupted by a server restart; plays imported before it were kept', finished_at = now()
+		WHERE status = 'running'`)
+	if err != nil {
+		return 0, fmt.Errorf("failing interrupted import jobs: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// GetJob returns a single import scoped to the user, or nil if it doesn't exist.
+func GetJob(ctx context.Context, db *sql.DB, userID, jobID int64) (*Job, error) {
+	row := db.QueryRowContext(ctx, `
+		SELECT id, source, status, total, processed, added, skipped, unresolved, error, created_at, finished_at
+		FROM history_imports
+		WHERE id = $1 AND user_id = $2`, jobID, userID)
+
+	j, err := scanJob(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting import job: %w", err)
+	}
+	return j, nil
+}
+
+// ListJobs returns the user's most recent imports (newest first).
+func ListJobs(ctx context.Context, db *sql.DB, userID int64) ([]Job, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, source, status, total, processed, added, skipped, unresolved, error, created_at, finished_at
+		FROM history_imports
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT 20`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("listing import jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []Job{}
+	for rows.Next() {
+		j, err := scanJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning import job: %w", err)
+		}
+		jobs = append(jobs, *j)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating import jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanJob(row rowScanner) (*Job, error) {
+	j := &Job{}
+	err := row.Scan(&j.ID, &j.Source, &j.Status,
+		&j.Summary.Total, &j.Summary.Processed, &j.Summary.Added, &j.Summary.Skipped, &j.Summary.Unresolved,
+		&j.Error, &j.CreatedAt, &j.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	return j, nil
+}
@@ -0,0 +1,244 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SourceLastFM identifies imports of Last.fm scrobble exports.
+const SourceLastFM = "lastfm"
+
+// lastfmDateLayouts are the human-readable timestamp formats used by the
+// common Last.fm export tools. Numeric values are treated as Unix time.
+var lastfmDateLayouts = []string{
+	"02 Jan 2006 15:04",
+	"2 Jan 2006 15:04",
+	"02 Jan 2006, 15:04",
+	"2 Jan 2006, 15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	time.RFC3339,
+}
+
+// ParseLastFMCSV reads a Last.fm scrobble export in CSV form. Both the
+// headerless "artist,album,track,date" layout and exports with a header row
+// naming the columns (artist, album, track/name, uts/date/utc_time) are accepted.
+func ParseLastFMCSV(r io.Reader) ([]Scrobble, error) {
+	cr := csv.NewReader(bufio.NewReader(r))
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+
+	first, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading last.fm csv: %w", err)
+	}
+
+	// Default positional layout, overridden by a header row when there is one.
+	artistCol, albumCol, trackCol, dateCol := 0, 1, 2, 3
+	rows := [][]string{first}
+	if cols, ok := lastfmHeader(first); ok {
+		artistCol, albumCol, trackCol, dateCol = cols[0], cols[1], cols[2], cols[3]
+		rows = nil
+	}
+
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading last.fm csv: %w", err)
+		}
+		rows = append(rows, row)
+	}
+
+	scrobbles := make([]Scrobble, 0, len(rows))
+	for _, row := range rows {
+		field := func(i int) string {
+			if i < 0 || i >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[i])
+		}
+		s := Scrobble{
+			Artist: field(artistCol),
+			Album:  field(albumCol),
+			Track:  field(trackCol),
+		}
+		s.PlayedAt, _ = parseLastFMTime(field(dateCol)) // zero time is counted as skipped on import
+		scrobbles = append(scrobbles, s)
+	}
+	return scrobbles, nil
+}
+
+// lastfmHeader reports whether row is a header and, if so, the indexes of the
+// artist, album, track and timestamp columns.
+func lastfmHeader(row []string) ([4]int, bool) {
+	cols := [4]int{-1, -1, -1, -1}
+	for i, name := range row {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "artist", "artist_name":
+			cols[0] = i
+		case "album", "album_name":
+			cols[1] = i
+		case "track", "name", "title", "track_name":
+			cols[2] = i
+		case "uts", "timestamp", "date", "utc_time", "time":
+			// Prefer the Unix column when an export carries both.
+			if cols[3] == -1 || strings.EqualFold(name, "uts") {
+				cols[3] = i
+			}
+		}
+	}
+	return cols, cols[0] != -1 && cols[2] != -1
+}
+
+func parseLastFMTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, fmt.Errorf("missing timestamp")
+	}
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+		if n > 1e12 { // milliseconds
+			return time.UnixMilli(n).UTC(), nil
+		}
+		return time.Unix(n, 0).UTC(), nil
+	}
+	for _, layout := range lastfmDateLayouts {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised timestamp %q", v)
+}
+
+// lastfmText is the {"#text": ..., "mbid": ...} shape Last.fm uses for artist
+// and album fields; extended responses use "name" for artists instead.
+type lastfmText struct {
+	Text string `json:"#text"`
+	Name string `json:"name"`
+}
+
+func (t *lastfmText) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		return json.Unmarshal(b, &t.Text)
+	}
+	type plain lastfmText
+	return json.Unmarshal(b, (*plain)(t))
+}
+
+func (t lastfmText) value() string {
+	if t.Text != "" {
+		return t.Text
+	}
+	return t.Name
+}
+
+// lastfmTrack is one entry of user.getRecentTracks.
+type lastfmTrack struct {
+	Artist lastfmText `json:"artist"`
+	Album  lastfmText `json:"album"`
+	Name   string     `json:"name"`
+	Date   *struct {
+		UTS string `json:"uts"`
+	} `json:"date"`
+}
+
+// ParseLastFMJSON reads a Last.fm export in JSON form: either a single
+// user.getRecentTracks response, an array of such pages (as written by most
+// backup tools), or a flat array of track objects.
+func ParseLastFMJSON(r io.Reader) ([]Scrobble, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("decoding last.fm json: %w", err)
+	}
+
+	tracks, err := collectLastFMTracks(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	scrobbles := make([]Scrobble, 0, len(tracks))
+	for _, t := range tracks {
+		if t.Date == nil {
+			continue // "now playing" entry, not a scrobble
+		}
+		s := Scrobble{
+			Artist: strings.TrimSpace(t.Artist.value()),
+			Album:  strings.TrimSpace(t.Album.value()),
+			Track:  strings.TrimSpace(t.Name),
+		}
+		s.PlayedAt, _ = parseLastFMTime(t.Date.UTS)
+		scrobbles = append(scrobbles, s)
+	}
+	return scrobbles, nil
+}
+
+func collectLastFMTracks(raw json.RawMessage) ([]lastfmTrack, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	// A page: {"recenttracks": {"track": [...]}} or {"track": [...]}.
+	if raw[0] == '{' {
+		var page struct {
+			RecentTracks *struct {
+				Track []lastfmTrack `json:"track"`
+			} `json:"recenttracks"`
+			Track []lastfmTrack `json:"track"`
+		}
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return nil, fmt.Errorf("decoding last.fm page: %w", err)
+		}
+		if page.RecentTracks != nil {
+			return page.RecentTracks.Track, nil
+		}
+		return page.Track, nil
+	}
+
+	if raw[0] != '[' {
+		return nil, fmt.Errorf("last.fm export must be a JSON object or array")
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, fmt.Errorf("decoding last.fm export: %w", err)
+	}
+
+	var tracks []lastfmTrack
+	for i, item := range items {
+		var probe map[string]json.RawMessage
+		if err := json.Unmarshal(item, &probe); err != nil {
+			return nil, fmt.Errorf("decoding last.fm entry %d: %w", i+1, err)
+		}
+		_, isPage := probe["recenttracks"]
+		if _, ok := probe["track"]; ok {
+			isPage = true
+		}
+		if isPage {
+			page, err := collectLastFMTracks(item)
+			if err != nil {
+				return nil, err
+			}
+			tracks = append(tracks, page...)
+			continue
+		}
+
+		var t lastfmTrack
+		if err := json.Unmarshal(item, &t); err != nil {
+			return nil, fmt.Errorf("decoding last.fm entry %d: %w", i+1, err)
+		}
+		tracks = append(tracks, t)
+	}
+	return tracks, nil
+}
@@ -0,0 +1,124 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// SourceListenBrainz identifies imports of ListenBrainz listen dumps.
+const SourceListenBrainz = "listenbrainz"
+
+// SourceScrobbleAPI identifies listens pushed through the ListenBrainz-compatible
+// submission API (/1/submit-listens).
+const SourceScrobbleAPI = "scrobble"
+
+// ListenBrainzListen is a single listen in ListenBrainz's JSON format, as found
+// in user export dumps (one per line) and submit-listens payloads.
+type ListenBrainzListen struct {
+	ListenedAt    int64 `json:"listened_at"`
+	TrackMetadata struct {
+		ArtistName     string `json:"artist_name"`
+		TrackName      string `json:"track_name"`
+		ReleaseName    string `json:"release_name"`
+		AdditionalInfo struct {
+			DurationMs int    `json:"duration_ms"`
+			Duration   int    `json:"duration"` // seconds; some clients send this instead
+			SpotifyID  string `json:"spotify_id"`
+		} `json:"additional_info"`
+	} `json:"track_metadata"`
+}
+
+// Scrobble converts the listen to the importer's source-neutral form.
+func (l ListenBrainzListen) Scrobble() Scrobble {
+	md := l.TrackMetadata
+	s := Scrobble{
+		Artist:     strings.TrimSpace(md.ArtistName),
+		Track:      strings.TrimSpace(md.TrackName),
+		Album:      strings.TrimSpace(md.ReleaseName),
+		DurationMs: md.AdditionalInfo.DurationMs,
+		SpotifyID:  spotifyTrackID(md.AdditionalInfo.SpotifyID),
+	}
+	if s.DurationMs == 0 {
+		s.DurationMs = md.AdditionalInfo.Duration * 1000
+	}
+	if l.ListenedAt > 0 {
+		s.PlayedAt = time.Unix(l.ListenedAt, 0).UTC()
+	}
+	return s
+}
+
+// spotifyTrackID extracts the bare track ID from an open.spotify.com URL or
+// spotify:track: URI. Anything else yields "".
+func spotifyTrackID(v string) string {
+	v = strings.TrimSpace(v)
+	switch {
+	case strings.HasPrefix(v, "spotify:track:"):
+		return strings.TrimPrefix(v, "spotify:track:")
+	case strings.Contains(v, "open.spotify.com/track/"):
+		id := v[strings.Index(v, "/track/")+len("/track/"):]
+		if i := strings.IndexAny(id, "?/#"); i >= 0 {
+			id = id[:i]
+		}
+		return id
+	}
+	return ""
+}
+
+// ParseListenBrainz reads a ListenBrainz listen dump. The JSONL format of
+// current exports (one listen per line) is expected; older exports that wrote
+// a single JSON array are accepted too.
+func ParseListenBrainz(r io.Reader) ([]Scrobble, error) {
+	br := bufio.NewReader(r)
+
+	// Peek past leading whitespace to tell a JSON array from JSONL.
+	for {
+		b, err := br.Peek(1)
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading listenbrainz dump: %w", err)
+		}
+		if b[0] != ' ' && b[0] != '\n' && b[0] != '\r' && b[0] != '\t' {
+			break
+		}
+		br.ReadByte()
+	}
+
+	var listens []ListenBrainzListen
+	if b, _ := br.Peek(1); b[0] == '[' {
+		if err := json.NewDecoder(br).Decode(&listens); err != nil {
+			return nil, fmt.Errorf("decoding listenbrainz dump: %w", err)
+		}
+	} else {
+		sc := bufio.NewScanner(br)
+		sc.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+		line := 0
+		for sc.Scan() {
+			line++
+			text := bytes.TrimSpace(sc.Bytes())
+			if len(text) == 0 {
+				continue
+			}
+			var l ListenBrainzListen
+			if err := json.Unmarshal(text, &l); err != nil {
+				return nil, fmt.Errorf("decoding listenbrainz line %d: %w", line, err)
+			}
+			listens = append(listens, l)
+		}
+		if err := sc.Err(); err != nil {
+			return nil, fmt.Errorf("reading listenbrainz dump: %w", err)
+		}
+	}
+
+	scrobbles := make([]Scrobble, len(listens))
+	for i, l := range listens {
+		scrobbles[i] = l.Scrobble()
+	}
+	return scrobbles, nil
+}
@@ -0,0 +1,191 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"soundscraibe/internal/spotify"
+)
+
+// QueueListens stores listens submitted through the submission API in
+// pending_listens, so they survive a Spotify outage or a restart until
+// ImportPendingListens writes them to plays.
+func QueueListens(ctx context.Context, db *sql.DB, userID int64, scrobbles []Scrobble) error {
+	if len(scrobbles) == 0 {
+		return nil
+	}
+
+	var (
+		artists   = make([]string, len(scrobbles))
+		tracks    = make([]string, len(scrobbles))
+		albums    = make([]string, len(scrobbles))
+		listened  = make([]time.Time, len(scrobbles))
+		durations = make([]int32, len(scrobbles))
+		ids       = make([]string, len(scrobbles))
+	)
+	for i, s := range scrobbles {
+		artists[i] = s.Artist
+		tracks[i] = s.Track
+		albums[i] = s.Album
+		listened[i] = s.PlayedAt
+		durations[i] = int32(s.DurationMs)
+		ids[i] = s.SpotifyID
+	}
+
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO pending_listens (user_id, artist_name, track_name, album_name, listened_at, duration_ms, spotify_id)
+		SELECT $1, * FROM unnest($2::text[], $3::text[], $4::text[], $5::timestamptz[], $6::int[], $7::text[])`,
+		userID, artists, tracks, albums, listened, durations, ids,
+	)
+	if err != nil {
+		return fmt.Errorf("queueing listens: %w", err)
+	}
+	return nil
+}
+
+// pendingLease is how long an import holds the listens it claimed. It only
+// matters when an import dies without releasing them; it has to outlast the
+// Spotify lookups for a batch, which can wait out rate limits.
+const pendingLease = 15 * time.Minute
+
+// ImportPendingListens imports the user's queued listens like ImportScrobbles
+// (source SourceScrobbleAPI) in batches, removing each listen from the queue
+// once it's written to plays. Listens a concurrent call has claimed are left
+// to it. Listens whose Spotify lookup failed (rate limits, outages, a dead
+// token) aren't written and stay queued, as does a batch that failed, for the
+// next call; a batch with failed lookups also ends this one.
+func ImportPendingListens(ctx context.Context, db *sql.DB, sp spotify.API, token TokenFunc, userID int64) (*Summary, error) {
+	total := &Summary{}
+	var earliest time.Time
+	defer func() { afterImport(ctx, db, userID, total, earliest) }()
+
+	for {
+		n, kept, err := importPendingBatch(ctx, db, sp, token, userID, total, &earliest)
+		if err != nil {
+			return total, err
+		}
+		if n < batchSize || kept > 0 {
+			return total, nil
+		}
+	}
+}
+
+// importPendingBatch claims up to batchSize queued listens, imports the ones
+// that could be looked up and deletes those, releasing the rest, and adds the
+// outcome to total. Returns how many were claimed and how many were kept
+// queued. No transaction is held open while Spotify is asked.
+func importPendingBatch(ctx context.Context, db *sql.DB, sp spotify.API, token TokenFunc, userID int64, total *Summary, earliest *time.Time) (claimed, kept int, err error) {
+	ids, scrobbles, err := claimPendingListens(ctx, db, userID)
+	if err != nil || len(ids) == 0 {
+		return 0, 0, err
+	}
+
+	r := newNameResolver(db, sp, token, userID)
+	if err := r.resolve(ctx, scrobbles); err != nil {
+		releasePendingListens(ctx, db, nil, ids)
+		return 0, 0, err
+	}
+	var (
+		done, retry []int64
+		ready       []Scrobble
+	)
+	for i, s := range scrobbles {
+		if r.lookupFailed(s) {
+			retry = append(retry, ids[i])
+			continue
+		}
+		done = append(done, ids[i])
+		ready = append(ready, s)
+	}
+
+	summary := &Summary{Total: len(ready), Processed: len(ready)}
+	plays := r.plays(SourceScrobbleAPI, ready, summary)
+	added, unresolved, err := writeBatch(ctx, db, userID, plays)
+	if err != nil {
+		releasePendingListens(ctx, db, nil, ids)
+		return 0, 0, err
+	}
+	*earliest = earliestPlay(plays, *earliest)
+	total.Total += summary.Total
+	total.Processed += summary.Processed
+	total.Added += added
+	total.Skipped += summary.Skipped + len(plays) - added
+	total.Unresolved += unresolved
+
+	// If this fails the claims expire and the written listens are imported
+	// again next time, deduplicated against the plays written here.
+	if err := releasePendingListens(ctx, db, done, retry); err != nil {
+		return 0, 0, err
+	}
+	return len(ids), len(retry), nil
+}
+
+// claimPendingListens leases up to batchSize of the user's unclaimed (or
+// expired) queued listens for pendingLease.
+func claimPendingListens(ctx context.Context, db *sql.DB, userID int64) ([]int64, []Scrobble, error) {
+	rows, err := db.QueryContext(ctx, `
+		UPDATE pending_listens
+		SET claimed_until = now() + make_interval(secs => $3)
+		WHERE id IN (
+			SELECT id FROM pending_listens
+			WHERE user_id = $1 AND (claimed_until IS NULL OR claimed_until < now())
+			ORDER BY id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, artist_name, track_name, album_name, listened_at, duration_ms, spotify_id`,
+		userID, batchSize, pendingLease.Seconds())
+	if err != nil {
+		return nil, nil, fmt.Errorf("claiming pending listens: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		ids       []int64
+		scrobbles []Scrobble
+	)
+	for rows.Next() {
+		var (
+			id int64
+			s  Scrobble
+		)
+		if err := rows.Scan(&id, &s.Artist, &s.Track, &s.Album, &s.PlayedAt, &s.DurationMs, &s.SpotifyID); err != nil {
+			return nil, nil, fmt.Errorf("scanning pending listen: %w", err)
+		}
+		ids = append(ids, id)
+		scrobbles = append(scrobbles, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("iterating pending listens: %w", err)
+	}
+	return ids, scrobbles, nil
+}
+
+// releasePendingListens deletes the imported listens and gives up the claim on
+// the ones left for a later import. It runs even if ctx was cancelled, so an
+// import stopped by shutdown doesn't leave its listens leased.
+func releasePendingListens(ctx context.Context, db *sql.DB, imported, retry []int64) error {
+	ctx = context.WithoutCancel(ctx)
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning pending listens release: %w", err)
+	}
+	defer tx.Rollback()
+
+	if len(imported) > 0 {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM pending_listens WHERE id = ANY($1)`, imported); err != nil {
+			return fmt.Errorf("deleting imported listens: %w", err)
+		}
+	}
+	if len(retry) > 0 {
+		if _, err := tx.ExecContext(ctx, `UPDATE pending_listens SET claimed_until = NULL WHERE id = ANY($1)`, retry); err != nil {
+			return fmt.Errorf("releasing pending listens: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing pending listens release: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,364 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"soundscraibe/internal/recommend"
+	"soundscraibe/internal/spotify"
+)
+
+// lookupConcurrency bounds parallel Spotify searches while resolving names.
+const lookupConcurrency = 4
+
+// missRetryAfter is how long a name that Spotify couldn't match stays cached
+// before it's searched again (the catalog grows, and search ranking changes).
+const missRetryAfter = 30 * 24 * time.Hour
+
+// Scrobble is a play from a third-party scrobbler, identified by names rather
+// than Spotify IDs.
+type Scrobble struct {
+	Artist     string
+	Track      string
+	Album      string
+	PlayedAt   time.Time // when the listen started
+	DurationMs int       // 0 when the source doesn't say
+	SpotifyID  string    // track ID, when the source already knows it
+}
+
+// ImportScrobbles maps scrobbles to Spotify tracks and writes them into the
+// user's plays under the given source. Names are resolved through
+// Spotify search (best-scoring hit, as for AI recommendations) with results
+// cached in track_name_cache. Scrobbles that can't be matched are kept as unresolved plays
+// under their original names. Play completion and listening sessions are
+// updated from the earliest imported play on. progress may be nil.
+func ImportScrobbles(ctx context.Context, db *sql.DB, sp spotify.API, token TokenFunc, userID int64, source string, scrobbles []Scrobble, progress ProgressFunc) (*Summary, error) {
+	summary := &Summary{Total: len(scrobbles)}
+	var earliest time.Time
+	defer func() { afterImport(ctx, db, userID, summary, earliest) }()
+	r := newNameResolver(db, sp, token, userID)
+
+	for start := 0; start < len(scrobbles); start += batchSize {
+		end := min(start+batchSize, len(scrobbles))
+		batch := scrobbles[start:end]
+
+		if err := r.resolve(ctx, batch); err != nil {
+			return summary, err
+		}
+		plays := r.plays(source, batch, summary)
+
+		earliest = earliestPlay(plays, earliest)
+		added, unresolved, err := writeBatch(ctx, db, userID, plays)
+		if err != nil {
+			return summary, err
+		}
+		summary.Added += added
+		summary.Unresolved += unresolved
+		summary.Skipped += len(plays) - added // already in plays
+		summary.Processed = end
+
+		if progress != nil {
+			progress(*summary)
+		}
+	}
+
+	return summary, nil
+}
+
+// plays turns resolved scrobbles into plays, counting the ones missing a name
+// or time as skipped.
+func (r *nameResolver) plays(source string, batch []Scrobble, summary *Summary) []Play {
+	var plays []Play
+	for _, s := range batch {
+		if s.Artist == "" || s.Track == "" || s.PlayedAt.IsZero() {
+			summary.Skipped++
+			continue
+		}
+
+		m := r.lookup(s)
+		if m == nil {
+			plays = append(plays, Play{
+				Source:     source,
+				TrackName:  s.Track,
+				Artists:    []spotify.Artist{{Name: s.Artist}},
+				AlbumName:  s.Album,
+				DurationMs: s.DurationMs,
+				PlayedAt:   s.PlayedAt,
+			})
+			continue
+		}
+
+		// Scrobblers record when a listen started, Spotify when it ended.
+		// Shift to the end so plays already synced or imported from Spotify
+		// fall inside the dedupe window.
+		playedAt := s.PlayedAt.Add(time.Duration(m.DurationMs) * time.Millisecond)
+		plays = append(plays, Play{
+			Source:     source,
+			TrackID:    m.ID,
+			TrackName:  m.Name,
+			Artists:    m.Artists,
+			AlbumID:    m.AlbumID,
+			AlbumName:  m.AlbumName,
+			DurationMs: m.DurationMs,
+			PlayedAt:   playedAt,
+		})
+	}
+	return plays
+}
+
+// nameResolver turns artist/track names into Spotify track metadata, keeping
+// what it learns for the rest of the import.
+type nameResolver struct {
+	db          *sql.DB
+	sp          spotify.API
+	userID      int64
+	token       TokenFunc
+	accessToken string                // from token, fetched for each batch
+	ids         map[string]string     // lookup key → track ID ("" = no match)
+	meta        map[string]*trackMeta // track ID → metadata
+	failed      map[string]bool       // lookup keys and track IDs Spotify couldn't be asked about
+}
+
+func newNameResolver(db *sql.DB, sp spotify.API, token TokenFunc, userID int64) *nameResolver {
+	return &nameResolver{
+		db:     db,
+		sp:     sp,
+		userID: userID,
+		token:  token,
+		ids:    make(map[string]string),
+		meta:   make(map[string]*trackMeta),
+		failed: make(map[string]bool),
+	}
+}
+
+// lookupFailed reports whether resolving s failed outright (rate limits,
+// outages, a dead token), as opposed to Spotify having no match for it.
+func (r *nameResolver) lookupFailed(s Scrobble) bool {
+	if s.SpotifyID != "" {
+		return r.failed[s.SpotifyID]
+	}
+	return r.failed[lookupKey(s.Artist, s.Track)]
+}
+
+// lookup returns the resolved metadata for a scrobble, or nil if unresolved.
+func (r *nameResolver) lookup(s Scrobble) *trackMeta {
+	id := s.SpotifyID
+	if id == "" {
+		id = r.ids[lookupKey(s.Artist, s.Track)]
+	}
+	if m := r.meta[id]; m != nil && len(m.Artists) > 0 {
+		return m
+	}
+	return nil
+}
+
+// resolve fills in track IDs and metadata for every scrobble in the batch that
+// hasn't been seen yet: cache first, then Spotify search for the rest.
+func (r *nameResolver) resolve(ctx context.Context, batch []Scrobble) error {
+	accessToken, err := r.token(ctx)
+	if err != nil {
+		return fmt.Errorf("getting spotify token: %w", err)
+	}
+	r.accessToken = accessToken
+
+	var keys []string
+	byKey := make(map[string]Scrobble)
+	var needMeta []string
+	for _, s := range batch {
+		if s.SpotifyID != "" {
+			if _, ok := r.meta[s.SpotifyID]; !ok {
+				needMeta = append(needMeta, s.SpotifyID)
+				r.meta[s.SpotifyID] = nil // placeholder so it's only queued once
+			}
+			continue
+		}
+		if s.Artist == "" || s.Track == "" {
+			continue
+		}
+		key := lookupKey(s.Artist, s.Track)
+		if _, ok := r.ids[key]; ok {
+			continue
+		}
+		if _, ok := byKey[key]; !ok {
+			byKey[key] = s
+			keys = append(keys, key)
+		}
+	}
+
+	cached, err := loadNameCache(ctx, r.db, keys)
+	if err != nil {
+		return err
+	}
+
+	// Search for everything the cache didn't know.
+	var misses []string
+	for _, key := range keys {
+		if id, ok := cached[key]; ok {
+			r.ids[key] = id
+			if _, known := r.meta[id]; id != "" && !known {
+				needMeta = append(needMeta, id)
+				r.meta[id] = nil
+			}
+			continue
+		}
+		misses = append(misses, key)
+	}
+
+	if err := r.search(ctx, misses, byKey); err != nil {
+		return err
+	}
+
+	// Metadata for IDs that came from the cache or the source itself.
+	var ids []string
+	for _, id := range needMeta {
+		if r.meta[id] == nil {
+			ids = append(ids, id)
+		}
+	}
+	meta, failed, err := resolveTrackMeta(ctx, r.db, r.sp, r.token, r.userID, ids)
+	if err != nil {
+		return err
+	}
+	for id, m := range meta {
+		r.meta[id] = m
+	}
+	for _, id := range failed {
+		r.failed[id] = true
+	}
+	return nil
+}
+
+// search looks up each key on Spotify, caching hits and definite misses.
+// Lookups that fail outright (rate limits, outages, a dead token) leave the
+// key unresolved and marked failed for this import and aren't cached, so a
+// later import can retry them; only a database error stops the import.
+func (r *nameResolver) search(ctx context.Context, keys []string, byKey map[string]Scrobble) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	type result struct {
+		track *spotify.SearchTrack
+		err   error
+	}
+	results := make([]result, len(keys))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, lookupConcurrency)
+	for i, key := range keys {
+		wg.Add(1)
+		go func(idx int, s Scrobble) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			t, err := recommend.FindTrack(ctx, r.sp, r.accessToken, s.Track, s.Artist)
+			results[idx] = result{track: t, err: err}
+		}(i, byKey[key])
+	}
+	wg.Wait()
+
+	var (
+		cacheKeys, cacheIDs []string
+		failed              int
+		lastErr             error
+	)
+	for i, res := range results {
+		key := keys[i]
+		if res.err != nil {
+			failed++
+			lastErr = res.err
+			r.ids[key] = ""
+			r.failed[key] = true
+			continue
+		}
+
+		id := ""
+		if t := res.track; t != nil {
+			id = t.ID
+			if r.meta[id] == nil {
+				artists := make([]spotify.Artist, len(t.Artists))
+				copy(artists, t.Artists)
+				r.meta[id] = &trackMeta{
+					ID:         id,
+					Name:       t.Name,
+					DurationMs: t.DurationMs,
+					AlbumID:    t.Album.ID,
+					AlbumName:  t.Album.Name,
+					Artists:    artists,
+				}
+			}
+		}
+		r.ids[key] = id
+		cacheKeys = append(cacheKeys, key)
+		cacheIDs = append(cacheIDs, id)
+	}
+
+	if failed > 0 {
+		slog.WarnContext(ctx, "import: searches failed, keeping those plays unresolved", "failed", failed, "total", len(keys), "error", lastErr)
+	}
+
+	return storeNameCache(ctx, r.db, cacheKeys, cacheIDs)
+}
+
+// lookupKey is the normalized cache key for an artist/track pair.
+func lookupKey(artist, track string) string {
+	return normalizeName(artist) + "\x1f" + normalizeName(track)
+}
+
+func normalizeName(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// loadNameCache returns cached track IDs for the given keys. Misses older than
+// missRetryAfter are left out so they get searched again.
+func loadNameCache(ctx context.Context, db *sql.DB, keys []string) (map[string]string, error) {
+	cached := make(map[string]string, len(keys))
+	if len(keys) == 0 {
+		return cached, nil
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT lookup_key, track_id
+		FROM track_name_cache
+		WHERE lookup_key = ANY($1)
+		  AND (track_id != '' OR resolved_at > now() - make_interval(secs => $2))`,
+		keys, missRetryAfter.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("loading name cache: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key, id string
+		if err := rows.Scan(&key, &id); err != nil {
+			return nil, fmt.Errorf("scanning name cache: %w", err)
+		}
+		cached[key] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating name cache: %w", err)
+	}
+	return cached, nil
+}
+
+func storeNameCache(ctx context.Context, db *sql.DB, keys, ids []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO track_name_cache (lookup_key, track_id)
+		SELECT * FROM unnest($1::text[], $2::text[])
+		ON CONFLICT (lookup_key) DO UPDATE
+		SET track_id = EXCLUDED.track_id, resolved_at = now()`,
+		keys, ids)
+	if err != nil {
+		return fmt.Errorf("storing name cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"soundscraibe/internal/spotify"
+)
+
+// SourceSpotifyExport identifies imports of Spotify's Extended Streaming History.
+const SourceSpotifyExport = "spotify_export"
+
+// minPlayMs mirrors Spotify's own definition of a stream: anything shorter than
+// 30 seconds never shows up in recently-played, so it's skipped here too to keep
+// imported and synced history comparable. Skips are estimated from the plays
+// that remain.
+const minPlayMs = 30_000
+
+// StreamRecord is one entry of a Streaming_History_Audio_*.json file from the
+// Spotify data export ("Extended streaming history").
+type StreamRecord struct {
+	TS         string `json:"ts"` // when the stream ended, RFC 3339 UTC
+	MsPlayed   int    `json:"ms_played"`
+	TrackURI   string `json:"spotify_track_uri"`
+	TrackName  string `json:"master_metadata_track_name"`
+	ArtistName string `json:"master_metadata_album_artist_name"`
+	AlbumName  string `json:"master_metadata_album_album_name"`
+	EpisodeURI string `json:"spotify_episode_uri"`
+}
+
+// trackID returns the bare Spotify track ID from the record's URI, if any.
+func (r StreamRecord) trackID() string {
+	return strings.TrimPrefix(r.TrackURI, "spotify:track:")
+}
+
+// ParseSpotifyExport decodes a Streaming_History_Audio_*.json file. Records are
+// decoded one at a time so multi-hundred-MB exports don't need a second copy in memory.
+func ParseSpotifyExport(r io.Reader) ([]StreamRecord, error) {
+	dec := json.NewDecoder(r)
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, fmt.Errorf("reading streaming history: %w", err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return nil, fmt.Errorf("streaming history must be a JSON array")
+	}
+
+	var records []StreamRecord
+	for dec.More() {
+		var rec StreamRecord
+		if err := dec.Decode(&rec); err != nil {
+			return nil, fmt.Errorf("decoding streaming history record %d: %w", len(records)+1, err)
+		}
+		records = append(records, rec)
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return nil, fmt.Errorf("reading end of streaming history: %w", err)
+	}
+
+	return records, nil
+}
+
+// trackMeta is the catalog data needed to turn an export record into a play.
+type trackMeta struct {
+	ID         string
+	Name       string
+	DurationMs int
+	AlbumID    string
+	AlbumName  string
+	Artists    []spotify.Artist
+}
+
+// ImportSpotifyExport writes export records into the user's plays. Track
+// metadata (artist/album IDs, duration) comes from existing plays first and
+// Spotify batch lookups second. Plays already present (within dedupeWindow)
+// are skipped; local files and removed tracks are kept as unresolved plays.
+// Play completion and listening sessions are updated from the earliest
+// imported play on.
+// progress may be nil.
+func ImportSpotifyExport(ctx context.Context, db *sql.DB, sp spotify.API, token TokenFunc, userID int64, records []StreamRecord, progress ProgressFunc) (*Summary, error) {
+	summary := &Summary{Total: len(records)}
+	var earliest time.Time
+	defer func() { afterImport(ctx, db, userID, summary, earliest) }()
+
+	// Collect the track IDs worth resolving.
+	var ids []string
+	seen := make(map[string]bool)
+	for _, rec := range records {
+		if id := rec.trackID(); id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	meta, _, err := resolveTrackMeta(ctx, db, sp, token, userID, ids)
+	if err != nil {
+		return summary, err
+	}
+
+	for start := 0; start < len(records); start += batchSize {
+		end := min(start+batchSize, len(records))
+
+		var plays []Play
+		candidates := 0
+		for _, rec := range records[start:end] {
+			if rec.EpisodeURI != "" || (rec.TrackURI == "" && rec.TrackName == "") {
+				summary.Skipped++ // podcasts, audiobooks, empty entries
+				continue
+			}
+			if rec.MsPlayed < minPlayMs {
+				summary.Skipped++
+				continue
+			}
+			playedAt, err := time.Parse(time.RFC3339, rec.TS)
+			if err != nil {
+				summary.Skipped++
+				continue
+			}
+			msPlayed := rec.MsPlayed
+			m, ok := meta[rec.trackID()]
+			if !ok || len(m.Artists) == 0 {
+				// Local files and tracks since pulled from the catalog: keep the
+				// play under the names from the export.
+				plays = append(plays, Play{
+					Source:    SourceSpotifyExport,
+					TrackName: rec.TrackName,
+					Artists:   []spotify.Artist{{Name: rec.ArtistName}},
+					AlbumName: rec.AlbumName,
+					MsPlayed:  &msPlayed,
+					PlayedAt:  playedAt,
+				})
+				candidates++
+				continue
+			}
+
+			plays = append(plays, Play{
+				Source:     SourceSpotifyExport,
+				TrackID:    rec.trackID(),
+				TrackName:  m.Name,
+				Artists:    m.Artists,
+				AlbumID:    m.AlbumID,
+				AlbumName:  m.AlbumName,
+				DurationMs: m.DurationMs,
+				MsPlayed:   &msPlayed,
+				PlayedAt:   playedAt,
+			})
+			candidates++
+		}
+
+		earliest = earliestPlay(plays, earliest)
+		added, unresolved, err := writeBatch(ctx, db, userID, plays)
+		if err != nil {
+			return summary, err
+		}
+		summary.Added += added
+		summary.Unresolved += unresolved
+		summary.Skipped += candidates - added // already in plays
+		summary.Processed = end
+
+		if progress != nil {
+			progress(*summary)
+		}
+	}
+
+	return summary, nil
+}
+
+// resolveTrackMeta maps track IDs to catalog metadata, using the user's own
+// plays (idx_plays_user_track) and falling back to Spotify's batch /tracks endpoint, with a token from
+// token for every request. IDs Spotify
+// couldn't be asked about (rate limits, outages, a dead token) are left out of
+// meta and returned as failed; only a database error is returned as an error.
+func resolveTrackMeta(ctx context.Context, db *sql.DB, sp spotify.API, token TokenFunc, userID int64, ids []string) (meta map[string]*trackMeta, failed []string, err error) {
+	meta = make(map[string]*trackMeta, len(ids))
+	if len(ids) == 0 {
+		return meta, nil, nil
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT p.track_id, MAX(p.track_name), MAX(p.duration_ms), MAX(p.album_id), MAX(p.album_name), pa.artist_id, MAX(pa.artist_name)
+		FROM plays p
+		JOIN play_artists pa ON pa.play_id = p.id
+		WHERE p.user_id = $1 AND p.track_id = ANY($2) AND pa.artist_id != ''
+		GROUP BY p.track_id, pa.artist_id
+		ORDER BY p.track_id, MIN(pa.position)`, userID, ids)
+	if err != nil {
+		return nil, nil, fmt.Errorf("looking up known tracks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			trackID string
+			m       trackMeta
+			artist  spotify.Artist
+		)
+		if err := rows.Scan(&trackID, &m.Name, &m.DurationMs, &m.AlbumID, &m.AlbumName, &artist.ID, &artist.Name); err != nil {
+			return nil, nil, fmt.Errorf("scanning known track: %w", err)
+		}
+		if existing, ok := meta[trackID]; ok {
+			existing.Artists = append(existing.Artists, artist)
+			continue
+		}
+		m.Artists = []spotify.Artist{artist}
+		meta[trackID] = &m
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("iterating known tracks: %w", err)
+	}
+
+	var missing []string
+	for _, id := range ids {
+		if _, ok := meta[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+
+	for start := 0; start < len(missing); start += spotify.MaxBatchIDs {
+		chunk := missing[start:min(start+spotify.MaxBatchIDs, len(missing))]
+		accessToken, err := token(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("getting spotify token: %w", err)
+		}
+		tracks, err := sp.GetTracks(ctx, accessToken, chunk)
+		if err != nil {
+			slog.WarnContext(ctx, "import: track lookup failed, keeping those plays unresolved", "count", len(chunk), "error", err)
+			failed = append(failed, chunk...)
+			continue
+		}
+		for _, t := range tracks {
+			if t == nil || t.ID == "" {
+				continue
+			}
+			artists := make([]spotify.Artist, len(t.Artists))
+			for i, a := range t.Artists {
+				artists[i] = spotify.Artist{ID: a.ID, Name: a.Name}
+			}
+			meta[t.ID] = &trackMeta{
+				ID:         t.ID,
+				Name:       t.Name,
+				DurationMs: t.DurationMs,
+				AlbumID:    t.Album.ID,
+				AlbumName:  t.Album.Name,
+				Artists:    artists,
+			}
+		}
+	}
+
+	return meta, failed, nil
+}
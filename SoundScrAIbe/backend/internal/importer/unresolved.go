@@ -0,0 +1,68 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// UnresolvedTrack groups a user's unresolved plays (no Spotify match) by the
+// artist/track names they were imported under.
+type UnresolvedTrack struct {
+	ArtistName  string    `json:"artist_name"`
+	TrackName   string    `json:"track_name"`
+	AlbumName   string    `json:"album_name"`
+	Plays       int       `json:"plays"`
+	Sources     []string  `json:"sources"`
+	FirstPlayed time.Time `json:"first_played"`
+	LastPlayed  time.Time `json:"last_played"`
+}
+
+// ListUnresolved returns the user's unresolved tracks, most-played first, along
+// with the total number of distinct unresolved tracks.
+func ListUnresolved(ctx context.Context, db *sql.DB, userID int64, limit, offset int) ([]UnresolvedTrack, int, error) {
+	var total int
+	err := db.QueryRowContext(ctx, `
+		SELECT COUNT(DISTINCT (pa.artist_name, p.track_name))
+		FROM plays p
+		JOIN play_artists pa ON pa.play_id = p.id AND pa.position = 0
+		WHERE p.user_id = $1 AND p.track_id = ''`, userID,
+	).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("counting unresolved tracks: %w", err)
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT pa.artist_name, p.track_name, MAX(p.album_name), COUNT(*),
+		       string_agg(DISTINCT p.source, ','), MIN(p.played_at), MAX(p.played_at)
+		FROM plays p
+		JOIN play_artists pa ON pa.play_id = p.id AND pa.position = 0
+		WHERE p.user_id = $1 AND p.track_id = ''
+		GROUP BY pa.artist_name, p.track_name
+		ORDER BY COUNT(*) DESC, pa.artist_name, p.track_name
+		LIMIT $2 OFFSET $3`, userID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("listing unresolved tracks: %w", err)
+	}
+	defer rows.Close()
+
+	tracks := []UnresolvedTrack{}
+	for rows.Next() {
+		var (
+			t       UnresolvedTrack
+			sources string
+		)
+		if err := rows.Scan(&t.ArtistName, &t.TrackName, &t.AlbumName, &t.Plays,
+			&sources, &t.FirstPlayed, &t.LastPlayed); err != nil {
+			return nil, 0, fmt.Errorf("scanning unresolved track: %w", err)
+		}
+		t.Sources = strings.Split(sources, ",")
+		tracks = append(tracks, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("iterating unresolved tracks: %w", err)
+	}
+	return tracks, total, nil
+}
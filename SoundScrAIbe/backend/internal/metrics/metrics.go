@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "soundscraibe"
+
+// registry holds the app's metrics plus the Go runtime and process collectors.
+var registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequestDuration observes API request latency by Gin route
+	// ("unmatched" for 404s), method and status code.
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route, method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	// SpotifyRequests counts Spotify API calls by endpoint (path with IDs
+	// replaced by {id}) and final status code, "error" for transport
+	// failures, or "circuit_open" when refused by the circuit breaker.
+	SpotifyRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "spotify_requests_total",
+		Help:      "Spotify API calls by endpoint and status.",
+	}, []string{"endpoint", "status"})
+
+	// SpotifyRequestDuration observes Spotify call latency, retries included.
+	SpotifyRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "spotify_request_duration_seconds",
+		Help:      "Spotify API call latency by endpoint, including retries.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint"})
+
+	// SpotifyRetries counts retried Spotify calls by endpoint and reason:
+	// "rate_limited" (429), "server_error" (5xx) or "timeout".
+	SpotifyRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "spotify_retries_total",
+		Help:      "Spotify API calls retried, by endpoint and reason.",
+	}, []string{"endpoint", "reason"})
+
+	// CatalogCacheLookups counts Spotify catalog lookups by entity kind and
+	// where they were answered: "memory", "database" or "miss" (fetched from
+	// Spotify).
+	CatalogCacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "catalog_cache_lookups_total",
+		Help:      "Spotify catalog lookups by kind and cache result.",
+	}, []string{"kind", "result"})
+
+	// AIRequestDuration observes LLM call latency by provider and outcome
+	// ("ok" or "error"). Streaming calls are timed until the last token.
+	AIRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ai_request_duration_seconds",
+		Help:      "LLM call latency by provider and outcome.",
+		Buckets:   []float64{0.5, 1, 2.5, 5, 10, 20, 30, 60, 120, 180},
+	}, []string{"provider", "outcome"})
+
+	// AIRetries counts LLM calls repeated because of a 429 ("rate_limited")
+	// or a response that wasn't valid JSON ("invalid_json").
+	AIRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ai_retries_total",
+		Help:      "LLM calls retried, by provider and reason.",
+	}, []string{"provider", "reason"})
+
+	// Resolutions counts recommendation lookups on Spotify by type and
+	// outcome: "resolved", "low_confidence", "no_match" or "error".
+	Resolutions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "recommendation_resolutions_total",
+		Help:      "Recommendations resolved against Spotify, by type and outcome.",
+	}, []string{"type", "outcome"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequestDuration,
+		SpotifyRequests,
+		SpotifyRequestDuration,
+		SpotifyRetries,
+		CatalogCacheLookups,
+		AIRequestDuration,
+		AIRetries,
+		Resolutions,
+	)
+}
+
+// RegisterDB exports db's connection pool stats (open/in-use/idle
+// connections, waits, closes).
+func RegisterDB(db *sql.DB) {
+	registry.MustRegister(collectors.NewDBStatsCollector(db, namespace))
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,158 @@
+package recommend
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"soundscraibe/internal/ai"
+)
+
+// ---------------------------------------------------------------------------
+// Feedback
+// ---------------------------------------------------------------------------
+
+// Feedback verdicts for a single recommendation.
+const (
+	VerdictUp       = "up"         // good suggestion
+	VerdictDown     = "down"       // bad suggestion
+	VerdictKnown    = "known"      // already knew it
+	VerdictNotForMe = "not_for_me" // fine in itself, but not my taste
+)
+
+// feedbackProfileLimit caps how many feedback entries of each kind go into
+// the taste profile.
+const feedbackProfileLimit = 20
+
+// ErrItemNotFound is returned when feedback targets a recommendation session
+// or item index that doesn't exist for the user.
+var ErrItemNotFound = errors.New("recommendation item not found")
+
+// ValidVerdict reports whether v is a known feedback verdict.
+func ValidVerdict(v string) bool {
+	switch v {
+	case VerdictUp, VerdictDown, VerdictKnown, VerdictNotForMe:
+		return true
+	}
+	return false
+}
+
+// SetFeedback records the user's verdict on item index of a saved
+// recommendation session, replacing any earlier verdict.
+func SetFeedback(ctx context.Context, db *sql.DB, userID, recID int64, index int, verdict string) error {
+	item, err := GetHistoryItem(ctx, db, userID, recID)
+	if err != nil {
+		return err
+	}
+	if item == nil || index < 0 || index >= len(item.Recommendations) {
+		return ErrItemNotFound
+	}
+	rec := item.Recommendations[index]
+
+	_, err = db.ExecContext(ctx,
+		`INSERT INTO recommendation_feedback
+		     (recommendation_id, item_index, user_id, verdict, entity_type, spotify_id, title, artist)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		 ON CONFLICT (recommendation_id, item_index)
+		 DO UPDATE SET verdict = $4, updated_at = now()`,
+		recID, index, userID, verdict, rec.Type, rec.SpotifyID, rec.Title, rec.Artist,
+	)
+	if err != nil {
+		return fmt.Errorf("saving recommendation feedback: %w", err)
+	}
+	return nil
+}
+
+// DeleteFeedback clears the user's verdict on a recommendation item.
+func DeleteFeedback(ctx context.Context, db *sql.DB, userID, recID int64, index int) error {
+	_, err := db.ExecContext(ctx,
+		`DELETE FROM recommendation_feedback
+		 WHERE recommendation_id = $1 AND item_index = $2 AND user_id = $3`,
+		recID, index, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("deleting recommendation feedback: %w", err)
+	}
+	return nil
+}
+
+// applyFeedback fills in the Feedback field of each recommendation in items.
+func applyFeedback(ctx context.Context, db *sql.DB, userID int64, items []HistoryItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*HistoryItem, len(items))
+	ids := make([]int64, len(items))
+	for i := range items {
+		byID[items[i].ID] = &items[i]
+		ids[i] = items[i].ID
+	}
+
+	rows, err := db.QueryContext(ctx,
+		`SELECT recommendation_id, item_index, verdict
+		 FROM recommendation_feedback
+		 WHERE user_id = $1 AND recommendation_id = ANY($2)`,
+		userID, ids,
+	)
+	if err != nil {
+		return fmt.Errorf("querying recommendation feedback: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			recID   int64
+			index   int
+			verdict string
+		)
+		if err := rows.Scan(&recID, &index, &verdict); err != nil {
+			return fmt.Errorf("scanning recommendation feedback: %w", err)
+		}
+		if item := byID[recID]; item != nil && index < len(item.Recommendations) {
+			item.Recommendations[index].Feedback = verdict
+		}
+	}
+	return rows.Err()
+}
+
+// loadFeedbackProfile returns the user's most recent feedback, split into
+// liked (up), rejected (down, not_for_me) and already-known items.
+func loadFeedbackProfile(ctx context.Context, db *sql.DB, userID int64) (liked, rejected, known []ai.FeedbackEntry, err error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT entity_type, title, artist, verdict
+		 FROM recommendation_feedback
+		 WHERE user_id = $1
+		 ORDER BY updated_at DESC
+		 LIMIT $2`, userID, feedbackProfileLimit*4)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("querying recommendation feedback: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e ai.FeedbackEntry
+		if err := rows.Scan(&e.EntityType, &e.Name, &e.Artist, &e.Verdict); err != nil {
+			return nil, nil, nil, fmt.Errorf("scanning recommendation feedback: %w", err)
+		}
+		switch e.Verdict {
+		case VerdictUp:
+			if len(liked) < feedbackProfileLimit {
+				liked = append(liked, e)
+			}
+		case VerdictDown, VerdictNotForMe:
+			if len(rejected) < feedbackProfileLimit {
+				rejected = append(rejected, e)
+			}
+		case VerdictKnown:
+			if len(known) < feedbackProfileLimit {
+				known = append(known, e)
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, nil, fmt.Errorf("iterating recommendation feedback: %w", err)
+	}
+	return liked, rejected, known, nil
+}
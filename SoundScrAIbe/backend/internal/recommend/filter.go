@@ -0,0 +1,250 @@
+package recommend
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"soundscraibe/internal/ai"
+	"soundscraibe/internal/spotify"
+)
+
+// ---------------------------------------------------------------------------
+// Known-item filtering
+// ---------------------------------------------------------------------------
+
+// Reasons a recommendation was dropped, in the order they're checked.
+const (
+	DropRated       = "rated"                  // user has rated it
+	DropShelved     = "shelved"                // on one of the user's shelves
+	DropLiked       = "liked"                  // in the user's Spotify liked songs
+	DropListened    = "listened"               // appears in plays
+	DropRecommended = "previously_recommended" // in an earlier recommendation session
+	DropDuplicate   = "duplicate"              // already in this session
+)
+
+// DroppedRecommendation is a recommendation removed because the user already
+// knows it. Index is its position in the model's output; backfilled items are
+// numbered after the original list.
+type DroppedRecommendation struct {
+	Index          int                    `json:"index"`
+	Reason         string                 `json:"reason"`
+	Recommendation ResolvedRecommendation `json:"recommendation"`
+}
+
+// EnforceNovelty drops recommendations the user already knows and asks the
+// model once for replacements. Failures are logged and leave the list as is
+// (or short), since a recommendation session shouldn't fail over this.
+func EnforceNovelty(ctx context.Context, db *sql.DB, sp spotify.API, llm ai.Provider, accessToken string, userID int64, userMessage string, recs []ResolvedRecommendation) ([]ResolvedRecommendation, []DroppedRecommendation) {
+	kept, dropped, err := FilterKnown(ctx, db, sp, accessToken, userID, recs)
+	if err != nil {
+		slog.WarnContext(ctx, "filter known recommendations failed", "error", err)
+		return recs, []DroppedRecommendation{}
+	}
+	if len(dropped) == 0 {
+		return kept, dropped
+	}
+
+	added, moreDropped, err := Backfill(ctx, db, sp, llm, accessToken, userID, userMessage, kept, dropped)
+	if err != nil {
+		slog.WarnContext(ctx, "backfill recommendations failed", "error", err)
+	}
+
+	return append(kept, added...), append(dropped, moreDropped...)
+}
+
+// FilterKnown splits resolved recommendations into ones that are new to the
+// user and ones they already know, matched by Spotify ID against ratings,
+// shelves, liked songs, listening history and earlier sessions. Unresolved
+// items can't be checked and are kept.
+func FilterKnown(ctx context.Context, db *sql.DB, sp spotify.API, accessToken string, userID int64, recs []ResolvedRecommendation) ([]ResolvedRecommendation, []DroppedRecommendation, error) {
+	return filterKnown(ctx, db, sp, accessToken, userID, recs, 0, map[string]string{})
+}
+
+// Backfill asks the model for replacements for dropped recommendations,
+// resolves them, and filters them the same way (also excluding everything
+// already in the session). It makes a single attempt, so the result may be
+// short.
+func Backfill(ctx context.Context, db *sql.DB, sp spotify.API, llm ai.Provider, accessToken string, userID int64, userMessage string, kept []ResolvedRecommendation, dropped []DroppedRecommendation) ([]ResolvedRecommendation, []DroppedRecommendation, error) {
+	need := len(dropped)
+	if need == 0 {
+		return nil, nil, nil
+	}
+
+	seen := make(map[string]string, len(kept)+len(dropped))
+	exclude := make([]string, 0, len(kept)+len(dropped))
+	for _, r := range kept {
+		seen[recKey(r)] = DropDuplicate
+		exclude = append(exclude, describeRec(r))
+	}
+	for _, d := range dropped {
+		seen[recKey(d.Recommendation)] = d.Reason
+		exclude = append(exclude, describeRec(d.Recommendation))
+	}
+
+	rawJSON, err := ai.CompleteJSON(ctx, llm, ai.BuildSystemPrompt(), userMessage+ai.FormatBackfillRequest(exclude, need))
+	if err != nil {
+		return nil, nil, fmt.Errorf("requesting backfill: %w", err)
+	}
+
+	var aiResp ai.AIResponse
+	if err := json.Unmarshal([]byte(rawJSON), &aiResp); err != nil {
+		return nil, nil, fmt.Errorf("parsing backfill response: %w", err)
+	}
+
+	resolved := ResolveAll(ctx, sp, accessToken, aiResp.Recommendations)
+	added, moreDropped, err := filterKnown(ctx, db, sp, accessToken, userID, resolved, len(kept)+len(dropped), seen)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(added) > need {
+		added = added[:need]
+	}
+	return added, moreDropped, nil
+}
+
+// filterKnown implements FilterKnown. Items whose key is already in seen are
+// dropped with the recorded reason; dropped indexes start at base.
+func filterKnown(ctx context.Context, db *sql.DB, sp spotify.API, accessToken string, userID int64, recs []ResolvedRecommendation, base int, seen map[string]string) ([]ResolvedRecommendation, []DroppedRecommendation, error) {
+	known, err := knownReasons(ctx, db, sp, accessToken, userID, recs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	kept := make([]ResolvedRecommendation, 0, len(recs))
+	dropped := []DroppedRecommendation{}
+	for i, r := range recs {
+		if r.SpotifyID == "" {
+			kept = append(kept, r)
+			continue
+		}
+
+		key := recKey(r)
+		reason, ok := seen[key]
+		if !ok {
+			reason, ok = known[key]
+		}
+		if ok {
+			dropped = append(dropped, DroppedRecommendation{Index: base + i, Reason: reason, Recommendation: r})
+			continue
+		}
+
+		seen[key] = DropDuplicate
+		kept = append(kept, r)
+	}
+
+	return kept, dropped, nil
+}
+
+// knownReasons looks up which of recs the user already knows, keyed by
+// recKey. When an item matches several sources the first check wins.
+func knownReasons(ctx context.Context, db *sql.DB, sp spotify.API, accessToken string, userID int64, recs []ResolvedRecommendation) (map[string]string, error) {
+	var ids, trackIDs, albumIDs, artistIDs []string
+	for _, r := range recs {
+		if r.SpotifyID == "" {
+			continue
+		}
+		ids = append(ids, r.SpotifyID)
+		switch r.Type {
+		case "album":
+			albumIDs = append(albumIDs, r.SpotifyID)
+		case "artist":
+			artistIDs = append(artistIDs, r.SpotifyID)
+		default:
+			trackIDs = append(trackIDs, r.SpotifyID)
+		}
+	}
+
+	reasons := make(map[string]string)
+	if len(ids) == 0 {
+		return reasons, nil
+	}
+
+	mark := func(reason, query string, args ...interface{}) error {
+		rows, err := db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("checking %s items: %w", reason, err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var entityType, entityID string
+			if err := rows.Scan(&entityType, &entityID); err != nil {
+				return fmt.Errorf("scanning %s items: %w", reason, err)
+			}
+			key := entityType + ":" + entityID
+			if _, ok := reasons[key]; !ok {
+				reasons[key] = reason
+			}
+		}
+		return rows.Err()
+	}
+
+	if err := mark(DropRated,
+		`SELECT entity_type, entity_id FROM ratings
+		 WHERE user_id = $1 AND entity_id = ANY($2)`, userID, ids); err != nil {
+		return nil, err
+	}
+
+	if err := mark(DropShelved,
+		`SELECT entity_type, entity_id FROM shelves
+		 WHERE user_id = $1 AND entity_id = ANY($2)`, userID, ids); err != nil {
+		return nil, err
+	}
+
+	if len(trackIDs) > 0 {
+		saved, err := sp.CheckSavedTracks(ctx, accessToken, trackIDs)
+		if err != nil {
+			// Liked songs are only one of several sources; don't fail the filter.
+			slog.WarnContext(ctx, "filter: check saved tracks failed", "error", err)
+		}
+		for i, ok := range saved {
+			if i >= len(trackIDs) {
+				break
+			}
+			key := "track:" + trackIDs[i]
+			if _, exists := reasons[key]; ok && !exists {
+				reasons[key] = DropLiked
+			}
+		}
+	}
+
+	if err := mark(DropListened,
+		`SELECT 'track', track_id FROM plays WHERE user_id = $1 AND track_id = ANY($2)
+		 UNION
+		 SELECT 'album', album_id FROM plays WHERE user_id = $1 AND album_id = ANY($3)
+		 UNION
+		 SELECT 'artist', pa.artist_id FROM play_artists pa JOIN plays p ON p.id = pa.play_id
+		 WHERE p.user_id = $1 AND pa.artist_id = ANY($4)`,
+		userID, trackIDs, albumIDs, artistIDs); err != nil {
+		return nil, err
+	}
+
+	if err := mark(DropRecommended,
+		`SELECT DISTINCT r->>'type', r->>'spotify_id'
+		 FROM ai_recommendations a, jsonb_array_elements(a.results_json) r
+		 WHERE a.user_id = $1 AND r->>'spotify_id' = ANY($2)`, userID, ids); err != nil {
+		return nil, err
+	}
+
+	return reasons, nil
+}
+
+// recKey identifies a resolved recommendation by type and Spotify ID.
+func recKey(r ResolvedRecommendation) string {
+	t := r.Type
+	if t != "album" && t != "artist" {
+		t = "track"
+	}
+	return t + ":" + r.SpotifyID
+}
+
+// describeRec formats a recommendation for the backfill exclusion list.
+func describeRec(r ResolvedRecommendation) string {
+	if r.Type == "artist" || r.Artist == "" {
+		return fmt.Sprintf("[%s] %s", r.Type, r.Title)
+	}
+	return fmt.Sprintf("[%s] \"%s\" by %s", r.Type, r.Title, r.Artist)
+}
@@ -0,0 +1,324 @@
+package recommend
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"soundscraibe/internal/spotify"
+)
+
+// ---------------------------------------------------------------------------
+// Candidate scoring
+// ---------------------------------------------------------------------------
+//
+// Spotify's top search hit for a free-text query is often a cover, a karaoke
+// version or a different artist's song with the same title, especially when
+// the model made the title up. Instead of trusting it, we fetch several
+// candidates and score each one against what we were looking for.
+
+// MinMatchConfidence is the score below which a search hit is treated as no
+// match at all.
+const MinMatchConfidence = 0.6
+
+// searchCandidates is how many search hits are scored per lookup.
+const searchCandidates = 5
+
+// maxAlternates caps the alternate candidates kept on a recommendation.
+const maxAlternates = 3
+
+// coverMarkers flag versions that are almost never what was asked for.
+var coverMarkers = []string{
+	"karaoke", "tribute", "cover", "in the style of", "made famous by",
+	"originally performed", "backing track", "instrumental version", "lullaby",
+}
+
+// Query describes what a lookup is looking for. Album and Year are optional
+// hints that adjust the score when set.
+type Query struct {
+	Title  string
+	Artist string
+	Album  string
+	Year   string
+}
+
+// TrackMatch is a track search hit with its confidence score (0-1).
+type TrackMatch struct {
+	Track      spotify.SearchTrack
+	Confidence float64
+}
+
+// AlbumMatch is an album search hit with its confidence score (0-1).
+type AlbumMatch struct {
+	Album      spotify.SearchAlbum
+	Confidence float64
+}
+
+// ArtistMatch is an artist search hit with its confidence score (0-1).
+type ArtistMatch struct {
+	Artist     spotify.SearchArtist
+	Confidence float64
+}
+
+// scoreTrack rates how well a track hit matches q.
+func scoreTrack(q Query, t spotify.SearchTrack) float64 {
+	names := make([]string, len(t.Artists))
+	for i, a := range t.Artists {
+		names[i] = a.Name
+	}
+
+	artist := artistSimilarity(q.Artist, names)
+	score := 0.55*titleSimilarity(q.Title, t.Name) + 0.45*artist - wrongArtistPenalty(artist)
+	if q.Album != "" {
+		score += 0.1 * (titleSimilarity(q.Album, t.Album.Name) - 0.5)
+	}
+	score += yearAdjustment(q.Year, t.Album.ReleaseDate)
+	score -= coverPenalty(q, t.Name, t.Album.Name, strings.Join(names, " "))
+	return clampScore(score)
+}
+
+// scoreAlbum rates how well an album hit matches q.
+func scoreAlbum(q Query, a spotify.SearchAlbum) float64 {
+	names := make([]string, len(a.Artists))
+	for i, ar := range a.Artists {
+		names[i] = ar.Name
+	}
+
+	artist := artistSimilarity(q.Artist, names)
+	score := 0.55*titleSimilarity(q.Title, a.Name) + 0.45*artist - wrongArtistPenalty(artist)
+	score += yearAdjustment(q.Year, a.ReleaseDate)
+	score -= coverPenalty(q, a.Name, strings.Join(names, " "))
+	return clampScore(score)
+}
+
+// scoreArtist rates how well an artist hit matches q.Artist.
+func scoreArtist(q Query, a spotify.SearchArtist) float64 {
+	return clampScore(similarity(q.Artist, a.Name) - coverPenalty(q, a.Name))
+}
+
+// sortTrackMatches, sortAlbumMatches and sortArtistMatches order matches by
+// confidence, keeping Spotify's ranking for ties.
+func sortTrackMatches(m []TrackMatch) {
+	sort.SliceStable(m, func(i, j int) bool { return m[i].Confidence > m[j].Confidence })
+}
+
+func sortAlbumMatches(m []AlbumMatch) {
+	sort.SliceStable(m, func(i, j int) bool { return m[i].Confidence > m[j].Confidence })
+}
+
+func sortArtistMatches(m []ArtistMatch) {
+	sort.SliceStable(m, func(i, j int) bool { return m[i].Confidence > m[j].Confidence })
+}
+
+// titleSimilarity compares titles, also trying the candidate without version
+// suffixes ("- 2011 Remaster", "(Live)") so those don't cost much.
+func titleSimilarity(want, got string) float64 {
+	full := similarity(want, got)
+	base := similarity(baseTitle(want), baseTitle(got))
+	// A version suffix is a small mismatch, not none at all.
+	return max(full, 0.95*base)
+}
+
+// artistSimilarity compares the wanted artist (possibly "A & B" or "A feat. B")
+// with the best-matching credited artist.
+func artistSimilarity(want string, got []string) float64 {
+	if strings.TrimSpace(want) == "" {
+		return 0.5 // no artist to compare; neither reward nor punish
+	}
+	best := 0.0
+	for _, w := range splitArtists(want) {
+		for _, g := range got {
+			best = max(best, similarity(w, g))
+		}
+	}
+	if len(got) > 1 {
+		best = max(best, similarity(want, strings.Join(got, " ")))
+	}
+	return best
+}
+
+// wrongArtistPenalty pushes a same-title song by a clearly different artist
+// below the threshold; title alone is never enough.
+func wrongArtistPenalty(artist float64) float64 {
+	if artist < 0.5 {
+		return 0.25
+	}
+	return 0
+}
+
+// yearAdjustment nudges the score when the model supplied a release year.
+func yearAdjustment(want, releaseDate string) float64 {
+	want = strings.TrimSpace(want)
+	if len(want) < 4 || len(releaseDate) < 4 {
+		return 0
+	}
+	switch diff := yearDiff(want[:4], releaseDate[:4]); {
+	case diff < 0:
+		return 0
+	case diff == 0:
+		return 0.05
+	case diff <= 2:
+		return 0
+	default:
+		// Reissues and compilations often carry a later date; don't punish hard.
+		return -0.05
+	}
+}
+
+// yearDiff returns |a-b| for two four-digit years, or -1 if either isn't one.
+func yearDiff(a, b string) int {
+	ya, yb := 0, 0
+	for i := 0; i < 4; i++ {
+		if a[i] < '0' || a[i] > '9' || b[i] < '0' || b[i] > '9' {
+			return -1
+		}
+		ya = ya*10 + int(a[i]-'0')
+		yb = yb*10 + int(b[i]-'0')
+	}
+	if ya > yb {
+		return ya - yb
+	}
+	return yb - ya
+}
+
+// coverPenalty is applied when the candidate looks like a cover or karaoke
+// version and the query didn't ask for one.
+func coverPenalty(q Query, fields ...string) float64 {
+	wanted := strings.ToLower(q.Title + " " + q.Artist + " " + q.Album)
+	got := strings.ToLower(strings.Join(fields, " "))
+	for _, m := range coverMarkers {
+		if strings.Contains(got, m) && !strings.Contains(wanted, m) {
+			return 0.4
+		}
+	}
+	return 0
+}
+
+func clampScore(s float64) float64 {
+	return min(max(s, 0), 1)
+}
+
+// ---------------------------------------------------------------------------
+// String similarity
+// ---------------------------------------------------------------------------
+
+// similarity returns a 0-1 score for two names after normalization: the
+// better of an edit-distance ratio (typos, punctuation) and token overlap
+// (reordered or extra words).
+func similarity(a, b string) float64 {
+	na, nb := normalizeTitle(a), normalizeTitle(b)
+	if na == "" || nb == "" {
+		return 0
+	}
+	if na == nb {
+		return 1
+	}
+	return max(editRatio(na, nb), tokenDice(na, nb))
+}
+
+// normalizeTitle lowercases s, turns "&" into "and", drops punctuation and a
+// leading "the", and collapses whitespace.
+func normalizeTitle(s string) string {
+	s = strings.ReplaceAll(strings.ToLower(s), "&", " and ")
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		case r == '\'' || r == '’':
+			// drop apostrophes so "don't" == "dont"
+		default:
+			b.WriteByte(' ')
+		}
+	}
+	s = strings.Join(strings.Fields(b.String()), " ")
+	return strings.TrimPrefix(s, "the ")
+}
+
+// baseTitle strips version decorations: anything in brackets and anything
+// after " - ".
+func baseTitle(s string) string {
+	if i := strings.Index(s, " - "); i > 0 {
+		s = s[:i]
+	}
+	var b strings.Builder
+	depth := 0
+	for _, r := range s {
+		switch r {
+		case '(', '[':
+			depth++
+		case ')', ']':
+			if depth > 0 {
+				depth--
+			}
+		default:
+			if depth == 0 {
+				b.WriteRune(r)
+			}
+		}
+	}
+	return b.String()
+}
+
+// splitArtists splits a multi-artist credit into individual names.
+func splitArtists(s string) []string {
+	lower := strings.ToLower(s)
+	for _, sep := range []string{" featuring ", " feat. ", " feat ", " ft. ", " with ", " x ", " & ", " and ", ", ", "; ", " / "} {
+		lower = strings.ReplaceAll(lower, sep, "\x00")
+	}
+	parts := []string{s}
+	for _, p := range strings.Split(lower, "\x00") {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return parts
+}
+
+// editRatio is 1 - levenshtein(a, b) / max(len(a), len(b)), over runes.
+func editRatio(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return 1 - float64(prev[len(rb)])/float64(longest)
+}
+
+// tokenDice is the Sørensen–Dice coefficient of the two word sets.
+func tokenDice(a, b string) float64 {
+	ta, tb := strings.Fields(a), strings.Fields(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	set := make(map[string]bool, len(ta))
+	for _, t := range ta {
+		set[t] = true
+	}
+	common := 0
+	for _, t := range tb {
+		if set[t] {
+			common++
+			delete(set, t)
+		}
+	}
+	return 2 * float64(common) / float64(len(ta)+len(tb))
+}
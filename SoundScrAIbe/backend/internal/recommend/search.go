@@ -0,0 +1,108 @@
+package recommend
+
+import (
+	"context"
+	"strings"
+
+	"soundscraibe/internal/spotify"
+)
+
+// ---------------------------------------------------------------------------
+// Name → Spotify lookups
+// ---------------------------------------------------------------------------
+//
+// These search Spotify by name and score the top few hits (see match.go).
+// They're shared by Resolve (AI recommendations) and the scrobble importer,
+// which both start from free-text titles rather than Spotify IDs.
+
+// MatchTracks searches for a track and returns the scored candidates, best
+// first.
+func MatchTracks(ctx context.Context, sp spotify.API, accessToken string, q Query) ([]TrackMatch, error) {
+	resp, err := sp.Search(ctx, accessToken, searchQuery(q.Title, q.Artist), "track", searchCandidates)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Tracks == nil {
+		return nil, nil
+	}
+
+	matches := make([]TrackMatch, len(resp.Tracks.Items))
+	for i, t := range resp.Tracks.Items {
+		matches[i] = TrackMatch{Track: t, Confidence: scoreTrack(q, t)}
+	}
+	sortTrackMatches(matches)
+	return matches, nil
+}
+
+// MatchAlbums searches for an album and returns the scored candidates, best
+// first.
+func MatchAlbums(ctx context.Context, sp spotify.API, accessToken string, q Query) ([]AlbumMatch, error) {
+	resp, err := sp.Search(ctx, accessToken, searchQuery(q.Title, q.Artist), "album", searchCandidates)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Albums == nil {
+		return nil, nil
+	}
+
+	matches := make([]AlbumMatch, len(resp.Albums.Items))
+	for i, a := range resp.Albums.Items {
+		matches[i] = AlbumMatch{Album: a, Confidence: scoreAlbum(q, a)}
+	}
+	sortAlbumMatches(matches)
+	return matches, nil
+}
+
+// MatchArtists searches for an artist by q.Artist and returns the scored
+// candidates, best first.
+func MatchArtists(ctx context.Context, sp spotify.API, accessToken string, q Query) ([]ArtistMatch, error) {
+	resp, err := sp.Search(ctx, accessToken, q.Artist, "artist", searchCandidates)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Artists == nil {
+		return nil, nil
+	}
+
+	matches := make([]ArtistMatch, len(resp.Artists.Items))
+	for i, a := range resp.Artists.Items {
+		matches[i] = ArtistMatch{Artist: a, Confidence: scoreArtist(q, a)}
+	}
+	sortArtistMatches(matches)
+	return matches, nil
+}
+
+// FindTrack returns the best track for title and artist, or nil (with a nil
+// error) if nothing scores at least MinMatchConfidence.
+func FindTrack(ctx context.Context, sp spotify.API, accessToken, title, artist string) (*spotify.SearchTrack, error) {
+	matches, err := MatchTracks(ctx, sp, accessToken, Query{Title: title, Artist: artist})
+	if err != nil || len(matches) == 0 || matches[0].Confidence < MinMatchConfidence {
+		return nil, err
+	}
+	return &matches[0].Track, nil
+}
+
+// FindAlbum returns the best album for title and artist, or nil (with a nil
+// error) if nothing scores at least MinMatchConfidence.
+func FindAlbum(ctx context.Context, sp spotify.API, accessToken, title, artist string) (*spotify.SearchAlbum, error) {
+	matches, err := MatchAlbums(ctx, sp, accessToken, Query{Title: title, Artist: artist})
+	if err != nil || len(matches) == 0 || matches[0].Confidence < MinMatchConfidence {
+		return nil, err
+	}
+	return &matches[0].Album, nil
+}
+
+// FindArtist returns the best artist for name, or nil (with a nil error) if
+// nothing scores at least MinMatchConfidence.
+func FindArtist(ctx context.Context, sp spotify.API, accessToken, name string) (*spotify.SearchArtist, error) {
+	matches, err := MatchArtists(ctx, sp, accessToken, Query{Artist: name})
+	if err != nil || len(matches) == 0 || matches[0].Confidence < MinMatchConfidence {
+		return nil, err
+	}
+	return &matches[0].Artist, nil
+}
+
+// searchQuery builds the free-text query used for title + artist lookups.
+func searchQuery(title, artist string) string {
+	return strings.TrimSpace(title + " " + artist)
+}
@@ -5,13 +5,15 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
+	"math"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"soundscraibe/internal/ai"
+	"soundscraibe/internal/metrics"
 	"soundscraibe/internal/spotify"
 )
 
@@ -33,12 +35,33 @@ type ResolvedRecommendation struct {
 	DiscoveryAngle string   `json:"discovery_angle"`
 	MoodTags       []string `json:"mood_tags"`
 	Resolved       bool     `json:"resolved"`
+	// MatchConfidence is how well the chosen (or, if unresolved, best)
+	// Spotify candidate matched the model's title/artist, from 0 to 1.
+	MatchConfidence float64     `json:"match_confidence"`
+	Alternates      []Candidate `json:"alternates,omitempty"`
+	// Feedback is the user's verdict on this item, filled in from
+	// recommendation_feedback when reading history (never stored in results_json).
+	Feedback string `json:"feedback,omitempty"`
+}
+
+// Candidate is a Spotify search hit considered when resolving a
+// recommendation.
+type Candidate struct {
+	SpotifyID  string  `json:"spotify_id"`
+	Title      string  `json:"title"`
+	Artist     string  `json:"artist"`
+	Album      string  `json:"album,omitempty"`
+	Year       string  `json:"year,omitempty"`
+	ImageURL   string  `json:"image_url,omitempty"`
+	SpotifyURL string  `json:"spotify_url,omitempty"`
+	Confidence float64 `json:"confidence"`
 }
 
 // Response is the full recommendation response returned to the frontend.
 type Response struct {
 	TasteSummary    string                   `json:"taste_summary"`
 	Recommendations []ResolvedRecommendation `json:"recommendations"`
+	Dropped         []DroppedRecommendation  `json:"dropped"`
 	Mode            string                   `json:"mode"`
 	UserPrompt      string                   `json:"user_prompt,omitempty"`
 }
@@ -50,6 +73,7 @@ type HistoryItem struct {
 	UserPrompt      string                   `json:"user_prompt"`
 	TasteSummary    string                   `json:"taste_summary"`
 	Recommendations []ResolvedRecommendation `json:"recommendations"`
+	Dropped         []DroppedRecommendation  `json:"dropped"`
 	CreatedAt       time.Time                `json:"created_at"`
 }
 
@@ -59,7 +83,7 @@ type HistoryItem struct {
 
 // GatherTasteProfile collects data from DB and Spotify API concurrently.
 // It uses goroutines for parallel data fetching and is graceful about partial failures.
-func GatherTasteProfile(ctx context.Context, db *sql.DB, accessToken string, userID int64) (*ai.TasteProfile, error) {
+func GatherTasteProfile(ctx context.Context, db *sql.DB, sp spotify.API, accessToken string, userID int64) (*ai.TasteProfile, error) {
 	var (
 		mu   sync.Mutex
 		wg   sync.WaitGroup
@@ -80,6 +104,9 @@ func GatherTasteProfile(ctx context.Context, db *sql.DB, accessToken string, use
 		onRotation     []ai.ShelfEntry
 		userTags       []string
 		listeningHours []ai.HourEntry
+		likedRecs      []ai.FeedbackEntry
+		rejectedRecs   []ai.FeedbackEntry
+		knownRecs      []ai.FeedbackEntry
 	)
 
 	spotifyFailed := 0
@@ -95,9 +122,9 @@ func GatherTasteProfile(ctx context.Context, db *sql.DB, accessToken string, use
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		res, err := spotify.GetTopArtists(ctx, accessToken, "short_term", 20)
+		res, err := sp.GetTopArtists(ctx, accessToken, "short_term", 20)
 		if err != nil {
-			log.Printf("gather: short_term top artists failed (non-fatal): %v", err)
+			slog.WarnContext(ctx, "gather: short_term top artists failed", "error", err)
 			addErr(err)
 			mu.Lock()
 			spotifyFailed++
@@ -112,9 +139,9 @@ func GatherTasteProfile(ctx context.Context, db *sql.DB, accessToken string, use
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		res, err := spotify.GetTopArtists(ctx, accessToken, "medium_term", 30)
+		res, err := sp.GetTopArtists(ctx, accessToken, "medium_term", 30)
 		if err != nil {
-			log.Printf("gather: medium_term top artists failed (non-fatal): %v", err)
+			slog.WarnContext(ctx, "gather: medium_term top artists failed", "error", err)
 			addErr(err)
 			mu.Lock()
 			spotifyFailed++
@@ -129,9 +156,9 @@ func GatherTasteProfile(ctx context.Context, db *sql.DB, accessToken string, use
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		res, err := spotify.GetTopTracks(ctx, accessToken, "medium_term", 20)
+		res, err := sp.GetTopTracks(ctx, accessToken, "medium_term", 20)
 		if err != nil {
-			log.Printf("gather: top tracks failed (non-fatal): %v", err)
+			slog.WarnContext(ctx, "gather: top tracks failed", "error", err)
 			addErr(err)
 			mu.Lock()
 			spotifyFailed++
@@ -146,9 +173,9 @@ func GatherTasteProfile(ctx context.Context, db *sql.DB, accessToken string, use
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		res, err := spotify.GetRecentlyPlayed(ctx, accessToken)
+		res, err := sp.GetRecentlyPlayed(ctx, accessToken, 0)
 		if err != nil {
-			log.Printf("gather: recently played failed (non-fatal): %v", err)
+			slog.WarnContext(ctx, "gather: recently played failed", "error", err)
 			addErr(err)
 			mu.Lock()
 			spotifyFailed++
@@ -173,7 +200,7 @@ func GatherTasteProfile(ctx context.Context, db *sql.DB, accessToken string, use
 			 ORDER BY r.score DESC
 			 LIMIT 30`, userID)
 		if err != nil {
-			log.Printf("gather: high rated query failed: %v", err)
+			slog.ErrorContext(ctx, "gather: high rated query failed", "error", err)
 			addErr(fmt.Errorf("querying high rated: %w", err))
 			return
 		}
@@ -189,7 +216,7 @@ func GatherTasteProfile(ctx context.Context, db *sql.DB, accessToken string, use
 				extraJSON  sql.NullString
 			)
 			if err := rows.Scan(&entityType, &entityID, &score, &name, &extraJSON); err != nil {
-				log.Printf("gather: high rated scan failed: %v", err)
+				slog.ErrorContext(ctx, "gather: high rated scan failed", "error", err)
 				continue
 			}
 			entry := ai.RatedEntry{
@@ -221,7 +248,7 @@ func GatherTasteProfile(ctx context.Context, db *sql.DB, accessToken string, use
 			 WHERE s.user_id = $1 AND s.status = 'on_rotation'
 			 LIMIT 20`, userID)
 		if err != nil {
-			log.Printf("gather: on rotation query failed: %v", err)
+			slog.ErrorContext(ctx, "gather: on rotation query failed", "error", err)
 			addErr(fmt.Errorf("querying on rotation: %w", err))
 			return
 		}
@@ -236,7 +263,7 @@ func GatherTasteProfile(ctx context.Context, db *sql.DB, accessToken string, use
 				extraJSON  sql.NullString
 			)
 			if err := rows.Scan(&entityType, &entityID, &name, &extraJSON); err != nil {
-				log.Printf("gather: on rotation scan failed: %v", err)
+				slog.ErrorContext(ctx, "gather: on rotation scan failed", "error", err)
 				continue
 			}
 			entry := ai.ShelfEntry{
@@ -262,7 +289,7 @@ func GatherTasteProfile(ctx context.Context, db *sql.DB, accessToken string, use
 		rows, err := db.QueryContext(ctx,
 			`SELECT name FROM tags WHERE user_id = $1 ORDER BY name`, userID)
 		if err != nil {
-			log.Printf("gather: tags query failed: %v", err)
+			slog.ErrorContext(ctx, "gather: tags query failed", "error", err)
 			addErr(fmt.Errorf("querying tags: %w", err))
 			return
 		}
@@ -285,14 +312,15 @@ func GatherTasteProfile(ctx context.Context, db *sql.DB, accessToken string, use
 	go func() {
 		defer wg.Done()
 		rows, err := db.QueryContext(ctx,
-			`SELECT EXTRACT(HOUR FROM played_at)::int AS hour, COUNT(*) AS cnt
-			 FROM listening_history
-			 WHERE user_id = $1
+			`SELECT EXTRACT(HOUR FROM p.played_at AT TIME ZONE u.timezone)::int AS hour, COUNT(*) AS cnt
+			 FROM plays p
+			 JOIN users u ON u.id = p.user_id
+			 WHERE p.user_id = $1
 			 GROUP BY hour
 			 ORDER BY cnt DESC
 			 LIMIT 3`, userID)
 		if err != nil {
-			log.Printf("gather: listening hours query failed: %v", err)
+			slog.ErrorContext(ctx, "gather: listening hours query failed", "error", err)
 			addErr(fmt.Errorf("querying listening hours: %w", err))
 			return
 		}
@@ -311,6 +339,20 @@ func GatherTasteProfile(ctx context.Context, db *sql.DB, accessToken string, use
 		mu.Unlock()
 	}()
 
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		liked, rejected, known, err := loadFeedbackProfile(ctx, db, userID)
+		if err != nil {
+			slog.ErrorContext(ctx, "gather: recommendation feedback query failed", "error", err)
+			addErr(err)
+			return
+		}
+		mu.Lock()
+		likedRecs, rejectedRecs, knownRecs = liked, rejected, known
+		mu.Unlock()
+	}()
+
 	wg.Wait()
 
 	// If all 4 Spotify calls failed, that's a problem (no Spotify data at all).
@@ -427,6 +469,9 @@ func GatherTasteProfile(ctx context.Context, db *sql.DB, accessToken string, use
 		UserTags:       userTags,
 		TopGenres:      topGenres,
 		ListeningHours: listeningHours,
+		LikedRecs:      likedRecs,
+		RejectedRecs:   rejectedRecs,
+		KnownRecs:      knownRecs,
 	}
 
 	return profile, nil
@@ -450,7 +495,7 @@ func parseArtistName(extraJSON string) string {
 
 // ResolveAll takes Claude's raw recommendations and searches Spotify for each one concurrently.
 // It returns the resolved array in the same order as the input.
-func ResolveAll(ctx context.Context, accessToken string, recs []ai.RawRecommendation) []ResolvedRecommendation {
+func ResolveAll(ctx context.Context, sp spotify.API, accessToken string, recs []ai.RawRecommendation) []ResolvedRecommendation {
 	results := make([]ResolvedRecommendation, len(recs))
 	var wg sync.WaitGroup
 
@@ -458,110 +503,201 @@ func ResolveAll(ctx context.Context, accessToken string, recs []ai.RawRecommenda
 		wg.Add(1)
 		go func(idx int, r ai.RawRecommendation) {
 			defer wg.Done()
+			results[idx] = Resolve(ctx, sp, accessToken, r)
+		}(i, rec)
+	}
 
-			artistName := r.ArtistName()
-			resolved := ResolvedRecommendation{
-				Type:           r.Type,
-				Title:          r.Title,
-				Artist:         artistName,
-				Album:          r.Album,
-				Year:           r.YearString(),
-				Why:            r.Why,
-				DiscoveryAngle: r.DiscoveryAngle,
-				MoodTags:       r.MoodTags,
-				Resolved:       false,
-			}
-
-			// Build search query based on type.
-			var query, searchType string
-			switch r.Type {
-			case "track":
-				query = r.Title + " " + artistName
-				searchType = "track"
-			case "album":
-				query = r.Title + " " + artistName
-				searchType = "album"
-			case "artist":
-				query = artistName
-				searchType = "artist"
-			default:
-				query = r.Title + " " + artistName
-				searchType = "track"
-			}
+	wg.Wait()
 
-			searchResp, err := spotify.Search(ctx, accessToken, query, searchType, 1)
-			if err != nil {
-				log.Printf("resolve: search failed for %q (non-fatal): %v", query, err)
-				results[idx] = resolved
-				return
-			}
+	return results
+}
 
-			switch searchType {
-			case "track":
-				if searchResp.Tracks != nil && len(searchResp.Tracks.Items) > 0 {
-					t := searchResp.Tracks.Items[0]
-					resolved.SpotifyID = t.ID
-					if len(t.Album.Images) > 0 {
-						resolved.ImageURL = t.Album.Images[0].URL
-					}
-					resolved.SpotifyURL = fmt.Sprintf("https://open.spotify.com/track/%s", t.ID)
-					resolved.Resolved = true
-				}
-			case "album":
-				if searchResp.Albums != nil && len(searchResp.Albums.Items) > 0 {
-					a := searchResp.Albums.Items[0]
-					resolved.SpotifyID = a.ID
-					if len(a.Images) > 0 {
-						resolved.ImageURL = a.Images[0].URL
-					}
-					resolved.SpotifyURL = a.ExternalURLs.Spotify
-					resolved.Resolved = true
-				}
-			case "artist":
-				if searchResp.Artists != nil && len(searchResp.Artists.Items) > 0 {
-					a := searchResp.Artists.Items[0]
-					resolved.SpotifyID = a.ID
-					if len(a.Images) > 0 {
-						resolved.ImageURL = a.Images[0].URL
-					}
-					resolved.SpotifyURL = a.ExternalURLs.Spotify
-					resolved.Resolved = true
-				}
-			}
+// Resolve searches Spotify for a single raw recommendation and picks the
+// best-scoring candidate. If no candidate reaches MinMatchConfidence the
+// recommendation stays unresolved rather than linking to the wrong thing; the
+// candidates are kept as alternates either way. Search failures are logged and
+// leave the recommendation unresolved.
+func Resolve(ctx context.Context, sp spotify.API, accessToken string, r ai.RawRecommendation) ResolvedRecommendation {
+	artistName := r.ArtistName()
+	resolved := ResolvedRecommendation{
+		Type:           r.Type,
+		Title:          r.Title,
+		Artist:         artistName,
+		Album:          r.Album,
+		Year:           r.YearString(),
+		Why:            r.Why,
+		DiscoveryAngle: r.DiscoveryAngle,
+		MoodTags:       r.MoodTags,
+		Resolved:       false,
+	}
 
-			results[idx] = resolved
-		}(i, rec)
+	// Ensure nil mood_tags become empty slices for consistent JSON.
+	if resolved.MoodTags == nil {
+		resolved.MoodTags = []string{}
 	}
 
-	wg.Wait()
+	q := Query{Title: r.Title, Artist: artistName, Album: r.Album, Year: resolved.Year}
 
-	// Ensure nil mood_tags become empty slices for consistent JSON.
-	for i := range results {
-		if results[i].MoodTags == nil {
-			results[i].MoodTags = []string{}
+	var (
+		candidates []Candidate
+		err        error
+	)
+	switch r.Type {
+	case "album":
+		var matches []AlbumMatch
+		matches, err = MatchAlbums(ctx, sp, accessToken, q)
+		for _, m := range matches {
+			candidates = append(candidates, albumCandidate(m))
+		}
+	case "artist":
+		var matches []ArtistMatch
+		matches, err = MatchArtists(ctx, sp, accessToken, q)
+		for _, m := range matches {
+			candidates = append(candidates, artistCandidate(m))
+		}
+	default: // "track" and anything unexpected
+		var matches []TrackMatch
+		matches, err = MatchTracks(ctx, sp, accessToken, q)
+		for _, m := range matches {
+			candidates = append(candidates, trackCandidate(m))
 		}
 	}
+	if err != nil {
+		slog.WarnContext(ctx, "resolve: search failed", "type", r.Type, "title", r.Title, "error", err)
+		observeResolution(r.Type, "error")
+		return resolved
+	}
+	if len(candidates) == 0 {
+		observeResolution(r.Type, "no_match")
+		return resolved
+	}
 
-	return results
+	best := candidates[0]
+	resolved.MatchConfidence = best.Confidence
+	if best.Confidence < MinMatchConfidence {
+		slog.InfoContext(ctx, "resolve: low-confidence match", "type", r.Type, "title", r.Title, "best_title", best.Title, "best_artist", best.Artist, "confidence", best.Confidence)
+		resolved.Alternates = candidates[:min(len(candidates), maxAlternates)]
+		observeResolution(r.Type, "low_confidence")
+		return resolved
+	}
+
+	resolved.SpotifyID = best.SpotifyID
+	resolved.ImageURL = best.ImageURL
+	resolved.SpotifyURL = best.SpotifyURL
+	resolved.Resolved = true
+	if len(candidates) > 1 {
+		resolved.Alternates = candidates[1:min(len(candidates), maxAlternates+1)]
+	}
+
+	observeResolution(r.Type, "resolved")
+	return resolved
+}
+
+// observeResolution counts a Resolve outcome, folding unexpected types into
+// "track" as the search does.
+func observeResolution(typ, outcome string) {
+	if typ != "album" && typ != "artist" {
+		typ = "track"
+	}
+	metrics.Resolutions.WithLabelValues(typ, outcome).Inc()
+}
+
+// trackCandidate, albumCandidate and artistCandidate convert scored search
+// hits into Candidates.
+func trackCandidate(m TrackMatch) Candidate {
+	t := m.Track
+	names := make([]string, len(t.Artists))
+	for i, a := range t.Artists {
+		names[i] = a.Name
+	}
+	c := Candidate{
+		SpotifyID:  t.ID,
+		Title:      t.Name,
+		Artist:     strings.Join(names, ", "),
+		Album:      t.Album.Name,
+		Year:       releaseYear(t.Album.ReleaseDate),
+		SpotifyURL: fmt.Sprintf("https://open.spotify.com/track/%s", t.ID),
+		Confidence: roundConfidence(m.Confidence),
+	}
+	if len(t.Album.Images) > 0 {
+		c.ImageURL = t.Album.Images[0].URL
+	}
+	return c
+}
+
+func albumCandidate(m AlbumMatch) Candidate {
+	a := m.Album
+	names := make([]string, len(a.Artists))
+	for i, ar := range a.Artists {
+		names[i] = ar.Name
+	}
+	c := Candidate{
+		SpotifyID:  a.ID,
+		Title:      a.Name,
+		Artist:     strings.Join(names, ", "),
+		Year:       releaseYear(a.ReleaseDate),
+		SpotifyURL: a.ExternalURLs.Spotify,
+		Confidence: roundConfidence(m.Confidence),
+	}
+	if len(a.Images) > 0 {
+		c.ImageURL = a.Images[0].URL
+	}
+	return c
+}
+
+func artistCandidate(m ArtistMatch) Candidate {
+	a := m.Artist
+	c := Candidate{
+		SpotifyID:  a.ID,
+		Title:      a.Name,
+		Artist:     a.Name,
+		SpotifyURL: a.ExternalURLs.Spotify,
+		Confidence: roundConfidence(m.Confidence),
+	}
+	if len(a.Images) > 0 {
+		c.ImageURL = a.Images[0].URL
+	}
+	return c
+}
+
+// releaseYear returns the year part of a Spotify release date.
+func releaseYear(date string) string {
+	if len(date) < 4 {
+		return ""
+	}
+	return date[:4]
+}
+
+// roundConfidence rounds a score to two decimals for storage and display.
+func roundConfidence(c float64) float64 {
+	return math.Round(c*100) / 100
 }
 
 // ---------------------------------------------------------------------------
 // Persistence
 // ---------------------------------------------------------------------------
 
-// SaveRecommendation persists a recommendation session to the database.
-func SaveRecommendation(ctx context.Context, db *sql.DB, userID int64, mode, userPrompt, tasteSummary string, results []ResolvedRecommendation) (int64, error) {
+// SaveRecommendation persists a recommendation session to the database, along
+// with any recommendations dropped by EnforceNovelty.
+func SaveRecommendation(ctx context.Context, db *sql.DB, userID int64, mode, userPrompt, tasteSummary string, results []ResolvedRecommendation, dropped []DroppedRecommendation) (int64, error) {
 	resultsJSON, err := json.Marshal(results)
 	if err != nil {
 		return 0, fmt.Errorf("marshalling results: %w", err)
 	}
+	if dropped == nil {
+		dropped = []DroppedRecommendation{}
+	}
+	droppedJSON, err := json.Marshal(dropped)
+	if err != nil {
+		return 0, fmt.Errorf("marshalling dropped results: %w", err)
+	}
 
 	var id int64
 	err = db.QueryRowContext(ctx,
-		`INSERT INTO ai_recommendations (user_id, mode, user_prompt, taste_summary, results_json)
-		 VALUES ($1, $2, $3, $4, $5)
+		`INSERT INTO ai_recommendations (user_id, mode, user_prompt, taste_summary, results_json, dropped_json)
+		 VALUES ($1, $2, $3, $4, $5, $6)
 		 RETURNING id`,
-		userID, mode, userPrompt, tasteSummary, resultsJSON,
+		userID, mode, userPrompt, tasteSummary, resultsJSON, droppedJSON,
 	).Scan(&id)
 	if err != nil {
 		return 0, fmt.Errorf("saving recommendation: %w", err)
@@ -606,7 +742,7 @@ func CheckRateLimit(ctx context.Context, db *sql.DB, userID int64) (int, error)
 // GetHistory returns the user's recommendation history (most recent first).
 func GetHistory(ctx context.Context, db *sql.DB, userID int64) ([]HistoryItem, error) {
 	rows, err := db.QueryContext(ctx,
-		`SELECT id, mode, user_prompt, taste_summary, results_json, created_at
+		`SELECT id, mode, user_prompt, taste_summary, results_json, dropped_json, created_at
 		 FROM ai_recommendations
 		 WHERE user_id = $1
 		 ORDER BY created_at DESC
@@ -632,13 +768,17 @@ func GetHistory(ctx context.Context, db *sql.DB, userID int64) ([]HistoryItem, e
 		items = []HistoryItem{}
 	}
 
+	if err := applyFeedback(ctx, db, userID, items); err != nil {
+		return nil, err
+	}
+
 	return items, nil
 }
 
 // GetHistoryItem returns a single recommendation session by ID, scoped to the user.
 func GetHistoryItem(ctx context.Context, db *sql.DB, userID int64, recID int64) (*HistoryItem, error) {
 	row := db.QueryRowContext(ctx,
-		`SELECT id, mode, user_prompt, taste_summary, results_json, created_at
+		`SELECT id, mode, user_prompt, taste_summary, results_json, dropped_json, created_at
 		 FROM ai_recommendations
 		 WHERE id = $1 AND user_id = $2`, recID, userID)
 
@@ -648,9 +788,10 @@ func GetHistoryItem(ctx context.Context, db *sql.DB, userID int64, recID int64)
 		userPrompt   string
 		tasteSummary string
 		resultsJSON  []byte
+		droppedJSON  []byte
 		createdAt    time.Time
 	)
-	if err := row.Scan(&id, &mode, &userPrompt, &tasteSummary, &resultsJSON, &createdAt); err != nil {
+	if err := row.Scan(&id, &mode, &userPrompt, &tasteSummary, &resultsJSON, &droppedJSON, &createdAt); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
@@ -661,15 +802,25 @@ func GetHistoryItem(ctx context.Context, db *sql.DB, userID int64, recID int64)
 	if err := json.Unmarshal(resultsJSON, &recs); err != nil {
 		return nil, fmt.Errorf("parsing recommendation results: %w", err)
 	}
+	dropped := []DroppedRecommendation{}
+	if err := json.Unmarshal(droppedJSON, &dropped); err != nil {
+		return nil, fmt.Errorf("parsing dropped recommendations: %w", err)
+	}
 
-	return &HistoryItem{
+	items := []HistoryItem{{
 		ID:              id,
 		Mode:            mode,
 		UserPrompt:      userPrompt,
 		TasteSummary:    tasteSummary,
 		Recommendations: recs,
+		Dropped:         dropped,
 		CreatedAt:       createdAt,
-	}, nil
+	}}
+	if err := applyFeedback(ctx, db, userID, items); err != nil {
+		return nil, err
+	}
+
+	return &items[0], nil
 }
 
 // scanHistoryItem scans a single row from a recommendation history query.
@@ -680,9 +831,10 @@ func scanHistoryItem(rows *sql.Rows) (*HistoryItem, error) {
 		userPrompt   string
 		tasteSummary string
 		resultsJSON  []byte
+		droppedJSON  []byte
 		createdAt    time.Time
 	)
-	if err := rows.Scan(&id, &mode, &userPrompt, &tasteSummary, &resultsJSON, &createdAt); err != nil {
+	if err := rows.Scan(&id, &mode, &userPrompt, &tasteSummary, &resultsJSON, &droppedJSON, &createdAt); err != nil {
 		return nil, fmt.Errorf("scanning recommendation row: %w", err)
 	}
 
@@ -690,6 +842,10 @@ func scanHistoryItem(rows *sql.Rows) (*HistoryItem, error) {
 	if err := json.Unmarshal(resultsJSON, &recs); err != nil {
 		return nil, fmt.Errorf("parsing recommendation results: %w", err)
 	}
+	dropped := []DroppedRecommendation{}
+	if err := json.Unmarshal(droppedJSON, &dropped); err != nil {
+		return nil, fmt.Errorf("parsing dropped recommendations: %w", err)
+	}
 
 	return &HistoryItem{
 		ID:              id,
@@ -697,6 +853,7 @@ func scanHistoryItem(rows *sql.Rows) (*HistoryItem, error) {
 		UserPrompt:      userPrompt,
 		TasteSummary:    tasteSummary,
 		Recommendations: recs,
+		Dropped:         dropped,
 		CreatedAt:       createdAt,
 	}, nil
 }
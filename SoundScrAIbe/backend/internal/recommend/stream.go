@@ -0,0 +1,220 @@
+package recommend
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"sync"
+
+	"soundscraibe/internal/ai"
+	"soundscraibe/internal/spotify"
+)
+
+// ---------------------------------------------------------------------------
+// Streaming pipeline
+// ---------------------------------------------------------------------------
+
+// Event names emitted by Stream, in the order they first appear.
+const (
+	EventProfile        = "profile"        // taste profile gathered
+	EventToken          = "token"          // raw LLM output chunk
+	EventSummary        = "summary"        // taste summary complete
+	EventRecommendation = "recommendation" // one recommendation resolved against Spotify
+	EventDropped        = "dropped"        // an emitted recommendation was removed as already known
+	EventSaved          = "saved"          // history entry written (final event)
+	EventError          = "error"          // pipeline failed (final event)
+)
+
+// Event is a progress update from Stream.
+type Event struct {
+	Name string
+	Data interface{}
+}
+
+// ProfileEvent summarizes the gathered taste profile.
+type ProfileEvent struct {
+	TopArtists  int      `json:"top_artists"`
+	TopTracks   int      `json:"top_tracks"`
+	RecentPlays int      `json:"recent_plays"`
+	HighRated   int      `json:"high_rated"`
+	TopGenres   []string `json:"top_genres"`
+}
+
+// TokenEvent carries a chunk of raw model output.
+type TokenEvent struct {
+	Text string `json:"text"`
+}
+
+// SummaryEvent carries the taste summary as soon as the model has written it.
+type SummaryEvent struct {
+	TasteSummary string `json:"taste_summary"`
+}
+
+// RecommendationEvent carries one resolved recommendation. Index is its
+// position in the model's list, since lookups finish out of order.
+type RecommendationEvent struct {
+	Index          int                    `json:"index"`
+	Recommendation ResolvedRecommendation `json:"recommendation"`
+}
+
+// SavedEvent carries the ID of the saved history entry.
+type SavedEvent struct {
+	ID int64 `json:"id"`
+}
+
+// ErrorEvent describes why the pipeline stopped.
+type ErrorEvent struct {
+	Error string `json:"error"`
+}
+
+// Stream runs the same pipeline as the blocking endpoints (gather profile →
+// LLM → resolve on Spotify → drop known items and backfill → save) but reports each stage on the returned
+// channel as it happens. Each recommendation is resolved as soon as the model
+// finishes writing it, rather than after the full response. The channel is
+// closed after a final saved or error event.
+func Stream(ctx context.Context, db *sql.DB, sp spotify.API, llm ai.Provider, accessToken string, userID int64, mode, userPrompt string) <-chan Event {
+	events := make(chan Event, 16)
+
+	go func() {
+		defer close(events)
+		send := func(name string, data interface{}) {
+			select {
+			case events <- Event{Name: name, Data: data}:
+			case <-ctx.Done():
+			}
+		}
+
+		profile, err := GatherTasteProfile(ctx, db, sp, accessToken, userID)
+		if err != nil {
+			slog.ErrorContext(ctx, "gather taste profile failed", "error", err)
+			send(EventError, ErrorEvent{Error: "failed to gather taste profile"})
+			return
+		}
+		genres := profile.TopGenres
+		if genres == nil {
+			genres = []string{}
+		}
+		send(EventProfile, ProfileEvent{
+			TopArtists:  len(profile.TopArtists),
+			TopTracks:   len(profile.TopTracks),
+			RecentPlays: len(profile.RecentPlays),
+			HighRated:   len(profile.HighRated),
+			TopGenres:   genres,
+		})
+
+		var (
+			mu      sync.Mutex
+			wg      sync.WaitGroup
+			results []ResolvedRecommendation
+			summary string
+		)
+
+		// launch resolves a recommendation in the background and emits it
+		// when the Spotify lookup finishes.
+		launch := func(rec ai.RawRecommendation) {
+			mu.Lock()
+			idx := len(results)
+			results = append(results, ResolvedRecommendation{})
+			mu.Unlock()
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				resolved := Resolve(ctx, sp, accessToken, rec)
+
+				mu.Lock()
+				results[idx] = resolved
+				mu.Unlock()
+
+				send(EventRecommendation, RecommendationEvent{Index: idx, Recommendation: resolved})
+			}()
+		}
+
+		parser := &ai.ResponseParser{}
+		systemPrompt := ai.BuildSystemPrompt()
+		userMessage := ai.FormatTasteProfile(profile, userPrompt)
+
+		rawJSON, err := ai.StreamJSON(ctx, llm, systemPrompt, userMessage, func(chunk string) {
+			send(EventToken, TokenEvent{Text: chunk})
+
+			s, recs := parser.Feed(chunk)
+			if s != "" {
+				summary = s
+				send(EventSummary, SummaryEvent{TasteSummary: s})
+			}
+			for _, rec := range recs {
+				launch(rec)
+			}
+		})
+		if err != nil {
+			wg.Wait()
+			slog.ErrorContext(ctx, "ai API call failed", "error", err)
+			msg := "AI recommendation failed"
+			if ai.IsRateLimited(err) {
+				msg = "AI service is temporarily busy. Please try again in a minute."
+			}
+			send(EventError, ErrorEvent{Error: msg})
+			return
+		}
+
+		slog.InfoContext(ctx, "ai raw response", "response", rawJSON)
+
+		// Pick up anything the incremental parser couldn't (e.g. a summary
+		// written after the recommendations).
+		var full ai.AIResponse
+		if err := json.Unmarshal([]byte(rawJSON), &full); err == nil {
+			if summary == "" && full.TasteSummary != "" {
+				summary = full.TasteSummary
+				send(EventSummary, SummaryEvent{TasteSummary: summary})
+			}
+			mu.Lock()
+			launched := len(results)
+			mu.Unlock()
+			if len(full.Recommendations) > launched {
+				for _, rec := range full.Recommendations[launched:] {
+					launch(rec)
+				}
+			}
+		}
+
+		wg.Wait()
+
+		if len(results) == 0 {
+			send(EventError, ErrorEvent{Error: "failed to parse AI response"})
+			return
+		}
+
+		// Drop anything the user already knows, then backfill the gaps.
+		// Replacements continue the index sequence after the original list.
+		kept, dropped, err := FilterKnown(ctx, db, sp, accessToken, userID, results)
+		if err != nil {
+			slog.WarnContext(ctx, "filter known recommendations failed", "error", err)
+			kept, dropped = results, []DroppedRecommendation{}
+		}
+		for _, d := range dropped {
+			send(EventDropped, d) // Index refers to an earlier recommendation event
+		}
+		if len(dropped) > 0 {
+			added, moreDropped, err := Backfill(ctx, db, sp, llm, accessToken, userID, userMessage, kept, dropped)
+			if err != nil {
+				slog.WarnContext(ctx, "backfill recommendations failed", "error", err)
+			}
+			for i, rec := range added {
+				send(EventRecommendation, RecommendationEvent{Index: len(results) + i, Recommendation: rec})
+			}
+			kept = append(kept, added...)
+			dropped = append(dropped, moreDropped...)
+		}
+
+		id, err := SaveRecommendation(ctx, db, userID, mode, userPrompt, summary, kept, dropped)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to save recommendation", "error", err)
+			send(EventError, ErrorEvent{Error: "failed to save recommendations"})
+			return
+		}
+		send(EventSaved, SavedEvent{ID: id})
+	}()
+
+	return events
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"soundscraibe/internal/accesstoken"
+	"soundscraibe/internal/user"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxTokenLifetimeDays bounds expires_in_days on new access tokens.
+const maxTokenLifetimeDays = 365
+
+// accessTokenResponse is a personal access token as listed to its owner.
+type accessTokenResponse struct {
+	accesstoken.Token
+	Expired bool `json:"expired"`
+}
+
+// createdAccessTokenResponse adds the token value, shown only on creation.
+type createdAccessTokenResponse struct {
+	accessTokenResponse
+	Value string `json:"token"`
+}
+
+type createAccessTokenRequest struct {
+	Name          string `json:"name" binding:"required"`
+	Scope         string `json:"scope" binding:"required"`
+	ExpiresInDays int    `json:"expires_in_days"` // 0 = never expires
+}
+
+// ---------------------------------------------------------------------------
+// ListAccessTokens handles GET /api/tokens
+// Lists the user's personal access tokens (never the token values), newest
+// first, with when each was last used.
+// ---------------------------------------------------------------------------
+func (h *handlers) ListAccessTokens(c *gin.Context) {
+	u := c.MustGet("user").(*user.User)
+
+	tokens, err := accesstoken.List(c.Request.Context(), h.db, u.ID)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to list access tokens", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list access tokens"})
+		return
+	}
+
+	resp := make([]accessTokenResponse, len(tokens))
+	for i := range tokens {
+		resp[i] = accessTokenResponse{Token: tokens[i], Expired: tokens[i].Expired()}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ---------------------------------------------------------------------------
+// CreateAccessToken handles POST /api/tokens
+// Body: {"name": "...", "scope": "read"|"write", "expires_in_days": 30}
+// Returns the token value once; only its hash is stored.
+// ---------------------------------------------------------------------------
+func (h *handlers) CreateAccessToken(c *gin.Context) {
+	u := c.MustGet("user").(*user.User)
+
+	var req createAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name and scope are required"})
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name must be 1-100 characters"})
+		return
+	}
+	if !accesstoken.ValidScope(req.Scope) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scope must be read or write"})
+		return
+	}
+	if req.ExpiresInDays < 0 || req.ExpiresInDays > maxTokenLifetimeDays {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in_days must be between 0 (never) and 365"})
+		return
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresInDays > 0 {
+		t := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		expiresAt = &t
+	}
+
+	token, plain, err := accesstoken.Create(c.Request.Context(), h.db, u.ID, req.Name, req.Scope, expiresAt)
+	if errors.Is(err, accesstoken.ErrTooMany) {
+		c.JSON(http.StatusConflict, gin.H{"error": "too many access tokens; revoke one first"})
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to create access token", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create access token"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, createdAccessTokenResponse{
+		accessTokenResponse: accessTokenResponse{Token: *token},
+		Value:               plain,
+	})
+}
+
+// ---------------------------------------------------------------------------
+// DeleteAccessToken handles DELETE /api/tokens/:id
+// Revokes one of the user's personal access tokens.
+// ---------------------------------------------------------------------------
+func (h *handlers) DeleteAccessToken(c *gin.Context) {
+	u := c.MustGet("user").(*user.User)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token id"})
+		return
+	}
+
+	found, err := accesstoken.Delete(c.Request.Context(), h.db, u.ID, id)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to delete access token", "token_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke access token"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "access token not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
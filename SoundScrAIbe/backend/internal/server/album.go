@@ -2,11 +2,9 @@ package server
 
 import (
 	"database/sql"
-	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 
-	"soundscraibe/internal/spotify"
 	"soundscraibe/internal/user"
 
 	"github.com/gin-gonic/gin"
@@ -23,9 +21,9 @@ func (h *handlers) AlbumDetail(c *gin.Context) {
 
 	ctx := c.Request.Context()
 
-	album, err := spotify.GetAlbum(ctx, currentUser.AccessToken, albumID)
+	album, err := h.sp.GetAlbum(ctx, currentUser.AccessToken, albumID)
 	if err != nil {
-		log.Printf("failed to fetch album %s for user %d: %v", albumID, currentUser.ID, err)
+		slog.ErrorContext(ctx, "failed to fetch album", "album_id", albumID, "error", err)
 		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to fetch album"})
 		return
 	}
@@ -37,7 +35,7 @@ func (h *handlers) AlbumDetail(c *gin.Context) {
 		currentUser.ID, albumID,
 	).Scan(&ratingScore)
 	if err != nil && err != sql.ErrNoRows {
-		log.Printf("failed to query rating for album %s: %v", albumID, err)
+		slog.ErrorContext(ctx, "failed to query rating", "album_id", albumID, "error", err)
 	}
 
 	// Query shelf
@@ -47,7 +45,7 @@ func (h *handlers) AlbumDetail(c *gin.Context) {
 		currentUser.ID, albumID,
 	).Scan(&shelfStatus)
 	if err != nil && err != sql.ErrNoRows {
-		log.Printf("failed to query shelf for album %s: %v", albumID, err)
+		slog.ErrorContext(ctx, "failed to query shelf", "album_id", albumID, "error", err)
 	}
 
 	// Query tags
@@ -80,23 +78,7 @@ func (h *handlers) AlbumDetail(c *gin.Context) {
 		artists[i] = artistItem{ID: a.ID, Name: a.Name}
 	}
 
-	// Upsert entity_metadata
-	albumCover := ""
-	if len(album.Images) > 0 {
-		albumCover = album.Images[0].URL
-	}
-	artistName := ""
-	if len(album.Artists) > 0 {
-		artistName = album.Artists[0].Name
-	}
-	_, _ = h.db.ExecContext(ctx,
-		`INSERT INTO entity_metadata (entity_type, entity_id, name, image_url, extra_json)
-		 VALUES ('album', $1, $2, $3, $4)
-		 ON CONFLICT ON CONSTRAINT uq_entity_meta
-		 DO UPDATE SET name = $2, image_url = $3, extra_json = $4, updated_at = now()`,
-		albumID, album.Name, albumCover,
-		fmt.Sprintf(`{"artist_name": %q}`, artistName),
-	)
+	h.refreshMetadata(ctx, currentUser, "album", albumID)
 
 	genres := album.Genres
 	if genres == nil {
@@ -2,11 +2,10 @@ package server
 
 import (
 	"database/sql"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 
-	"soundscraibe/internal/spotify"
 	"soundscraibe/internal/user"
 
 	"github.com/gin-gonic/gin"
@@ -23,9 +22,9 @@ func (h *handlers) ArtistDetail(c *gin.Context) {
 
 	ctx := c.Request.Context()
 
-	artist, err := spotify.GetArtist(ctx, currentUser.AccessToken, artistID)
+	artist, err := h.sp.GetArtist(ctx, currentUser.AccessToken, artistID)
 	if err != nil {
-		log.Printf("failed to fetch artist %s for user %d: %v", artistID, currentUser.ID, err)
+		slog.ErrorContext(ctx, "failed to fetch artist", "artist_id", artistID, "error", err)
 		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to fetch artist"})
 		return
 	}
@@ -34,13 +33,14 @@ func (h *handlers) ArtistDetail(c *gin.Context) {
 	var playCount int
 	var firstPlayed, lastPlayed *time.Time
 	err = h.db.QueryRowContext(ctx,
-		`SELECT COUNT(*), MIN(played_at), MAX(played_at)
-		 FROM listening_history
-		 WHERE user_id = $1 AND artist_id = $2`,
+		`SELECT COUNT(*), MIN(p.played_at), MAX(p.played_at)
+		 FROM play_artists pa
+		 JOIN plays p ON p.id = pa.play_id
+		 WHERE pa.artist_id = $2 AND p.user_id = $1`,
 		currentUser.ID, artistID,
 	).Scan(&playCount, &firstPlayed, &lastPlayed)
 	if err != nil {
-		log.Printf("failed to query listening history for artist %s: %v", artistID, err)
+		slog.ErrorContext(ctx, "failed to query listening history", "artist_id", artistID, "error", err)
 		playCount = 0
 	}
 
@@ -51,7 +51,7 @@ func (h *handlers) ArtistDetail(c *gin.Context) {
 		currentUser.ID, artistID,
 	).Scan(&ratingScore)
 	if err != nil && err != sql.ErrNoRows {
-		log.Printf("failed to query rating for artist %s: %v", artistID, err)
+		slog.ErrorContext(ctx, "failed to query rating", "artist_id", artistID, "error", err)
 	}
 
 	// Query shelf
@@ -61,7 +61,7 @@ func (h *handlers) ArtistDetail(c *gin.Context) {
 		currentUser.ID, artistID,
 	).Scan(&shelfStatus)
 	if err != nil && err != sql.ErrNoRows {
-		log.Printf("failed to query shelf for artist %s: %v", artistID, err)
+		slog.ErrorContext(ctx, "failed to query shelf", "artist_id", artistID, "error", err)
 	}
 
 	// Query tags
@@ -84,18 +84,7 @@ func (h *handlers) ArtistDetail(c *gin.Context) {
 		artistTags = []string{}
 	}
 
-	// Upsert entity_metadata
-	artistImage := ""
-	if len(artist.Images) > 0 {
-		artistImage = artist.Images[0].URL
-	}
-	_, _ = h.db.ExecContext(ctx,
-		`INSERT INTO entity_metadata (entity_type, entity_id, name, image_url)
-		 VALUES ('artist', $1, $2, $3)
-		 ON CONFLICT ON CONSTRAINT uq_entity_meta
-		 DO UPDATE SET name = $2, image_url = $3, updated_at = now()`,
-		artistID, artist.Name, artistImage,
-	)
+	h.refreshMetadata(ctx, currentUser, "artist", artistID)
 
 	// Build listening stats
 	listeningStats := gin.H{"play_count": playCount}
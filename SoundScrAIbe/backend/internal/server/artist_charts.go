@@ -3,7 +3,7 @@ package server
 import (
 	"context"
 	"database/sql"
-	"log"
+	"log/slog"
 	"net/http"
 	"sync"
 	"time"
@@ -44,10 +44,7 @@ func (h *handlers) ArtistCharts(c *gin.Context) {
 
 	ctx := c.Request.Context()
 
-	// Step 1: Sync recently played into listening_history (non-fatal).
-	syncListeningHistory(ctx, h.db, currentUser)
-
-	// Step 2: Concurrently fetch DB aggregation and Spotify top artists.
+	// Step 1: Concurrently fetch DB aggregation and Spotify top artists.
 	var (
 		wg         sync.WaitGroup
 		dbEntries  []artistChartEntry
@@ -65,23 +62,23 @@ func (h *handlers) ArtistCharts(c *gin.Context) {
 
 	go func() {
 		defer wg.Done()
-		topArtists, topErr = spotify.GetTopArtists(ctx, currentUser.AccessToken, timeRange, 50)
+		topArtists, topErr = h.sp.GetTopArtists(ctx, currentUser.AccessToken, timeRange, 50)
 	}()
 
 	wg.Wait()
 
 	if dbErr != nil {
-		log.Printf("failed to aggregate artist stats for user %d: %v", currentUser.ID, dbErr)
+		slog.ErrorContext(ctx, "failed to aggregate artist stats", "error", dbErr)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load artist chart data"})
 		return
 	}
 
 	// Top-artists is non-fatal: if it fails, we just skip images/rank.
 	if topErr != nil {
-		log.Printf("failed to fetch top artists for user %d (non-fatal): %v", currentUser.ID, topErr)
+		slog.WarnContext(ctx, "failed to fetch top artists", "error", topErr)
 	}
 
-	// Step 3: Merge top-artist data (image, rank) into DB entries.
+	// Step 2: Merge top-artist data (image, rank) into DB entries.
 	if topArtists != nil {
 		artistRank := make(map[string]int, len(topArtists.Items))
 		artistImage := make(map[string]string, len(topArtists.Items))
@@ -99,19 +96,18 @@ func (h *handlers) ArtistCharts(c *gin.Context) {
 		}
 	}
 
-	// Step 4: Fetch images for artists still missing them (up to 10 individual lookups).
-	fetched := 0
-	for i := range dbEntries {
-		if dbEntries[i].ArtistImageURL != "" || fetched >= 10 {
-			continue
+	// Step 3: Fetch images for artists still missing them in one batch lookup.
+	var missing []string
+	for _, e := range dbEntries {
+		if e.ArtistImageURL == "" {
+			missing = append(missing, e.ArtistID)
 		}
-		artist, err := spotify.GetArtist(ctx, currentUser.AccessToken, dbEntries[i].ArtistID)
-		if err != nil {
-			log.Printf("failed to fetch artist %s (non-fatal): %v", dbEntries[i].ArtistID, err)
-			continue
+	}
+	images := h.fetchArtistImages(ctx, currentUser.AccessToken, missing)
+	for i := range dbEntries {
+		if img, ok := images[dbEntries[i].ArtistID]; ok {
+			dbEntries[i].ArtistImageURL = img
 		}
-		dbEntries[i].ArtistImageURL = artist.ImageURL()
-		fetched++
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -121,53 +117,16 @@ func (h *handlers) ArtistCharts(c *gin.Context) {
 	})
 }
 
-// syncListeningHistory fetches recently played tracks and upserts them into the
-// listening_history table. Errors are logged but do not fail the request.
-func syncListeningHistory(ctx context.Context, db *sql.DB, currentUser *user.User) {
-	result, err := spotify.GetRecentlyPlayed(ctx, currentUser.AccessToken)
-	if err != nil {
-		log.Printf("failed to fetch recently played for sync (user %d): %v", currentUser.ID, err)
-		return
-	}
-
-	for _, item := range result.Items {
-		playedAt, err := time.Parse(time.RFC3339, item.PlayedAt)
-		if err != nil {
-			log.Printf("skipping item with unparseable played_at %q: %v", item.PlayedAt, err)
-			continue
-		}
-
-		for _, artist := range item.Track.Artists {
-			_, err := db.ExecContext(ctx,
-				`INSERT INTO listening_history (user_id, track_id, track_name, artist_id, artist_name, album_id, album_name, duration_ms, played_at)
-				 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-				 ON CONFLICT (user_id, track_id, artist_id, played_at) DO UPDATE SET album_id = EXCLUDED.album_id, album_name = EXCLUDED.album_name`,
-				currentUser.ID,
-				item.Track.ID,
-				item.Track.Name,
-				artist.ID,
-				artist.Name,
-				item.Track.Album.ID,
-				item.Track.Album.Name,
-				item.Track.DurationMs,
-				playedAt,
-			)
-			if err != nil {
-				log.Printf("failed to upsert listening_history row for user %d, track %s: %v",
-					currentUser.ID, item.Track.ID, err)
-			}
-		}
-	}
-}
-
-// aggregateArtistStats queries the listening_history table for per-artist play counts
-// and total listening time, ordered by play count descending.
+// aggregateArtistStats queries plays for per-artist play counts and total
+// listening time, ordered by play count descending. A play counts in full for
+// every artist credited on it.
 func aggregateArtistStats(ctx context.Context, db *sql.DB, userID int64) ([]artistChartEntry, error) {
 	rows, err := db.QueryContext(ctx,
-		`SELECT artist_id, MAX(artist_name) AS artist_name, COUNT(*) AS play_count, SUM(duration_ms) AS listening_time_ms
-		 FROM listening_history
-		 WHERE user_id = $1
-		 GROUP BY artist_id
+		`SELECT pa.artist_id, MAX(pa.artist_name) AS artist_name, COUNT(*) AS play_count, SUM(COALESCE(p.ms_played, p.duration_ms)) AS listening_time_ms
+		 FROM plays p
+		 JOIN play_artists pa ON pa.play_id = p.id
+		 WHERE p.user_id = $1 AND pa.artist_id != ''
+		 GROUP BY pa.artist_id
 		 ORDER BY play_count DESC
 		 LIMIT 50`,
 		userID,
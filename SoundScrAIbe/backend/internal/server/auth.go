@@ -1,13 +1,13 @@
 package server
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"soundscraibe/internal/session"
-	"soundscraibe/internal/spotify"
 	"soundscraibe/internal/user"
 
 	"github.com/gin-gonic/gin"
@@ -17,9 +17,10 @@ import (
 // to build the authorize URL with PKCE params.
 func (h *handlers) SpotifyAuthConfig(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
-		"client_id":    h.cfg.SpotifyClientID,
-		"redirect_uri": h.cfg.SpotifyRedirectURI,
-		"scope":        "user-read-private user-read-email user-read-recently-played user-library-read user-library-modify user-top-read user-follow-read",
+		"authorize_url": h.spotify.AuthorizeURL(),
+		"client_id":     h.cfg.SpotifyClientID,
+		"redirect_uri":  h.cfg.SpotifyRedirectURI,
+		"scope":         "user-read-private user-read-email user-read-recently-played user-library-read user-library-modify user-top-read user-follow-read",
 	})
 }
 
@@ -40,15 +41,15 @@ func (h *handlers) SpotifyCallback(c *gin.Context) {
 	// Exchange code for tokens
 	tokenResp, err := h.spotify.ExchangeCode(c.Request.Context(), req.Code, req.CodeVerifier)
 	if err != nil {
-		log.Printf("spotify token exchange error: %v", err)
+		slog.ErrorContext(c.Request.Context(), "spotify token exchange failed", "error", err)
 		c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("token exchange failed: %v", err)})
 		return
 	}
 
 	// Fetch Spotify profile
-	profile, err := spotify.GetProfile(c.Request.Context(), tokenResp.AccessToken)
+	profile, err := h.sp.GetProfile(c.Request.Context(), tokenResp.AccessToken)
 	if err != nil {
-		log.Printf("spotify profile fetch error: %v", err)
+		slog.ErrorContext(c.Request.Context(), "spotify profile fetch failed", "error", err)
 		c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("failed to fetch Spotify profile: %v", err)})
 		return
 	}
@@ -67,23 +68,24 @@ func (h *handlers) SpotifyCallback(c *gin.Context) {
 		TokenExpiry:   time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second),
 	}
 
-	userID, err := user.Upsert(c.Request.Context(), h.db, u)
+	userID, err := user.Upsert(c.Request.Context(), h.db, h.keys, u)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save user"})
 		return
 	}
 
 	// Create session
-	sessionToken, err := session.Create(c.Request.Context(), h.db, userID)
+	sessionToken, err := session.Create(c.Request.Context(), h.db, userID, sessionClient(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create session"})
 		return
 	}
 
+	// Kick off an initial history sync so stats aren't empty until the next worker tick.
+	h.bg.Go(c.Request.Context(), func(ctx context.Context) { h.syncer.SyncUser(ctx, userID) })
+
 	// Set session cookie
-	secure := h.cfg.Environment != "development"
-	c.SetSameSite(http.SameSiteLaxMode)
-	c.SetCookie("session", sessionToken, 604800, "/", "", secure, true)
+	h.setSessionCookie(c, sessionToken, int(session.Duration.Seconds()))
 
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
@@ -95,8 +97,7 @@ func (h *handlers) Logout(c *gin.Context) {
 		_ = session.Delete(c.Request.Context(), h.db, token)
 	}
 
-	c.SetSameSite(http.SameSiteLaxMode)
-	c.SetCookie("session", "", -1, "/", "", false, true)
+	h.clearSessionCookie(c)
 
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
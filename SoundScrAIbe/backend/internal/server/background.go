@@ -0,0 +1,53 @@
+package server
+
+import (
+	"context"
+	"sync"
+)
+
+// Background runs work that outlives the request that started it (history
+// imports, submitted listens, the sync after login), so shutdown can wait for
+// it instead of killing it halfway.
+type Background struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewBackground creates an empty set of background jobs.
+func NewBackground() *Background {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Background{ctx: ctx, cancel: cancel}
+}
+
+// Go runs fn in a goroutine with a context that keeps reqCtx's values (request
+// and user IDs for logging) but is only cancelled by Shutdown.
+func (b *Background) Go(reqCtx context.Context, fn func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(reqCtx))
+	stop := context.AfterFunc(b.ctx, cancel)
+	b.wg.Go(func() {
+		defer cancel()
+		defer stop()
+		fn(ctx)
+	})
+}
+
+// Shutdown waits for running jobs to finish until ctx is done, then cancels
+// the rest and waits for them to return. It reports ctx's error if jobs had to
+// be cancelled.
+func (b *Background) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		b.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
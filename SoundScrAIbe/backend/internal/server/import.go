@@ -0,0 +1,259 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+
+	"soundscraibe/internal/auth"
+	"soundscraibe/internal/importer"
+	"soundscraibe/internal/user"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportUploadBytes caps a single import upload. A decade of extended
+// streaming history is ~10 files of ~10MB each, so this leaves plenty of room.
+const maxImportUploadBytes = 512 << 20
+
+// ---------------------------------------------------------------------------
+// ImportSpotifyHistory handles POST /api/import/spotify-history
+// Accepts one or more Streaming_History_Audio_*.json files (multipart field
+// "files"), validates them, and imports them in the background.
+// ---------------------------------------------------------------------------
+
+func (h *handlers) ImportSpotifyHistory(c *gin.Context) {
+	u := c.MustGet("user").(*user.User)
+	ctx := c.Request.Context()
+
+	files, ok := uploadedFiles(c)
+	if !ok {
+		return
+	}
+
+	// Parse everything up front so a malformed file is rejected before any rows are written.
+	var records []importer.StreamRecord
+	for _, fh := range files {
+		f, err := fh.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("failed to read %s", fh.Filename)})
+			return
+		}
+		recs, err := importer.ParseSpotifyExport(f)
+		f.Close()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s: %v", fh.Filename, err)})
+			return
+		}
+		records = append(records, recs...)
+	}
+
+	jobID, err := importer.CreateJob(ctx, h.db, u.ID, importer.SourceSpotifyExport, len(records))
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to create import job", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start import"})
+		return
+	}
+
+	token := auth.TokenSource(h.db, h.keys, h.spotify, u)
+	h.runImport(ctx, jobID, func(ctx context.Context, progress importer.ProgressFunc) (*importer.Summary, error) {
+		return importer.ImportSpotifyExport(ctx, h.db, h.sp, token, u.ID, records, progress)
+	})
+
+	c.JSON(http.StatusAccepted, gin.H{"import_id": jobID, "total": len(records)})
+}
+
+// scrobbleParsers maps the "format" form field to the parser for that export.
+var scrobbleParsers = map[string]struct {
+	source string
+	parse  func(io.Reader) ([]importer.Scrobble, error)
+}{
+	"lastfm-csv":   {importer.SourceLastFM, importer.ParseLastFMCSV},
+	"lastfm-json":  {importer.SourceLastFM, importer.ParseLastFMJSON},
+	"listenbrainz": {importer.SourceListenBrainz, importer.ParseListenBrainz},
+}
+
+// ---------------------------------------------------------------------------
+// ImportScrobbles handles POST /api/import/scrobbles
+// Accepts Last.fm CSV/JSON exports or ListenBrainz JSONL dumps (multipart
+// field "files", plus "format": lastfm-csv, lastfm-json or listenbrainz),
+// and imports them in the background, matching names to Spotify tracks.
+// ---------------------------------------------------------------------------
+
+func (h *handlers) ImportScrobbles(c *gin.Context) {
+	u := c.MustGet("user").(*user.User)
+	ctx := c.Request.Context()
+
+	files, ok := uploadedFiles(c)
+	if !ok {
+		return
+	}
+
+	format := c.PostForm("format")
+	parser, ok := scrobbleParsers[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be one of: lastfm-csv, lastfm-json, listenbrainz"})
+		return
+	}
+
+	var scrobbles []importer.Scrobble
+	for _, fh := range files {
+		f, err := fh.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("failed to read %s", fh.Filename)})
+			return
+		}
+		parsed, err := parser.parse(f)
+		f.Close()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s: %v", fh.Filename, err)})
+			return
+		}
+		scrobbles = append(scrobbles, parsed...)
+	}
+
+	jobID, err := importer.CreateJob(ctx, h.db, u.ID, parser.source, len(scrobbles))
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to create import job", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start import"})
+		return
+	}
+
+	token := auth.TokenSource(h.db, h.keys, h.spotify, u)
+	h.runImport(ctx, jobID, func(ctx context.Context, progress importer.ProgressFunc) (*importer.Summary, error) {
+		return importer.ImportScrobbles(ctx, h.db, h.sp, token, u.ID, parser.source, scrobbles, progress)
+	})
+
+	c.JSON(http.StatusAccepted, gin.H{"import_id": jobID, "total": len(scrobbles)})
+}
+
+// uploadedFiles returns the files from the multipart "files" field, writing a
+// 400 response and returning false if there are none.
+func uploadedFiles(c *gin.Context) ([]*multipart.FileHeader, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportUploadBytes)
+	form, err := c.MultipartForm()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expected a multipart upload with one or more files"})
+		return nil, false
+	}
+	files := form.File["files"]
+	if len(files) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at least one file is required in the \"files\" field"})
+		return nil, false
+	}
+	return files, true
+}
+
+// runImport executes an import in the background, detached from the request,
+// persisting progress after every batch and the final summary at the end. An
+// import still running when the shutdown grace period ends is cancelled and
+// recorded as failed.
+func (h *handlers) runImport(reqCtx context.Context, jobID int64, run func(context.Context, importer.ProgressFunc) (*importer.Summary, error)) {
+	h.bg.Go(reqCtx, func(ctx context.Context) {
+		summary, err := run(ctx, func(s importer.Summary) {
+			if err := importer.UpdateJob(ctx, h.db, jobID, s); err != nil {
+				slog.ErrorContext(ctx, "failed to record import progress", "job_id", jobID, "error", err)
+			}
+		})
+		if err != nil && ctx.Err() != nil {
+			err = errImportInterrupted
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "import failed", "job_id", jobID, "error", err)
+		} else {
+			slog.InfoContext(ctx, "import finished", "job_id", jobID, "total", summary.Total, "added", summary.Added, "skipped", summary.Skipped, "unresolved", summary.Unresolved)
+		}
+		if err := importer.FinishJob(context.WithoutCancel(ctx), h.db, jobID, *summary, err); err != nil {
+			slog.ErrorContext(ctx, "failed to record import result", "job_id", jobID, "error", err)
+		}
+	})
+}
+
+// errImportInterrupted is recorded for imports cut short by a server shutdown.
+// Plays already written stay. Uploading the files again isn't suggested: plays
+// kept unresolved the first time would be added again once they resolve.
+var errImportInterrupted = errors.New("interrupted by a server shutdown; plays imported before it were kept")
+
+// ---------------------------------------------------------------------------
+// ImportHistory handles GET /api/import
+// Returns the user's recent imports with their progress/summary.
+// ---------------------------------------------------------------------------
+
+func (h *handlers) ImportHistory(c *gin.Context) {
+	u := c.MustGet("user").(*user.User)
+
+	jobs, err := importer.ListJobs(c.Request.Context(), h.db, u.ID)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to list imports", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load imports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, jobs)
+}
+
+// ---------------------------------------------------------------------------
+// ImportStatus handles GET /api/import/:id
+// Returns a single import's progress, for polling while it runs.
+// ---------------------------------------------------------------------------
+
+func (h *handlers) ImportStatus(c *gin.Context) {
+	u := c.MustGet("user").(*user.User)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid import id"})
+		return
+	}
+
+	job, err := importer.GetJob(c.Request.Context(), h.db, u.ID, id)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to get import", "job_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load import"})
+		return
+	}
+	if job == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "import not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// ---------------------------------------------------------------------------
+// UnresolvedPlays handles GET /api/import/unresolved
+// Lists imported plays that couldn't be matched to a Spotify track, grouped by
+// artist/track name. Supports ?page= and ?limit= (max 100).
+// ---------------------------------------------------------------------------
+
+func (h *handlers) UnresolvedPlays(c *gin.Context) {
+	u := c.MustGet("user").(*user.User)
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	items, total, err := importer.ListUnresolved(c.Request.Context(), h.db, u.ID, limit, (page-1)*limit)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to list unresolved plays", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load unresolved plays"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
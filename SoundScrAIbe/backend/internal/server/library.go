@@ -4,11 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"strconv"
 
-	"soundscraibe/internal/spotify"
 	"soundscraibe/internal/user"
 
 	"github.com/gin-gonic/gin"
@@ -119,7 +118,7 @@ func (h *handlers) Library(c *gin.Context) {
 	countQuery := fmt.Sprintf("SELECT COUNT(DISTINCT (em.entity_type, em.entity_id)) %s", baseQuery)
 	err = h.db.QueryRowContext(ctx, countQuery, args...).Scan(&total)
 	if err != nil {
-		log.Printf("failed to count library items for user %d: %v", currentUser.ID, err)
+		slog.ErrorContext(ctx, "failed to count library items", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load library"})
 		return
 	}
@@ -143,7 +142,7 @@ func (h *handlers) Library(c *gin.Context) {
 
 	rows, err := h.db.QueryContext(ctx, dataQuery, args...)
 	if err != nil {
-		log.Printf("failed to query library items for user %d: %v", currentUser.ID, err)
+		slog.ErrorContext(ctx, "failed to query library items", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load library"})
 		return
 	}
@@ -165,7 +164,7 @@ func (h *handlers) Library(c *gin.Context) {
 		var item libraryItem
 		var extraJSON []byte
 		if err := rows.Scan(&item.EntityType, &item.EntityID, &item.Name, &item.ImageURL, &extraJSON, &item.Rating, &item.Shelf); err != nil {
-			log.Printf("failed to scan library item: %v", err)
+			slog.ErrorContext(ctx, "failed to scan library item", "error", err)
 			continue
 		}
 
@@ -258,7 +257,7 @@ func (h *handlers) LibrarySummary(c *gin.Context) {
 		WHERE (r.id IS NOT NULL OR s.id IS NOT NULL OR EXISTS (SELECT 1 FROM item_tags it WHERE it.user_id = $1 AND it.entity_type = em.entity_type AND it.entity_id = em.entity_id))%s
 	`, entityFilter), countArgs...).Scan(&rated, &onRotation, &wantToListen)
 	if err != nil {
-		log.Printf("failed to query library summary for user %d: %v", currentUser.ID, err)
+		slog.ErrorContext(ctx, "failed to query library summary", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load library summary"})
 		return
 	}
@@ -310,7 +309,7 @@ func (h *handlers) LibrarySummary(c *gin.Context) {
 	switch entityType {
 	case "track", "":
 		// When "track" or no filter, always include tracks
-		if resp, err := spotify.GetSavedTracks(ctx, currentUser.AccessToken, 4, 0); err == nil {
+		if resp, err := h.sp.GetSavedTracks(ctx, currentUser.AccessToken, 4, 0); err == nil {
 			favorites += resp.Total
 			for _, item := range resp.Items {
 				if len(item.Track.Album.Images) > 0 {
@@ -320,15 +319,15 @@ func (h *handlers) LibrarySummary(c *gin.Context) {
 		}
 		if entityType == "" {
 			// No filter = include all types
-			if resp, err := spotify.GetSavedAlbums(ctx, currentUser.AccessToken, 1, 0); err == nil {
+			if resp, err := h.sp.GetSavedAlbums(ctx, currentUser.AccessToken, 1, 0); err == nil {
 				favorites += resp.Total
 			}
-			if resp, err := spotify.GetFollowedArtists(ctx, currentUser.AccessToken, 1, ""); err == nil {
+			if resp, err := h.sp.GetFollowedArtists(ctx, currentUser.AccessToken, 1, ""); err == nil {
 				favorites += resp.Total
 			}
 		}
 	case "album":
-		if resp, err := spotify.GetSavedAlbums(ctx, currentUser.AccessToken, 4, 0); err == nil {
+		if resp, err := h.sp.GetSavedAlbums(ctx, currentUser.AccessToken, 4, 0); err == nil {
 			favorites += resp.Total
 			for _, item := range resp.Items {
 				if len(item.Album.Images) > 0 {
@@ -337,7 +336,7 @@ func (h *handlers) LibrarySummary(c *gin.Context) {
 			}
 		}
 	case "artist":
-		if resp, err := spotify.GetFollowedArtists(ctx, currentUser.AccessToken, 4, ""); err == nil {
+		if resp, err := h.sp.GetFollowedArtists(ctx, currentUser.AccessToken, 4, ""); err == nil {
 			favorites += resp.Total
 			for _, item := range resp.Items {
 				if item.ImageURL() != "" {
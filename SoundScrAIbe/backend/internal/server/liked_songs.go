@@ -1,12 +1,11 @@
 package server
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
 
-	"soundscraibe/internal/spotify"
 	"soundscraibe/internal/user"
 
 	"github.com/gin-gonic/gin"
@@ -30,9 +29,9 @@ func (h *handlers) CheckLikedSongs(c *gin.Context) {
 
 	ids := strings.Split(idsParam, ",")
 
-	saved, err := spotify.CheckSavedTracks(c.Request.Context(), currentUser.AccessToken, ids)
+	saved, err := h.sp.CheckSavedTracks(c.Request.Context(), currentUser.AccessToken, ids)
 	if err != nil {
-		log.Printf("failed to check saved tracks for user %d: %v", currentUser.ID, err)
+		slog.ErrorContext(c.Request.Context(), "failed to check saved tracks", "error", err)
 		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to check saved tracks"})
 		return
 	}
@@ -58,8 +57,8 @@ func (h *handlers) SaveLikedSong(c *gin.Context) {
 	currentUser := u.(*user.User)
 	trackID := c.Param("trackId")
 
-	if err := spotify.SaveTracks(c.Request.Context(), currentUser.AccessToken, []string{trackID}); err != nil {
-		log.Printf("failed to save track %s for user %d: %v", trackID, currentUser.ID, err)
+	if err := h.sp.SaveTracks(c.Request.Context(), currentUser.AccessToken, []string{trackID}); err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to save track", "track_id", trackID, "error", err)
 		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to save track"})
 		return
 	}
@@ -78,8 +77,8 @@ func (h *handlers) RemoveLikedSong(c *gin.Context) {
 	currentUser := u.(*user.User)
 	trackID := c.Param("trackId")
 
-	if err := spotify.RemoveTracks(c.Request.Context(), currentUser.AccessToken, []string{trackID}); err != nil {
-		log.Printf("failed to remove track %s for user %d: %v", trackID, currentUser.ID, err)
+	if err := h.sp.RemoveTracks(c.Request.Context(), currentUser.AccessToken, []string{trackID}); err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to remove track", "track_id", trackID, "error", err)
 		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to remove track"})
 		return
 	}
@@ -128,9 +127,9 @@ func (h *handlers) GetFavorites(c *gin.Context) {
 
 	switch entityType {
 	case "album":
-		resp, err := spotify.GetSavedAlbums(ctx, currentUser.AccessToken, limit, offset)
+		resp, err := h.sp.GetSavedAlbums(ctx, currentUser.AccessToken, limit, offset)
 		if err != nil {
-			log.Printf("failed to get saved albums for user %d: %v", currentUser.ID, err)
+			slog.ErrorContext(ctx, "failed to get saved albums", "error", err)
 			c.JSON(http.StatusBadGateway, gin.H{"error": "failed to fetch favorites"})
 			return
 		}
@@ -154,9 +153,9 @@ func (h *handlers) GetFavorites(c *gin.Context) {
 		}
 
 	case "artist":
-		resp, err := spotify.GetFollowedArtists(ctx, currentUser.AccessToken, limit, "")
+		resp, err := h.sp.GetFollowedArtists(ctx, currentUser.AccessToken, limit, "")
 		if err != nil {
-			log.Printf("failed to get followed artists for user %d: %v", currentUser.ID, err)
+			slog.ErrorContext(ctx, "failed to get followed artists", "error", err)
 			c.JSON(http.StatusBadGateway, gin.H{"error": "failed to fetch favorites"})
 			return
 		}
@@ -176,9 +175,9 @@ func (h *handlers) GetFavorites(c *gin.Context) {
 		}
 
 	case "track":
-		resp, err := spotify.GetSavedTracks(ctx, currentUser.AccessToken, limit, offset)
+		resp, err := h.sp.GetSavedTracks(ctx, currentUser.AccessToken, limit, offset)
 		if err != nil {
-			log.Printf("failed to get saved tracks for user %d: %v", currentUser.ID, err)
+			slog.ErrorContext(ctx, "failed to get saved tracks", "error", err)
 			c.JSON(http.StatusBadGateway, gin.H{"error": "failed to fetch favorites"})
 			return
 		}
@@ -205,7 +204,7 @@ func (h *handlers) GetFavorites(c *gin.Context) {
 		// "All" - combine tracks, albums, and artists
 		items = make([]favoriteItem, 0)
 
-		if resp, err := spotify.GetSavedTracks(ctx, currentUser.AccessToken, limit, offset); err == nil {
+		if resp, err := h.sp.GetSavedTracks(ctx, currentUser.AccessToken, limit, offset); err == nil {
 			total += resp.Total
 			for _, st := range resp.Items {
 				imageURL := ""
@@ -225,7 +224,7 @@ func (h *handlers) GetFavorites(c *gin.Context) {
 			}
 		}
 
-		if resp, err := spotify.GetSavedAlbums(ctx, currentUser.AccessToken, limit, offset); err == nil {
+		if resp, err := h.sp.GetSavedAlbums(ctx, currentUser.AccessToken, limit, offset); err == nil {
 			total += resp.Total
 			for _, item := range resp.Items {
 				imageURL := ""
@@ -245,7 +244,7 @@ func (h *handlers) GetFavorites(c *gin.Context) {
 			}
 		}
 
-		if resp, err := spotify.GetFollowedArtists(ctx, currentUser.AccessToken, limit, ""); err == nil {
+		if resp, err := h.sp.GetFollowedArtists(ctx, currentUser.AccessToken, limit, ""); err == nil {
 			total += resp.Total
 			for _, item := range resp.Items {
 				items = append(items, favoriteItem{
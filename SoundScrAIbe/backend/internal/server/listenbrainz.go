@@ -0,0 +1,200 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"soundscraibe/internal/auth"
+	"soundscraibe/internal/importer"
+	"soundscraibe/internal/listentoken"
+	"soundscraibe/internal/user"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListenBrainz API limits, matched so clients behave the same as against the real service.
+const (
+	maxListensPerSubmission = 1000
+	maxSubmissionBytes      = 10 << 20
+)
+
+// submitListensRequest is the body of POST /1/submit-listens.
+type submitListensRequest struct {
+	ListenType string                        `json:"listen_type"` // single, import, playing_now
+	Payload    []importer.ListenBrainzListen `json:"payload"`
+}
+
+// lbError writes an error in the ListenBrainz {code, error} shape.
+func lbError(c *gin.Context, status int, msg string) {
+	c.JSON(status, gin.H{"code": status, "error": msg})
+}
+
+// ---------------------------------------------------------------------------
+// ValidateToken handles GET /1/validate-token
+// Lets scrobblers check a token before submitting. Like ListenBrainz, an
+// unknown token is a 200 with valid=false rather than a 401.
+// ---------------------------------------------------------------------------
+
+func (h *handlers) ValidateToken(c *gin.Context) {
+	token, ok := listenTokenFromHeader(c)
+	if !ok {
+		token = c.Query("token")
+	}
+	if token == "" {
+		lbError(c, http.StatusBadRequest, "You need to provide an Authorization token.")
+		return
+	}
+
+	userID, err := listentoken.Authenticate(c.Request.Context(), h.db, token)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": http.StatusOK, "message": "Token invalid.", "valid": false})
+		return
+	}
+	u, err := user.GetByID(c.Request.Context(), h.db, h.keys, userID)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": http.StatusOK, "message": "Token invalid.", "valid": false})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": http.StatusOK, "message": "Token valid.", "valid": true, "user_name": u.SpotifyID})
+}
+
+// ---------------------------------------------------------------------------
+// SubmitListens handles POST /1/submit-listens
+// Accepts listens in ListenBrainz format, queues them in pending_listens and
+// writes them into plays (source "scrobble") in the background, matching
+// names to Spotify tracks the same way scrobble imports do. playing_now is
+// accepted and ignored.
+// ---------------------------------------------------------------------------
+
+func (h *handlers) SubmitListens(c *gin.Context) {
+	u := c.MustGet("user").(*user.User)
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSubmissionBytes)
+	var req submitListensRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		lbError(c, http.StatusBadRequest, "Cannot parse JSON document.")
+		return
+	}
+
+	switch req.ListenType {
+	case "single", "import", "playing_now":
+	default:
+		lbError(c, http.StatusBadRequest, "JSON document does not contain a valid listen_type key.")
+		return
+	}
+	if len(req.Payload) == 0 {
+		lbError(c, http.StatusBadRequest, "JSON document does not contain any listens in payload.")
+		return
+	}
+	if req.ListenType != "import" && len(req.Payload) != 1 {
+		lbError(c, http.StatusBadRequest, fmt.Sprintf("JSON document must contain exactly one listen for listen_type %s.", req.ListenType))
+		return
+	}
+	if len(req.Payload) > maxListensPerSubmission {
+		lbError(c, http.StatusBadRequest, fmt.Sprintf("Too many listens. You may not submit more than %d listens at once.", maxListensPerSubmission))
+		return
+	}
+
+	if req.ListenType == "playing_now" {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+		return
+	}
+
+	scrobbles := make([]importer.Scrobble, len(req.Payload))
+	for i, l := range req.Payload {
+		if l.ListenedAt <= 0 {
+			lbError(c, http.StatusBadRequest, "JSON document must contain the key listened_at at the top level.")
+			return
+		}
+		s := l.Scrobble()
+		if s.Artist == "" || s.Track == "" {
+			lbError(c, http.StatusBadRequest, "JSON document does not contain required track_metadata.artist_name and track_metadata.track_name.")
+			return
+		}
+		scrobbles[i] = s
+	}
+
+	// Matching names to Spotify can take a while for large imports, and
+	// scrobblers only need to know the listens were accepted. They're stored
+	// first so a Spotify outage or a restart can't lose them.
+	ctx := c.Request.Context()
+	if err := importer.QueueListens(ctx, h.db, u.ID, scrobbles); err != nil {
+		slog.ErrorContext(ctx, "failed to queue submitted listens", "count", len(scrobbles), "error", err)
+		lbError(c, http.StatusInternalServerError, "Failed to store listens.")
+		return
+	}
+	// Without a usable token every lookup would fail; the listens wait in the
+	// queue for the next sync after the user logs in again.
+	if !u.NeedsReauth && time.Now().Before(u.TokenExpiry) {
+		h.bg.Go(ctx, func(ctx context.Context) { h.importPendingListens(ctx, u) })
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// importPendingListens imports the user's queued listens, logging the outcome.
+// Whatever fails stays queued and is retried after the user's next sync.
+func (h *handlers) importPendingListens(ctx context.Context, u *user.User) {
+	summary, err := importer.ImportPendingListens(ctx, h.db, h.sp, auth.TokenSource(h.db, h.keys, h.spotify, u), u.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "submitted listens import failed, listens stay queued", "error", err)
+		return
+	}
+	slog.InfoContext(ctx, "submitted listens imported", "total", summary.Total, "added", summary.Added, "skipped", summary.Skipped, "unresolved", summary.Unresolved)
+}
+
+// ---------------------------------------------------------------------------
+// Listen token management (session-authenticated)
+// ---------------------------------------------------------------------------
+
+// GetListenToken handles GET /api/listen-token
+// Reports whether the user has a submission token and when it was last used.
+func (h *handlers) GetListenToken(c *gin.Context) {
+	u := c.MustGet("user").(*user.User)
+
+	info, err := listentoken.Get(c.Request.Context(), h.db, u.ID)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to load listen token", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load listen token"})
+		return
+	}
+	if info == nil {
+		c.JSON(http.StatusOK, gin.H{"exists": false})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"exists": true, "created_at": info.CreatedAt, "last_used_at": info.LastUsedAt})
+}
+
+// CreateListenToken handles POST /api/listen-token
+// Creates (or rotates) the user's submission token and returns it once.
+func (h *handlers) CreateListenToken(c *gin.Context) {
+	u := c.MustGet("user").(*user.User)
+
+	token, err := listentoken.Rotate(c.Request.Context(), h.db, u.ID)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to create listen token", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create listen token"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"token": token})
+}
+
+// DeleteListenToken handles DELETE /api/listen-token
+// Revokes the user's submission token.
+func (h *handlers) DeleteListenToken(c *gin.Context) {
+	u := c.MustGet("user").(*user.User)
+
+	if err := listentoken.Delete(c.Request.Context(), h.db, u.ID); err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to delete listen token", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke listen token"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,342 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"soundscraibe/internal/history"
+	"soundscraibe/internal/user"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ---------------------------------------------------------------------------
+// Response types
+// ---------------------------------------------------------------------------
+
+type listeningSession struct {
+	ID              int64      `json:"id"`
+	StartedAt       time.Time  `json:"started_at"`
+	EndedAt         time.Time  `json:"ended_at"`
+	Minutes         float64    `json:"minutes"` // start to end, pauses included
+	ListenedMinutes float64    `json:"listened_minutes"`
+	TrackCount      int        `json:"track_count"`
+	DominantArtist  *artistRef `json:"dominant_artist"`
+	DominantGenre   string     `json:"dominant_genre"`
+}
+
+type artistRef struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type sessionLengthBucket struct {
+	Label      string `json:"label"`
+	MinMinutes int    `json:"min_minutes"`
+	MaxMinutes int    `json:"max_minutes,omitempty"` // exclusive; 0 for the open-ended last bucket
+	Sessions   int    `json:"sessions"`
+}
+
+type sessionStartHour struct {
+	Hour     int `json:"hour"`
+	Sessions int `json:"sessions"`
+}
+
+type statsSessionsResponse struct {
+	Period         string                `json:"period"`
+	Timezone       string                `json:"timezone"`
+	Range          statsWindowJSON       `json:"range"`
+	GapMinutes     int                   `json:"gap_minutes"`
+	Sessions       int                   `json:"sessions"`
+	AvgMinutes     float64               `json:"avg_minutes"`
+	AvgTracks      float64               `json:"avg_tracks"`
+	SessionsPerDay float64               `json:"sessions_per_day"`
+	TotalMinutes   float64               `json:"total_minutes"`
+	LongestSession *listeningSession     `json:"longest_session"`
+	ByLength       []sessionLengthBucket `json:"by_length"`
+	ByStartHour    []sessionStartHour    `json:"by_start_hour"`
+}
+
+// sessionLengthBuckets splits sessions from background listening (a couple of
+// tracks) to sitting down with an album or more.
+var sessionLengthBuckets = []sessionLengthBucket{
+	{Label: "Under 15 min", MinMinutes: 0, MaxMinutes: 15},
+	{Label: "15–60 min", MinMinutes: 15, MaxMinutes: 60},
+	{Label: "1–2 hours", MinMinutes: 60, MaxMinutes: 120},
+	{Label: "2+ hours", MinMinutes: 120},
+}
+
+// sessionColumns are the listening_sessions columns read by scanSession.
+const sessionColumns = `id, started_at, ended_at, track_count, listened_ms,
+	dominant_artist_id, dominant_artist_name, dominant_genre`
+
+func scanSession(row rowScanner, loc *time.Location) (listeningSession, error) {
+	var (
+		s                    listeningSession
+		listenedMs           int64
+		artistID, artistName string
+	)
+	err := row.Scan(&s.ID, &s.StartedAt, &s.EndedAt, &s.TrackCount, &listenedMs,
+		&artistID, &artistName, &s.DominantGenre)
+	if err != nil {
+		return s, err
+	}
+	s.StartedAt = s.StartedAt.In(loc)
+	s.EndedAt = s.EndedAt.In(loc)
+	s.Minutes = roundMinutes(s.EndedAt.Sub(s.StartedAt).Milliseconds())
+	s.ListenedMinutes = roundMinutes(listenedMs)
+	if artistName != "" {
+		s.DominantArtist = &artistRef{ID: artistID, Name: artistName}
+	}
+	return s, nil
+}
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// ---------------------------------------------------------------------------
+// ListeningSessions — session history
+// ---------------------------------------------------------------------------
+
+// ListeningSessions handles GET /api/listening-sessions: the user's listening
+// sessions, newest first, that started within a range (from/to or period,
+// lifetime by default). Supports ?page= and ?limit= (max 100).
+func (h *handlers) ListeningSessions(c *gin.Context) {
+	u, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	currentUser := u.(*user.User)
+	loc, ok := statsLocation(c, currentUser)
+	if !ok {
+		return
+	}
+	window, period, ok := requestWindow(c, loc, time.Now().In(loc), "lifetime")
+	if !ok {
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	ctx := c.Request.Context()
+
+	var total int
+	err = h.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM listening_sessions WHERE user_id = $1 AND started_at >= $2 AND started_at < $3`,
+		currentUser.ID, window.From, window.To,
+	).Scan(&total)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to count listening sessions", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load listening sessions"})
+		return
+	}
+
+	rows, err := h.db.QueryContext(ctx,
+		`SELECT `+sessionColumns+`
+		FROM listening_sessions
+		WHERE user_id = $1 AND started_at >= $2 AND started_at < $3
+		ORDER BY started_at DESC
+		LIMIT $4 OFFSET $5`,
+		currentUser.ID, window.From, window.To, limit, (page-1)*limit,
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to list listening sessions", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load listening sessions"})
+		return
+	}
+	defer rows.Close()
+
+	items := []listeningSession{}
+	for rows.Next() {
+		s, err := scanSession(rows, loc)
+		if err != nil {
+			slog.ErrorContext(ctx, "listening session row scan failed", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load listening sessions"})
+			return
+		}
+		items = append(items, s)
+	}
+	if err := rows.Err(); err != nil {
+		slog.ErrorContext(ctx, "listening sessions rows error", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load listening sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":    items,
+		"total":    total,
+		"page":     page,
+		"limit":    limit,
+		"period":   period,
+		"timezone": loc.String(),
+		"range":    window.toJSON(loc),
+	})
+}
+
+// ---------------------------------------------------------------------------
+// StatsSessions — session length and frequency
+// ---------------------------------------------------------------------------
+
+// StatsSessions handles GET /api/stats/sessions: how many listening sessions
+// started within a range (from/to or period, month by default), how long they
+// run on average, the longest one, sessions per day, and how they split by
+// length and by start hour in the stats timezone.
+func (h *handlers) StatsSessions(c *gin.Context) {
+	u, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	currentUser := u.(*user.User)
+	loc, ok := statsLocation(c, currentUser)
+	if !ok {
+		return
+	}
+	now := time.Now().In(loc)
+	window, period, ok := requestWindow(c, loc, now, "month")
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	resp := statsSessionsResponse{
+		Period:      period,
+		Timezone:    loc.String(),
+		Range:       window.toJSON(loc),
+		GapMinutes:  int(history.SessionGap.Minutes()),
+		ByLength:    make([]sessionLengthBucket, len(sessionLengthBuckets)),
+		ByStartHour: make([]sessionStartHour, 24),
+	}
+	copy(resp.ByLength, sessionLengthBuckets)
+	for hour := range resp.ByStartHour {
+		resp.ByStartHour[hour] = sessionStartHour{Hour: hour}
+	}
+
+	rows, err := h.db.QueryContext(ctx,
+		`SELECT EXTRACT(HOUR FROM started_at AT TIME ZONE $4)::int AS hour,
+			EXTRACT(EPOCH FROM ended_at - started_at) / 60 AS minutes,
+			track_count
+		FROM listening_sessions
+		WHERE user_id = $1 AND started_at >= $2 AND started_at < $3`,
+		currentUser.ID, window.From, window.To, loc.String(),
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "stats sessions query failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load session stats"})
+		return
+	}
+	defer rows.Close()
+
+	var totalMinutes float64
+	var totalTracks int
+	for rows.Next() {
+		var hour, tracks int
+		var minutes float64
+		if err := rows.Scan(&hour, &minutes, &tracks); err != nil {
+			slog.ErrorContext(ctx, "stats sessions row scan failed", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load session stats"})
+			return
+		}
+		resp.Sessions++
+		totalMinutes += minutes
+		totalTracks += tracks
+		if hour >= 0 && hour < 24 {
+			resp.ByStartHour[hour].Sessions++
+		}
+		for i := len(resp.ByLength) - 1; i >= 0; i-- {
+			if minutes >= float64(resp.ByLength[i].MinMinutes) {
+				resp.ByLength[i].Sessions++
+				break
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		slog.ErrorContext(ctx, "stats sessions rows error", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load session stats"})
+		return
+	}
+
+	if resp.Sessions > 0 {
+		resp.TotalMinutes = math.Round(totalMinutes*10) / 10
+		resp.AvgMinutes = math.Round(totalMinutes/float64(resp.Sessions)*10) / 10
+		resp.AvgTracks = math.Round(float64(totalTracks)/float64(resp.Sessions)*10) / 10
+
+		longest, err := h.longestSession(ctx, currentUser.ID, window, loc)
+		if err != nil {
+			slog.ErrorContext(ctx, "longest session query failed", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load session stats"})
+			return
+		}
+		resp.LongestSession = longest
+
+		days, err := h.sessionDays(ctx, currentUser.ID, window, now)
+		if err != nil {
+			slog.ErrorContext(ctx, "session span query failed", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load session stats"})
+			return
+		}
+		resp.SessionsPerDay = math.Round(float64(resp.Sessions)/days*100) / 100
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// longestSession returns the longest session (start to end) that started in
+// the window, or nil if there is none.
+func (h *handlers) longestSession(ctx context.Context, userID int64, window statsWindow, loc *time.Location) (*listeningSession, error) {
+	row := h.db.QueryRowContext(ctx,
+		`SELECT `+sessionColumns+`
+		FROM listening_sessions
+		WHERE user_id = $1 AND started_at >= $2 AND started_at < $3
+		ORDER BY ended_at - started_at DESC, started_at DESC
+		LIMIT 1`,
+		userID, window.From, window.To,
+	)
+	s, err := scanSession(row, loc)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying longest session: %w", err)
+	}
+	return &s, nil
+}
+
+// sessionDays is the number of days sessions per day is averaged over: the
+// window up to now, starting no earlier than the user's first session so
+// lifetime isn't measured from 1970. At least one day.
+func (h *handlers) sessionDays(ctx context.Context, userID int64, window statsWindow, now time.Time) (float64, error) {
+	var first sql.NullTime
+	err := h.db.QueryRowContext(ctx,
+		`SELECT MIN(started_at) FROM listening_sessions WHERE user_id = $1`, userID,
+	).Scan(&first)
+	if err != nil {
+		return 0, fmt.Errorf("querying first session: %w", err)
+	}
+
+	from, to := window.From, window.To
+	if first.Valid && first.Time.After(from) {
+		from = first.Time
+	}
+	if now.Before(to) {
+		to = now
+	}
+	return math.Max(1, to.Sub(from).Hours()/24), nil
+}
@@ -1,7 +1,10 @@
 package server
 
 import (
+	"fmt"
+	"log/slog"
 	"net/http"
+	"time"
 
 	"soundscraibe/internal/user"
 
@@ -26,5 +29,51 @@ func (h *handlers) Me(c *gin.Context) {
 		"country":        currentUser.Country,
 		"product":        currentUser.Product,
 		"follower_count": currentUser.FollowerCount,
+		"timezone":       currentUser.Timezone,
 	})
 }
+
+// SetTimezone handles PUT /api/me/timezone, storing the IANA timezone that
+// stats are computed in.
+func (h *handlers) SetTimezone(c *gin.Context) {
+	u, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	currentUser := u.(*user.User)
+
+	var body struct {
+		Timezone string `json:"timezone"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	loc, err := parseTimezone(body.Timezone)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := user.SetTimezone(c.Request.Context(), h.db, currentUser.ID, loc.String()); err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to set timezone", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save timezone"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"timezone": loc.String()})
+}
+
+// parseTimezone loads an IANA timezone name such as "Europe/Sofia". The
+// server's own "Local" zone is rejected since it means nothing to Postgres.
+func parseTimezone(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("timezone must be an IANA name such as Europe/Sofia")
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", name)
+	}
+	return loc, nil
+}
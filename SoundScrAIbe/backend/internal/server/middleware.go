@@ -1,60 +1,268 @@
 package server
 
 import (
-	"log"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
 	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
 	"time"
 
+	"soundscraibe/internal/accesstoken"
+	"soundscraibe/internal/auth"
+	"soundscraibe/internal/listentoken"
+	"soundscraibe/internal/logging"
+	"soundscraibe/internal/metrics"
 	"soundscraibe/internal/session"
 	"soundscraibe/internal/user"
 
 	"github.com/gin-gonic/gin"
 )
 
-// AuthRequired validates the session cookie, loads the user, and auto-refreshes
-// expired Spotify tokens. Sets "user" on the gin context for downstream handlers.
+// requestIDHeader carries the request ID in and out; a well-formed incoming
+// ID (e.g. from a proxy) is kept so logs line up across hops.
+const requestIDHeader = "X-Request-ID"
+
+// requestID assigns each request an ID, echoes it in the response, and puts
+// it in the request context so everything logged for the request (including
+// outbound Spotify and AI calls) carries it.
+func requestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if !validRequestID(id) {
+			b := make([]byte, 8)
+			rand.Read(b)
+			id = hex.EncodeToString(b)
+		}
+		c.Header(requestIDHeader, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// validRequestID accepts short IDs made of characters safe to log and echo.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// requestLogger logs one line per request with its route, status and
+// duration. The request and user IDs come from the context.
+func requestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		slog.LogAttrs(c.Request.Context(), level, "request",
+			slog.String("method", c.Request.Method),
+			slog.String("route", route),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Int64("duration_ms", time.Since(start).Milliseconds()),
+			slog.String("client_ip", c.ClientIP()),
+		)
+	}
+}
+
+// observeRequest records each request's latency in the HTTP metrics, labelled
+// by Gin route so path parameters don't multiply series.
+func observeRequest() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.HTTPRequestDuration.
+			WithLabelValues(route, c.Request.Method, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// recovery turns a panicking handler into a 500 and logs the panic with the
+// stack, in place of gin's plain-text recovery output.
+func recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered interface{}) {
+		slog.ErrorContext(c.Request.Context(), "panic serving request",
+			"route", c.FullPath(), "panic", recovered, "stack", string(debug.Stack()))
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+	})
+}
+
+// reauthRequiredCode tells the frontend to send the user back through
+// Spotify OAuth rather than showing an error.
+const reauthRequiredCode = "spotify_reauth_required"
+
+// AuthRequired authenticates the request, loads the user, and auto-refreshes
+// expired Spotify tokens. Requests carrying "Authorization: Bearer <token>"
+// use a personal access token (routes that change state add WriteRequired);
+// otherwise the session cookie is validated and its last use recorded
+// (renewing it and re-issuing the cookie when it's close to expiry). Sets
+// "user" on the gin context for downstream handlers, plus "session" for
+// cookie sessions or "token_scope" for access tokens. Users whose Spotify
+// refresh token was rejected get a 401 with code reauthRequiredCode.
 func (h *handlers) AuthRequired() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		token, err := c.Cookie("session")
+		var userID int64
+
+		if bearer, ok := bearerToken(c); ok {
+			id, scope, err := accesstoken.Authenticate(c.Request.Context(), h.db, bearer)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired access token"})
+				return
+			}
+			userID = id
+			c.Set("token_scope", scope)
+		} else {
+			token, err := c.Cookie("session")
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+				return
+			}
+
+			sess, err := session.GetByToken(c.Request.Context(), h.db, token)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired session"})
+				return
+			}
+
+			renewed, err := session.Touch(c.Request.Context(), h.db, sess, sessionClient(c))
+			if err != nil {
+				slog.ErrorContext(c.Request.Context(), "failed to update session", "session_id", sess.ID, "error", err)
+			} else if renewed {
+				h.setSessionCookie(c, token, int(time.Until(sess.ExpiresAt).Seconds()))
+			}
+			userID = sess.UserID
+			c.Set("session", sess)
+		}
+
+		u, err := user.GetByID(c.Request.Context(), h.db, h.keys, userID)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+			return
+		}
+		c.Request = c.Request.WithContext(logging.WithUserID(c.Request.Context(), u.ID))
+
+		// Auto-refresh token if expired or expiring within 5 minutes. Transient
+		// failures are let through (the request may not need Spotify at all);
+		// a rejected refresh token means the user has to log in again.
+		if err := auth.EnsureFreshToken(c.Request.Context(), h.db, h.keys, h.spotify, u); errors.Is(err, auth.ErrReauthRequired) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "spotify authorization expired, please log in again",
+				"code":  reauthRequiredCode,
+			})
+			return
+		} else if err != nil {
+			slog.WarnContext(c.Request.Context(), "failed to refresh spotify token", "error", err)
+		}
+
+		c.Set("user", u)
+		c.Next()
+	}
+}
+
+// WriteRequired rejects requests authenticated with a read-only personal
+// access token. Every route that changes state, writes history or spends a
+// rate limit (the recommendation endpoints) must carry it, whatever its HTTP
+// method. Must run after AuthRequired.
+func (h *handlers) WriteRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if scope, ok := c.Get("token_scope"); ok && scope != accesstoken.ScopeWrite {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "access token is read-only"})
 			return
 		}
+		c.Next()
+	}
+}
 
-		sess, err := session.GetByToken(c.Request.Context(), h.db, token)
+// SessionRequired rejects requests authenticated with a personal access token.
+// It guards endpoints that manage credentials, so a leaked token can't mint
+// new tokens or take over sessions. Must run after AuthRequired.
+func (h *handlers) SessionRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("session"); !ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "this endpoint requires a browser session"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// ListenTokenRequired authenticates ListenBrainz-compatible API calls using the
+// "Authorization: Token <token>" header, loads the token's owner, and sets
+// "user" on the gin context. Errors use the ListenBrainz {code, error} shape.
+func (h *handlers) ListenTokenRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := listenTokenFromHeader(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"code": http.StatusUnauthorized, "error": "You need to provide an Authorization header."})
+			return
+		}
+
+		userID, err := listentoken.Authenticate(c.Request.Context(), h.db, token)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired session"})
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"code": http.StatusUnauthorized, "error": "Invalid authorization token."})
 			return
 		}
 
-		u, err := user.GetByID(c.Request.Context(), h.db, sess.UserID)
+		u, err := user.GetByID(c.Request.Context(), h.db, h.keys, userID)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"code": http.StatusUnauthorized, "error": "Invalid authorization token."})
 			return
 		}
+		c.Request = c.Request.WithContext(logging.WithUserID(c.Request.Context(), u.ID))
 
-		// Auto-refresh token if expired or expiring within 5 minutes
-		if time.Until(u.TokenExpiry) < 5*time.Minute {
-			tokenResp, err := h.spotify.RefreshAccessToken(c.Request.Context(), u.RefreshToken)
-			if err != nil {
-				log.Printf("failed to refresh spotify token for user %d: %v", u.ID, err)
-			} else {
-				refreshToken := u.RefreshToken
-				if tokenResp.RefreshToken != "" {
-					refreshToken = tokenResp.RefreshToken
-				}
-				expiry := time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
-				if err := user.UpdateTokens(c.Request.Context(), h.db, u.ID, tokenResp.AccessToken, refreshToken, expiry); err != nil {
-					log.Printf("failed to update tokens for user %d: %v", u.ID, err)
-				} else {
-					u.AccessToken = tokenResp.AccessToken
-					u.RefreshToken = refreshToken
-					u.TokenExpiry = expiry
-				}
-			}
+		// Listens are matched to Spotify tracks, which needs a live token.
+		if err := auth.EnsureFreshToken(c.Request.Context(), h.db, h.keys, h.spotify, u); err != nil {
+			slog.WarnContext(c.Request.Context(), "failed to refresh spotify token", "error", err)
 		}
 
 		c.Set("user", u)
 		c.Next()
 	}
 }
+
+// bearerToken extracts the token from "Authorization: Bearer <token>".
+func bearerToken(c *gin.Context) (string, bool) {
+	scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// listenTokenFromHeader extracts the token from "Authorization: Token <token>".
+func listenTokenFromHeader(c *gin.Context) (string, bool) {
+	scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Token") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
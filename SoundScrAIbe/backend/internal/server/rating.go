@@ -1,10 +1,13 @@
 package server
 
 import (
+	"context"
 	"database/sql"
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
 
+	"soundscraibe/internal/entitymeta"
 	"soundscraibe/internal/user"
 
 	"github.com/gin-gonic/gin"
@@ -14,6 +17,34 @@ func validEntityType(t string) bool {
 	return t == "track" || t == "album" || t == "artist"
 }
 
+// ensureMetadata stores canonical Spotify metadata for an entity the user is
+// rating, shelving or tagging (see entitymeta.Ensure). If Spotify doesn't know
+// the entity it answers 404 and returns false; other failures are logged and
+// the request goes on.
+func (h *handlers) ensureMetadata(c *gin.Context, currentUser *user.User, entityType, entityID string) bool {
+	err := entitymeta.Ensure(c.Request.Context(), h.db, h.sp, currentUser.AccessToken, entityType, entityID)
+	if errors.Is(err, entitymeta.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": entityType + " not found"})
+		return false
+	}
+	if err != nil {
+		slog.WarnContext(c.Request.Context(), "failed to ensure entity metadata", "entity_type", entityType, "entity_id", entityID, "error", err)
+	}
+	return true
+}
+
+// refreshMetadata re-fetches an entity's metadata when its detail page is
+// opened, and touches it. Failures are logged and ignored.
+func (h *handlers) refreshMetadata(ctx context.Context, currentUser *user.User, entityType, entityID string) {
+	if _, err := entitymeta.Refresh(ctx, h.db, h.sp, currentUser.AccessToken, entityType, []string{entityID}); err != nil {
+		slog.WarnContext(ctx, "failed to refresh entity metadata", "entity_type", entityType, "entity_id", entityID, "error", err)
+		return
+	}
+	if err := entitymeta.Touch(ctx, h.db, entityType, entityID); err != nil {
+		slog.WarnContext(ctx, "failed to touch entity metadata", "entity_type", entityType, "entity_id", entityID, "error", err)
+	}
+}
+
 func (h *handlers) SetRating(c *gin.Context) {
 	u, exists := c.Get("user")
 	if !exists {
@@ -31,18 +62,18 @@ func (h *handlers) SetRating(c *gin.Context) {
 	}
 
 	var body struct {
-		Score    int    `json:"score" binding:"required,min=1,max=10"`
-		Name     string `json:"name"`
-		ImageURL string `json:"image_url"`
+		Score int `json:"score" binding:"required,min=1,max=10"`
 	}
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "score must be between 1 and 10"})
 		return
 	}
 
-	ctx := c.Request.Context()
+	if !h.ensureMetadata(c, currentUser, entityType, entityID) {
+		return
+	}
 
-	_, err := h.db.ExecContext(ctx,
+	_, err := h.db.ExecContext(c.Request.Context(),
 		`INSERT INTO ratings (user_id, entity_type, entity_id, score)
 		 VALUES ($1, $2, $3, $4)
 		 ON CONFLICT ON CONSTRAINT uq_rating
@@ -50,24 +81,11 @@ func (h *handlers) SetRating(c *gin.Context) {
 		currentUser.ID, entityType, entityID, body.Score,
 	)
 	if err != nil {
-		log.Printf("failed to upsert rating for user %d, %s/%s: %v", currentUser.ID, entityType, entityID, err)
+		slog.ErrorContext(c.Request.Context(), "failed to upsert rating", "entity_type", entityType, "entity_id", entityID, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save rating"})
 		return
 	}
 
-	if body.Name != "" {
-		_, err = h.db.ExecContext(ctx,
-			`INSERT INTO entity_metadata (entity_type, entity_id, name, image_url)
-			 VALUES ($1, $2, $3, $4)
-			 ON CONFLICT ON CONSTRAINT uq_entity_meta
-			 DO UPDATE SET name = $3, image_url = $4, updated_at = now()`,
-			entityType, entityID, body.Name, body.ImageURL,
-		)
-		if err != nil {
-			log.Printf("failed to upsert entity_metadata for %s/%s: %v", entityType, entityID, err)
-		}
-	}
-
 	c.JSON(http.StatusOK, gin.H{"score": body.Score})
 }
 
@@ -93,7 +111,7 @@ func (h *handlers) GetRating(c *gin.Context) {
 		currentUser.ID, entityType, entityID,
 	).Scan(&score)
 	if err != nil && err != sql.ErrNoRows {
-		log.Printf("failed to query rating for user %d, %s/%s: %v", currentUser.ID, entityType, entityID, err)
+		slog.ErrorContext(c.Request.Context(), "failed to query rating", "entity_type", entityType, "entity_id", entityID, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get rating"})
 		return
 	}
@@ -122,7 +140,7 @@ func (h *handlers) DeleteRating(c *gin.Context) {
 		currentUser.ID, entityType, entityID,
 	)
 	if err != nil {
-		log.Printf("failed to delete rating for user %d, %s/%s: %v", currentUser.ID, entityType, entityID, err)
+		slog.ErrorContext(c.Request.Context(), "failed to delete rating", "entity_type", entityType, "entity_id", entityID, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete rating"})
 		return
 	}
@@ -1,10 +1,9 @@
 package server
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
 
-	"soundscraibe/internal/spotify"
 	"soundscraibe/internal/user"
 
 	"github.com/gin-gonic/gin"
@@ -20,9 +19,9 @@ func (h *handlers) RecentlyPlayed(c *gin.Context) {
 
 	currentUser := u.(*user.User)
 
-	result, err := spotify.GetRecentlyPlayed(c.Request.Context(), currentUser.AccessToken)
+	result, err := h.sp.GetRecentlyPlayed(c.Request.Context(), currentUser.AccessToken, 0)
 	if err != nil {
-		log.Printf("failed to fetch recently played for user %d: %v", currentUser.ID, err)
+		slog.ErrorContext(c.Request.Context(), "failed to fetch recently played", "error", err)
 		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to fetch recently played tracks"})
 		return
 	}
@@ -2,8 +2,9 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
@@ -24,42 +25,25 @@ func (h *handlers) SmartRecommend(c *gin.Context) {
 	u := c.MustGet("user").(*user.User)
 	ctx := c.Request.Context()
 
-	// Check if Groq API key is configured.
-	if h.cfg.GroqAPIKey == "" {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "AI recommendations are not configured"})
-		return
-	}
-
-	// Check rate limit (60s).
-	remaining, err := recommend.CheckRateLimit(ctx, h.db, u.ID)
-	if err != nil {
-		log.Printf("rate limit check failed for user %d: %v", u.ID, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check rate limit"})
-		return
-	}
-	if remaining > 0 {
-		c.JSON(http.StatusTooManyRequests, gin.H{
-			"error":       fmt.Sprintf("please wait %d seconds between recommendations", remaining),
-			"retry_after": remaining,
-		})
+	if !h.canRecommend(c, u) {
 		return
 	}
 
 	// Gather taste profile.
-	profile, err := recommend.GatherTasteProfile(ctx, h.db, u.AccessToken, u.ID)
+	profile, err := recommend.GatherTasteProfile(ctx, h.db, h.sp, u.AccessToken, u.ID)
 	if err != nil {
-		log.Printf("gather taste profile failed for user %d: %v", u.ID, err)
+		slog.ErrorContext(ctx, "gather taste profile failed", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to gather taste profile"})
 		return
 	}
 
-	// Build prompts and call Groq.
+	// Build prompts and call the AI provider.
 	systemPrompt := ai.BuildSystemPrompt()
 	userMessage := ai.FormatTasteProfile(profile, "")
 
-	rawJSON, err := ai.CompleteJSON(ctx, h.cfg.GroqAPIKey, systemPrompt, userMessage)
+	rawJSON, err := ai.CompleteJSON(ctx, h.llm, systemPrompt, userMessage)
 	if err != nil {
-		log.Printf("ai API call failed for user %d: %v", u.ID, err)
+		slog.ErrorContext(ctx, "ai API call failed", "error", err)
 		if isAIRateLimitError(err) {
 			c.JSON(http.StatusTooManyRequests, gin.H{"error": "AI service is temporarily busy. Please try again in a minute."})
 			return
@@ -68,29 +52,33 @@ func (h *handlers) SmartRecommend(c *gin.Context) {
 		return
 	}
 
-	log.Printf("ai raw response for user %d: %s", u.ID, rawJSON)
+	slog.InfoContext(ctx, "ai raw response", "response", rawJSON)
 
 	// Parse AI response.
 	var aiResp ai.AIResponse
 	if err := json.Unmarshal([]byte(rawJSON), &aiResp); err != nil {
-		log.Printf("failed to parse ai response for user %d: %v", u.ID, err)
+		slog.ErrorContext(ctx, "failed to parse ai response", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to parse AI response"})
 		return
 	}
 
 	// Resolve recommendations to Spotify IDs.
-	resolved := recommend.ResolveAll(ctx, u.AccessToken, aiResp.Recommendations)
+	resolved := recommend.ResolveAll(ctx, h.sp, u.AccessToken, aiResp.Recommendations)
+
+	// Drop anything the user already knows and backfill the gaps.
+	resolved, dropped := recommend.EnforceNovelty(ctx, h.db, h.sp, h.llm, u.AccessToken, u.ID, userMessage, resolved)
 
 	// Save to database.
-	_, err = recommend.SaveRecommendation(ctx, h.db, u.ID, "smart", "", aiResp.TasteSummary, resolved)
+	_, err = recommend.SaveRecommendation(ctx, h.db, u.ID, "smart", "", aiResp.TasteSummary, resolved, dropped)
 	if err != nil {
-		log.Printf("failed to save recommendation for user %d: %v", u.ID, err)
+		slog.ErrorContext(ctx, "failed to save recommendation", "error", err)
 		// Non-fatal: still return the recommendations to the user.
 	}
 
 	c.JSON(http.StatusOK, recommend.Response{
 		TasteSummary:    aiResp.TasteSummary,
 		Recommendations: resolved,
+		Dropped:         dropped,
 		Mode:            "smart",
 	})
 }
@@ -119,42 +107,25 @@ func (h *handlers) PromptRecommend(c *gin.Context) {
 		return
 	}
 
-	// Check if Groq API key is configured.
-	if h.cfg.GroqAPIKey == "" {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "AI recommendations are not configured"})
-		return
-	}
-
-	// Check rate limit (60s).
-	remaining, err := recommend.CheckRateLimit(ctx, h.db, u.ID)
-	if err != nil {
-		log.Printf("rate limit check failed for user %d: %v", u.ID, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check rate limit"})
-		return
-	}
-	if remaining > 0 {
-		c.JSON(http.StatusTooManyRequests, gin.H{
-			"error":       fmt.Sprintf("please wait %d seconds between recommendations", remaining),
-			"retry_after": remaining,
-		})
+	if !h.canRecommend(c, u) {
 		return
 	}
 
 	// Gather taste profile.
-	profile, err := recommend.GatherTasteProfile(ctx, h.db, u.AccessToken, u.ID)
+	profile, err := recommend.GatherTasteProfile(ctx, h.db, h.sp, u.AccessToken, u.ID)
 	if err != nil {
-		log.Printf("gather taste profile failed for user %d: %v", u.ID, err)
+		slog.ErrorContext(ctx, "gather taste profile failed", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to gather taste profile"})
 		return
 	}
 
-	// Build prompts and call Groq with the user's prompt.
+	// Build prompts and call the AI provider with the user's prompt.
 	systemPrompt := ai.BuildSystemPrompt()
 	userMessage := ai.FormatTasteProfile(profile, body.Prompt)
 
-	rawJSON, err := ai.CompleteJSON(ctx, h.cfg.GroqAPIKey, systemPrompt, userMessage)
+	rawJSON, err := ai.CompleteJSON(ctx, h.llm, systemPrompt, userMessage)
 	if err != nil {
-		log.Printf("ai API call failed for user %d: %v", u.ID, err)
+		slog.ErrorContext(ctx, "ai API call failed", "error", err)
 		if isAIRateLimitError(err) {
 			c.JSON(http.StatusTooManyRequests, gin.H{"error": "AI service is temporarily busy. Please try again in a minute."})
 			return
@@ -163,34 +134,92 @@ func (h *handlers) PromptRecommend(c *gin.Context) {
 		return
 	}
 
-	log.Printf("ai raw response for user %d: %s", u.ID, rawJSON)
+	slog.InfoContext(ctx, "ai raw response", "response", rawJSON)
 
 	// Parse AI response.
 	var aiResp ai.AIResponse
 	if err := json.Unmarshal([]byte(rawJSON), &aiResp); err != nil {
-		log.Printf("failed to parse ai response for user %d: %v", u.ID, err)
+		slog.ErrorContext(ctx, "failed to parse ai response", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to parse AI response"})
 		return
 	}
 
 	// Resolve recommendations to Spotify IDs.
-	resolved := recommend.ResolveAll(ctx, u.AccessToken, aiResp.Recommendations)
+	resolved := recommend.ResolveAll(ctx, h.sp, u.AccessToken, aiResp.Recommendations)
+
+	// Drop anything the user already knows and backfill the gaps.
+	resolved, dropped := recommend.EnforceNovelty(ctx, h.db, h.sp, h.llm, u.AccessToken, u.ID, userMessage, resolved)
 
 	// Save to database.
-	_, err = recommend.SaveRecommendation(ctx, h.db, u.ID, "prompt", body.Prompt, aiResp.TasteSummary, resolved)
+	_, err = recommend.SaveRecommendation(ctx, h.db, u.ID, "prompt", body.Prompt, aiResp.TasteSummary, resolved, dropped)
 	if err != nil {
-		log.Printf("failed to save recommendation for user %d: %v", u.ID, err)
+		slog.ErrorContext(ctx, "failed to save recommendation", "error", err)
 		// Non-fatal: still return the recommendations to the user.
 	}
 
 	c.JSON(http.StatusOK, recommend.Response{
 		TasteSummary:    aiResp.TasteSummary,
 		Recommendations: resolved,
+		Dropped:         dropped,
 		Mode:            "prompt",
 		UserPrompt:      body.Prompt,
 	})
 }
 
+// ---------------------------------------------------------------------------
+// SmartRecommendStream handles POST /api/recommendations/smart/stream
+// Server-Sent Events variant of SmartRecommend: emits profile, token, summary
+// and recommendation events as the pipeline progresses, then a final saved
+// (or error) event. POST only, since a run calls the LLM, uses up the rate
+// limit and saves history; clients read the stream with fetch.
+// ---------------------------------------------------------------------------
+
+func (h *handlers) SmartRecommendStream(c *gin.Context) {
+	h.streamRecommendations(c, "smart", "")
+}
+
+// ---------------------------------------------------------------------------
+// PromptRecommendStream handles POST /api/recommendations/prompt/stream
+// Server-Sent Events variant of PromptRecommend, with the prompt in the JSON
+// body.
+// ---------------------------------------------------------------------------
+
+func (h *handlers) PromptRecommendStream(c *gin.Context) {
+	var body promptRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "prompt is required"})
+		return
+	}
+	if strings.TrimSpace(body.Prompt) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "prompt cannot be empty"})
+		return
+	}
+
+	h.streamRecommendations(c, "prompt", body.Prompt)
+}
+
+// streamRecommendations runs recommend.Stream and relays its events as SSE.
+// Pre-flight failures (not configured, rate limited) are plain JSON errors.
+func (h *handlers) streamRecommendations(c *gin.Context, mode, prompt string) {
+	u := c.MustGet("user").(*user.User)
+
+	if !h.canRecommend(c, u) {
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // disable proxy buffering (nginx)
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	for ev := range recommend.Stream(c.Request.Context(), h.db, h.sp, h.llm, u.AccessToken, u.ID, mode, prompt) {
+		c.SSEvent(ev.Name, ev.Data)
+		c.Writer.Flush()
+	}
+}
+
 // ---------------------------------------------------------------------------
 // RecommendationHistory handles GET /api/recommendations/history
 // Returns the user's recommendation history.
@@ -202,7 +231,7 @@ func (h *handlers) RecommendationHistory(c *gin.Context) {
 
 	items, err := recommend.GetHistory(ctx, h.db, u.ID)
 	if err != nil {
-		log.Printf("failed to get recommendation history for user %d: %v", u.ID, err)
+		slog.ErrorContext(ctx, "failed to get recommendation history", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load recommendation history"})
 		return
 	}
@@ -227,7 +256,7 @@ func (h *handlers) RecommendationDetail(c *gin.Context) {
 
 	item, err := recommend.GetHistoryItem(ctx, h.db, u.ID, id)
 	if err != nil {
-		log.Printf("failed to get recommendation %d for user %d: %v", id, u.ID, err)
+		slog.ErrorContext(ctx, "failed to get recommendation", "recommendation_id", id, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load recommendation"})
 		return
 	}
@@ -239,10 +268,113 @@ func (h *handlers) RecommendationDetail(c *gin.Context) {
 	c.JSON(http.StatusOK, item)
 }
 
+// ---------------------------------------------------------------------------
+// SetRecommendationFeedback handles PUT /api/recommendations/history/:id/items/:index/feedback
+// Records a verdict (up, down, known, not_for_me) on one recommendation.
+// ---------------------------------------------------------------------------
+
+func (h *handlers) SetRecommendationFeedback(c *gin.Context) {
+	u := c.MustGet("user").(*user.User)
+	ctx := c.Request.Context()
+
+	id, index, ok := recommendationItemParams(c)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Verdict string `json:"verdict" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "verdict is required"})
+		return
+	}
+	if !recommend.ValidVerdict(body.Verdict) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "verdict must be up, down, known, or not_for_me"})
+		return
+	}
+
+	err := recommend.SetFeedback(ctx, h.db, u.ID, id, index, body.Verdict)
+	if errors.Is(err, recommend.ErrItemNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "recommendation not found"})
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to save recommendation feedback", "recommendation_id", id, "index", index, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save feedback"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"verdict": body.Verdict})
+}
+
+// ---------------------------------------------------------------------------
+// DeleteRecommendationFeedback handles DELETE /api/recommendations/history/:id/items/:index/feedback
+// Clears the verdict on one recommendation.
+// ---------------------------------------------------------------------------
+
+func (h *handlers) DeleteRecommendationFeedback(c *gin.Context) {
+	u := c.MustGet("user").(*user.User)
+	ctx := c.Request.Context()
+
+	id, index, ok := recommendationItemParams(c)
+	if !ok {
+		return
+	}
+
+	if err := recommend.DeleteFeedback(ctx, h.db, u.ID, id, index); err != nil {
+		slog.ErrorContext(ctx, "failed to delete recommendation feedback", "recommendation_id", id, "index", index, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete feedback"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// recommendationItemParams parses the :id and :index path parameters,
+// writing a 400 and returning false if either is invalid.
+func recommendationItemParams(c *gin.Context) (int64, int, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid recommendation id"})
+		return 0, 0, false
+	}
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil || index < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid item index"})
+		return 0, 0, false
+	}
+	return id, index, true
+}
+
+// canRecommend checks that an AI provider is configured and the user isn't
+// rate limited (60s), writing the error response and returning false if not.
+func (h *handlers) canRecommend(c *gin.Context, u *user.User) bool {
+	if h.llm == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "AI recommendations are not configured"})
+		return false
+	}
+
+	remaining, err := recommend.CheckRateLimit(c.Request.Context(), h.db, u.ID)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "rate limit check failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check rate limit"})
+		return false
+	}
+	if remaining > 0 {
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":       fmt.Sprintf("please wait %d seconds between recommendations", remaining),
+			"retry_after": remaining,
+		})
+		return false
+	}
+
+	return true
+}
+
 // isAIRateLimitError checks whether an error from the AI client indicates a
 // rate limit (HTTP 429) so the handler can return an appropriate status to
 // the frontend.
 func isAIRateLimitError(err error) bool {
-	msg := err.Error()
-	return strings.Contains(msg, "status 429") || strings.Contains(msg, "rate_limit")
+	return ai.IsRateLimited(err) || strings.Contains(err.Error(), "rate_limit")
 }
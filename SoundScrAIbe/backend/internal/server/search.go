@@ -1,10 +1,9 @@
 package server
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
 
-	"soundscraibe/internal/spotify"
 	"soundscraibe/internal/user"
 
 	"github.com/gin-gonic/gin"
@@ -26,9 +25,9 @@ func (h *handlers) Search(c *gin.Context) {
 
 	types := c.DefaultQuery("types", "track,album,artist")
 
-	result, err := spotify.Search(c.Request.Context(), currentUser.AccessToken, query, types, 10)
+	result, err := h.sp.Search(c.Request.Context(), currentUser.AccessToken, query, types, 10)
 	if err != nil {
-		log.Printf("search failed for user %d: %v", currentUser.ID, err)
+		slog.ErrorContext(c.Request.Context(), "search failed", "error", err)
 		c.JSON(http.StatusBadGateway, gin.H{"error": "search failed"})
 		return
 	}
@@ -3,8 +3,13 @@ package server
 import (
 	"database/sql"
 
+	"soundscraibe/internal/ai"
+	"soundscraibe/internal/catalog"
 	"soundscraibe/internal/config"
+	"soundscraibe/internal/history"
+	"soundscraibe/internal/metrics"
 	"soundscraibe/internal/spotify"
+	"soundscraibe/internal/tokencrypt"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,19 +17,41 @@ import (
 type handlers struct {
 	db      *sql.DB
 	cfg     *config.Config
-	spotify *spotify.Config
+	spotify *spotify.Config // OAuth
+	sp      spotify.API     // Web API calls; catalog lookups are cached
+	keys    *tokencrypt.Keyring
+	syncer  *history.Worker
+	llm     ai.Provider // nil when AI recommendations aren't configured
+	bg      *Background
 }
 
-func New(db *sql.DB, cfg *config.Config) *gin.Engine {
-	r := gin.Default()
+// New builds the HTTP handler. Work started by requests that outlives them
+// (imports, the sync after login) runs on bg, which the caller drains on
+// shutdown.
+func New(db *sql.DB, cfg *config.Config, syncer *history.Worker, llm ai.Provider, sp *spotify.Client, keys *tokencrypt.Keyring, bg *Background) *gin.Engine {
+	r := gin.New()
+	r.Use(requestID(), requestLogger(), observeRequest(), recovery())
+
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	cacheOpts := catalog.Options{TTL: cfg.CatalogCacheTTL, Size: cfg.CatalogCacheSize}
+	if cfg.CatalogCacheDB {
+		cacheOpts.DB = db
+	}
 
 	h := &handlers{
-		db:  db,
-		cfg: cfg,
+		db:     db,
+		cfg:    cfg,
+		syncer: syncer,
+		llm:    llm,
+		sp:     catalog.New(sp, cacheOpts),
+		keys:   keys,
+		bg:     bg,
 		spotify: &spotify.Config{
 			ClientID:     cfg.SpotifyClientID,
 			ClientSecret: cfg.SpotifyClientSecret,
 			RedirectURI:  cfg.SpotifyRedirectURI,
+			Client:       sp,
 		},
 	}
 
@@ -41,27 +68,31 @@ func New(db *sql.DB, cfg *config.Config) *gin.Engine {
 
 		protected := api.Group("")
 		protected.Use(h.AuthRequired())
+		// Routes that change state require a write-scoped access token.
+		write := h.WriteRequired()
 		{
 			protected.GET("/me", h.Me)
+			protected.PUT("/me/timezone", write, h.SetTimezone)
 			protected.GET("/recently-played", h.RecentlyPlayed)
+			protected.GET("/sync/status", h.SyncStatus)
 			protected.GET("/liked-songs/check", h.CheckLikedSongs)
-			protected.PUT("/liked-songs/:trackId", h.SaveLikedSong)
-			protected.DELETE("/liked-songs/:trackId", h.RemoveLikedSong)
+			protected.PUT("/liked-songs/:trackId", write, h.SaveLikedSong)
+			protected.DELETE("/liked-songs/:trackId", write, h.RemoveLikedSong)
 			protected.GET("/artist-charts", h.ArtistCharts)
 			protected.GET("/tracks/:id", h.TrackDetail)
 			protected.GET("/albums/:id", h.AlbumDetail)
 			protected.GET("/artists/:id", h.ArtistDetail)
 
 			protected.GET("/ratings/:entityType/:entityId", h.GetRating)
-			protected.PUT("/ratings/:entityType/:entityId", h.SetRating)
-			protected.DELETE("/ratings/:entityType/:entityId", h.DeleteRating)
+			protected.PUT("/ratings/:entityType/:entityId", write, h.SetRating)
+			protected.DELETE("/ratings/:entityType/:entityId", write, h.DeleteRating)
 
 			protected.GET("/shelves/:entityType/:entityId", h.GetShelf)
-			protected.PUT("/shelves/:entityType/:entityId", h.SetShelf)
-			protected.DELETE("/shelves/:entityType/:entityId", h.DeleteShelf)
+			protected.PUT("/shelves/:entityType/:entityId", write, h.SetShelf)
+			protected.DELETE("/shelves/:entityType/:entityId", write, h.DeleteShelf)
 
 			protected.GET("/tags/:entityType/:entityId", h.GetTags)
-			protected.PUT("/tags/:entityType/:entityId", h.SetTags)
+			protected.PUT("/tags/:entityType/:entityId", write, h.SetTags)
 			protected.GET("/tags", h.GetUserTags)
 
 			protected.GET("/search", h.Search)
@@ -73,16 +104,55 @@ func New(db *sql.DB, cfg *config.Config) *gin.Engine {
 			protected.GET("/stats/spotify-top", h.SpotifyTop)
 			protected.GET("/stats/my-top", h.MyTop)
 			protected.GET("/stats/clock", h.StatsClock)
+			protected.GET("/stats/calendar", h.StatsCalendar)
+			protected.GET("/stats/weekday-hours", h.StatsWeekdayHours)
+			protected.GET("/stats/sessions", h.StatsSessions)
+			protected.GET("/listening-sessions", h.ListeningSessions)
+
+			// Credential management needs a browser session, not an access token.
+			credentials := protected.Group("", h.SessionRequired())
+			{
+				credentials.GET("/sessions", h.ListSessions)
+				credentials.POST("/sessions/revoke-others", h.RevokeOtherSessions)
+				credentials.DELETE("/sessions/:id", h.RevokeSession)
+				credentials.GET("/tokens", h.ListAccessTokens)
+				credentials.POST("/tokens", h.CreateAccessToken)
+				credentials.DELETE("/tokens/:id", h.DeleteAccessToken)
+				credentials.GET("/listen-token", h.GetListenToken)
+				credentials.POST("/listen-token", h.CreateListenToken)
+				credentials.DELETE("/listen-token", h.DeleteListenToken)
+			}
+
+			imports := protected.Group("/import")
+			{
+				imports.POST("/spotify-history", write, h.ImportSpotifyHistory)
+				imports.POST("/scrobbles", write, h.ImportScrobbles)
+				imports.GET("", h.ImportHistory)
+				imports.GET("/unresolved", h.UnresolvedPlays)
+				imports.GET("/:id", h.ImportStatus)
+			}
 
 			recommendations := protected.Group("/recommendations")
 			{
-				recommendations.POST("/smart", h.SmartRecommend)
-				recommendations.POST("/prompt", h.PromptRecommend)
+				recommendations.POST("/smart", write, h.SmartRecommend)
+				recommendations.POST("/prompt", write, h.PromptRecommend)
+				recommendations.POST("/smart/stream", write, h.SmartRecommendStream)
+				recommendations.POST("/prompt/stream", write, h.PromptRecommendStream)
 				recommendations.GET("/history", h.RecommendationHistory)
 				recommendations.GET("/history/:id", h.RecommendationDetail)
+				recommendations.PUT("/history/:id/items/:index/feedback", write, h.SetRecommendationFeedback)
+				recommendations.DELETE("/history/:id/items/:index/feedback", write, h.DeleteRecommendationFeedback)
 			}
 		}
 	}
 
+	// ListenBrainz-compatible API for external scrobblers (mpd, Navidrome,
+	// Pano Scrobbler): point the client's ListenBrainz URL at this server.
+	lb := r.Group("/1")
+	{
+		lb.GET("/validate-token", h.ValidateToken)
+		lb.POST("/submit-listens", h.ListenTokenRequired(), h.SubmitListens)
+	}
+
 	return r
 }
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"soundscraibe/internal/session"
+	"soundscraibe/internal/user"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sessionResponse is a session as listed to its owner.
+type sessionResponse struct {
+	session.Session
+	Current bool `json:"current"`
+}
+
+// ---------------------------------------------------------------------------
+// ListSessions handles GET /api/sessions
+// Lists the user's active sessions (devices), most recently used first, and
+// marks the one making the request.
+// ---------------------------------------------------------------------------
+func (h *handlers) ListSessions(c *gin.Context) {
+	u := c.MustGet("user").(*user.User)
+	current := c.MustGet("session").(*session.Session)
+
+	sessions, err := session.ListByUser(c.Request.Context(), h.db, u.ID)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to list sessions", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list sessions"})
+		return
+	}
+
+	resp := make([]sessionResponse, len(sessions))
+	for i, s := range sessions {
+		resp[i] = sessionResponse{Session: s, Current: s.ID == current.ID}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ---------------------------------------------------------------------------
+// RevokeSession handles DELETE /api/sessions/:id
+// Logs out one of the user's sessions. Revoking the current session also
+// clears its cookie.
+// ---------------------------------------------------------------------------
+func (h *handlers) RevokeSession(c *gin.Context) {
+	u := c.MustGet("user").(*user.User)
+	current := c.MustGet("session").(*session.Session)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session id"})
+		return
+	}
+
+	found, err := session.DeleteByID(c.Request.Context(), h.db, u.ID, id)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to revoke session", "session_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke session"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return
+	}
+
+	if id == current.ID {
+		h.clearSessionCookie(c)
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ---------------------------------------------------------------------------
+// RevokeOtherSessions handles POST /api/sessions/revoke-others
+// Logs out everywhere except the current session.
+// ---------------------------------------------------------------------------
+func (h *handlers) RevokeOtherSessions(c *gin.Context) {
+	u := c.MustGet("user").(*user.User)
+	current := c.MustGet("session").(*session.Session)
+
+	n, err := session.DeleteOthers(c.Request.Context(), h.db, u.ID, current.Token)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to revoke other sessions", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revoked": n})
+}
+
+// setSessionCookie issues the session cookie, valid for maxAge seconds.
+func (h *handlers) setSessionCookie(c *gin.Context, token string, maxAge int) {
+	secure := h.cfg.Environment != "development"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie("session", token, maxAge, "/", "", secure, true)
+}
+
+// clearSessionCookie removes the session cookie from the browser, with the
+// same attributes it was set with so the browser replaces it.
+func (h *handlers) clearSessionCookie(c *gin.Context) {
+	h.setSessionCookie(c, "", -1)
+}
+
+// sessionClient describes the client making the request, for session metadata.
+func sessionClient(c *gin.Context) session.Client {
+	return session.Client{UserAgent: c.Request.UserAgent(), IP: c.ClientIP()}
+}
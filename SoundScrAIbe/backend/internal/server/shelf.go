@@ -2,7 +2,7 @@ package server
 
 import (
 	"database/sql"
-	"log"
+	"log/slog"
 	"net/http"
 
 	"soundscraibe/internal/user"
@@ -31,9 +31,7 @@ func (h *handlers) SetShelf(c *gin.Context) {
 	}
 
 	var body struct {
-		Status   string `json:"status" binding:"required"`
-		Name     string `json:"name"`
-		ImageURL string `json:"image_url"`
+		Status string `json:"status" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "status is required"})
@@ -45,9 +43,11 @@ func (h *handlers) SetShelf(c *gin.Context) {
 		return
 	}
 
-	ctx := c.Request.Context()
+	if !h.ensureMetadata(c, currentUser, entityType, entityID) {
+		return
+	}
 
-	_, err := h.db.ExecContext(ctx,
+	_, err := h.db.ExecContext(c.Request.Context(),
 		`INSERT INTO shelves (user_id, entity_type, entity_id, status)
 		 VALUES ($1, $2, $3, $4)
 		 ON CONFLICT ON CONSTRAINT uq_shelf
@@ -55,24 +55,11 @@ func (h *handlers) SetShelf(c *gin.Context) {
 		currentUser.ID, entityType, entityID, body.Status,
 	)
 	if err != nil {
-		log.Printf("failed to upsert shelf for user %d, %s/%s: %v", currentUser.ID, entityType, entityID, err)
+		slog.ErrorContext(c.Request.Context(), "failed to upsert shelf", "entity_type", entityType, "entity_id", entityID, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save shelf"})
 		return
 	}
 
-	if body.Name != "" {
-		_, err = h.db.ExecContext(ctx,
-			`INSERT INTO entity_metadata (entity_type, entity_id, name, image_url)
-			 VALUES ($1, $2, $3, $4)
-			 ON CONFLICT ON CONSTRAINT uq_entity_meta
-			 DO UPDATE SET name = $3, image_url = $4, updated_at = now()`,
-			entityType, entityID, body.Name, body.ImageURL,
-		)
-		if err != nil {
-			log.Printf("failed to upsert entity_metadata for %s/%s: %v", entityType, entityID, err)
-		}
-	}
-
 	c.JSON(http.StatusOK, gin.H{"status": body.Status})
 }
 
@@ -98,7 +85,7 @@ func (h *handlers) GetShelf(c *gin.Context) {
 		currentUser.ID, entityType, entityID,
 	).Scan(&status)
 	if err != nil && err != sql.ErrNoRows {
-		log.Printf("failed to query shelf for user %d, %s/%s: %v", currentUser.ID, entityType, entityID, err)
+		slog.ErrorContext(c.Request.Context(), "failed to query shelf", "entity_type", entityType, "entity_id", entityID, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get shelf"})
 		return
 	}
@@ -127,7 +114,7 @@ func (h *handlers) DeleteShelf(c *gin.Context) {
 		currentUser.ID, entityType, entityID,
 	)
 	if err != nil {
-		log.Printf("failed to delete shelf for user %d, %s/%s: %v", currentUser.ID, entityType, entityID, err)
+		slog.ErrorContext(c.Request.Context(), "failed to delete shelf", "entity_type", entityType, "entity_id", entityID, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete shelf"})
 		return
 	}
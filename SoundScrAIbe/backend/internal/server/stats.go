@@ -4,7 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 	"math"
 	"net/http"
 	"strconv"
@@ -28,8 +28,12 @@ type statValue struct {
 }
 
 type statsOverviewResponse struct {
-	Period string              `json:"period"`
-	Stats  map[string]statValue `json:"stats"`
+	Period        string               `json:"period"`
+	Timezone      string               `json:"timezone"`
+	Range         statsWindowJSON      `json:"range"`
+	Compare       *statsWindowJSON     `json:"compare"` // nil when there's nothing to compare with
+	MinCompletion float64              `json:"min_completion,omitempty"`
+	Stats         map[string]statValue `json:"stats"`
 }
 
 type topItem struct {
@@ -41,12 +45,19 @@ type topItem struct {
 	PlayCount   int     `json:"play_count"`
 	TotalMs     int64   `json:"total_ms"`
 	SpotifyRank *int    `json:"spotify_rank,omitempty"`
+	// My-top tracks only, over all plays in the range; nil when no play's
+	// completion is known.
+	AvgCompletion *float64 `json:"avg_completion,omitempty"`
+	SkipRate      *float64 `json:"skip_rate,omitempty"`
 }
 
 type statsTopResponse struct {
-	Type      string    `json:"type"`
-	TimeRange string    `json:"time_range"`
-	Items     []topItem `json:"items"`
+	Type          string           `json:"type"`
+	TimeRange     string           `json:"time_range,omitempty"`
+	Period        string           `json:"period,omitempty"`
+	Range         *statsWindowJSON `json:"range,omitempty"` // my-top only
+	MinCompletion float64          `json:"min_completion,omitempty"`
+	Items         []topItem        `json:"items"`
 }
 
 type clockHour struct {
@@ -56,7 +67,47 @@ type clockHour struct {
 }
 
 type statsClockResponse struct {
-	Hours []clockHour `json:"hours"`
+	Timezone      string      `json:"timezone"`
+	MinCompletion float64     `json:"min_completion,omitempty"`
+	Hours         []clockHour `json:"hours"`
+}
+
+// statsLocation returns the timezone stats are computed in: the tz query
+// parameter if present, else the user's stored preference, else UTC. An
+// invalid tz is answered with a 400 and ok is false.
+func statsLocation(c *gin.Context, u *user.User) (loc *time.Location, ok bool) {
+	if tz := c.Query("tz"); tz != "" {
+		loc, err := parseTimezone(tz)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		return loc, true
+	}
+	if loc, err := parseTimezone(u.Timezone); err == nil {
+		return loc, true
+	}
+	return time.UTC, true
+}
+
+// minCompletion reads the optional "real listens" filter: real=true counts
+// only plays with at least the configured share of the track played, and
+// min_completion (0-1) sets the share explicitly. Plays whose completion is
+// unknown always count. 0 means no filter. Invalid values are answered with a
+// 400 and ok is false.
+func (h *handlers) minCompletion(c *gin.Context) (threshold float64, ok bool) {
+	if v := c.Query("min_completion"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 || f > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "min_completion must be between 0 and 1"})
+			return 0, false
+		}
+		return f, true
+	}
+	if realOnly, _ := strconv.ParseBool(c.Query("real")); realOnly {
+		return h.cfg.ListenThreshold, true
+	}
+	return 0, true
 }
 
 // ---------------------------------------------------------------------------
@@ -71,78 +122,75 @@ func (h *handlers) StatsOverview(c *gin.Context) {
 	}
 	currentUser := u.(*user.User)
 
-	period := c.DefaultQuery("period", "week")
-	switch period {
-	case "day", "week", "month", "year", "lifetime":
-		// valid
-	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "period must be day, week, month, year, or lifetime"})
+	loc, ok := statsLocation(c, currentUser)
+	if !ok {
 		return
 	}
 
-	ctx := c.Request.Context()
-	syncListeningHistory(ctx, h.db, currentUser)
-
-	now := time.Now().UTC()
-	var currentStart, currentEnd, prevStart, prevEnd time.Time
-
-	switch period {
-	case "day":
-		currentStart = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
-		currentEnd = now
-		prevStart = currentStart.AddDate(0, 0, -1)
-		// Previous window ends at yesterday's same time-of-day.
-		prevEnd = prevStart.Add(now.Sub(currentStart))
-	case "week":
-		currentStart = now.AddDate(0, 0, -7)
-		currentEnd = now
-		prevStart = now.AddDate(0, 0, -14)
-		prevEnd = currentStart
-	case "month":
-		currentStart = now.AddDate(0, 0, -30)
-		currentEnd = now
-		prevStart = now.AddDate(0, 0, -60)
-		prevEnd = currentStart
-	case "year":
-		currentStart = now.AddDate(0, 0, -365)
-		currentEnd = now
-		prevStart = now.AddDate(0, 0, -730)
-		prevEnd = currentStart
-	case "lifetime":
-		currentStart = time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
-		currentEnd = now
-		prevStart = time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
-		prevEnd = prevStart // zero-width window
+	// Windows are computed on the user's wall clock, so "day" starts at their
+	// midnight and AddDate keeps calendar days across DST changes.
+	now := time.Now().In(loc)
+
+	current, period, ok := requestWindow(c, loc, now, "week")
+	if !ok {
+		return
+	}
+
+	// A custom range is compared with the range of the same length right
+	// before it, a named period with its own comparison window.
+	previous := statsWindow{current.From.Add(-current.To.Sub(current.From)), current.From}
+	if period != "custom" {
+		_, previous, _ = periodWindows(period, now)
+	}
+
+	// compare_from/compare_to replace the default comparison, e.g. the same
+	// month a year earlier.
+	compare, found, err := customWindow(c, "compare_from", "compare_to", loc, now)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if found {
+		previous = compare
 	}
 
+	minCompletion, ok := h.minCompletion(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
 	query := `
-WITH plays AS (
-    SELECT DISTINCT ON (track_id, played_at)
-        track_id, artist_id, album_id, duration_ms
-    FROM listening_history
-    WHERE user_id = $1 AND played_at >= $2 AND played_at < $3
+WITH current_plays AS (
+    SELECT id, track_id, album_id, COALESCE(ms_played, duration_ms) AS played_ms
+    FROM plays
+    WHERE user_id = $1 AND played_at >= $2 AND played_at < $3 AND COALESCE(completion, 1) >= $6
 ),
 current_stats AS (
     SELECT
         COUNT(*) AS streams,
-        COALESCE(SUM(duration_ms), 0) AS total_ms,
-        COUNT(DISTINCT track_id) AS distinct_tracks,
-        COUNT(DISTINCT artist_id) AS distinct_artists,
+        COALESCE(SUM(played_ms), 0) AS total_ms,
+        COUNT(DISTINCT track_id) FILTER (WHERE track_id != '') AS distinct_tracks,
+        (SELECT COUNT(DISTINCT pa.artist_id) FROM play_artists pa
+         JOIN current_plays cp ON cp.id = pa.play_id
+         WHERE pa.artist_id != '') AS distinct_artists,
         COUNT(DISTINCT album_id) FILTER (WHERE album_id != '') AS distinct_albums
-    FROM plays
+    FROM current_plays
 ),
 prev_plays AS (
-    SELECT DISTINCT ON (track_id, played_at)
-        track_id, artist_id, album_id, duration_ms
-    FROM listening_history
-    WHERE user_id = $1 AND played_at >= $4 AND played_at < $5
+    SELECT id, track_id, album_id, COALESCE(ms_played, duration_ms) AS played_ms
+    FROM plays
+    WHERE user_id = $1 AND played_at >= $4 AND played_at < $5 AND COALESCE(completion, 1) >= $6
 ),
 prev_stats AS (
     SELECT
         COUNT(*) AS streams,
-        COALESCE(SUM(duration_ms), 0) AS total_ms,
-        COUNT(DISTINCT track_id) AS distinct_tracks,
-        COUNT(DISTINCT artist_id) AS distinct_artists,
+        COALESCE(SUM(played_ms), 0) AS total_ms,
+        COUNT(DISTINCT track_id) FILTER (WHERE track_id != '') AS distinct_tracks,
+        (SELECT COUNT(DISTINCT pa.artist_id) FROM play_artists pa
+         JOIN prev_plays pp ON pp.id = pa.play_id
+         WHERE pa.artist_id != '') AS distinct_artists,
         COUNT(DISTINCT album_id) FILTER (WHERE album_id != '') AS distinct_albums
     FROM prev_plays
 )
@@ -154,20 +202,20 @@ FROM current_stats c, prev_stats p`
 	var cStreams, cTotalMs, cTracks, cArtists, cAlbums int64
 	var pStreams, pTotalMs, pTracks, pArtists, pAlbums int64
 
-	err := h.db.QueryRowContext(ctx, query,
-		currentUser.ID, currentStart, currentEnd, prevStart, prevEnd,
+	err = h.db.QueryRowContext(ctx, query,
+		currentUser.ID, current.From, current.To, previous.From, previous.To, minCompletion,
 	).Scan(
 		&cStreams, &cTotalMs, &cTracks, &cArtists, &cAlbums,
 		&pStreams, &pTotalMs, &pTracks, &pArtists, &pAlbums,
 	)
 	if err != nil {
-		log.Printf("stats overview query failed for user %d: %v", currentUser.ID, err)
+		slog.ErrorContext(ctx, "stats overview query failed", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load stats"})
 		return
 	}
 
 	changePct := func(current, previous int64) *float64 {
-		if period == "lifetime" || previous == 0 {
+		if previous == 0 {
 			return nil
 		}
 		v := (float64(current-previous) / float64(previous)) * 100
@@ -185,7 +233,10 @@ FROM current_stats c, prev_stats p`
 	_ = pMinutes // used only via changePct on raw ms
 
 	resp := statsOverviewResponse{
-		Period: period,
+		Period:        period,
+		Timezone:      loc.String(),
+		Range:         current.toJSON(loc),
+		MinCompletion: minCompletion,
 		Stats: map[string]statValue{
 			"streams":           {Value: cStreams, ChangePct: changePct(cStreams, pStreams)},
 			"minutes":           {Value: int64(cMinutes), ChangePct: minutesChange},
@@ -195,6 +246,10 @@ FROM current_stats c, prev_stats p`
 			"different_albums":  {Value: cAlbums, ChangePct: changePct(cAlbums, pAlbums)},
 		},
 	}
+	if !previous.empty() {
+		cmp := previous.toJSON(loc)
+		resp.Compare = &cmp
+	}
 
 	c.JSON(http.StatusOK, resp)
 }
@@ -237,15 +292,14 @@ func (h *handlers) SpotifyTop(c *gin.Context) {
 	}
 
 	ctx := c.Request.Context()
-	syncListeningHistory(ctx, h.db, currentUser)
 
 	var items []topItem
 
 	switch typ {
 	case "tracks":
-		topTracks, err := spotify.GetTopTracks(ctx, currentUser.AccessToken, timeRange, limit)
+		topTracks, err := h.sp.GetTopTracks(ctx, currentUser.AccessToken, timeRange, limit)
 		if err != nil {
-			log.Printf("spotify top tracks failed for user %d: %v", currentUser.ID, err)
+			slog.ErrorContext(ctx, "spotify top tracks failed", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load spotify top tracks"})
 			return
 		}
@@ -264,9 +318,9 @@ func (h *handlers) SpotifyTop(c *gin.Context) {
 		}
 
 	case "artists":
-		topArtists, err := spotify.GetTopArtists(ctx, currentUser.AccessToken, timeRange, limit)
+		topArtists, err := h.sp.GetTopArtists(ctx, currentUser.AccessToken, timeRange, limit)
 		if err != nil {
-			log.Printf("spotify top artists failed for user %d: %v", currentUser.ID, err)
+			slog.ErrorContext(ctx, "spotify top artists failed", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load spotify top artists"})
 			return
 		}
@@ -313,15 +367,6 @@ func (h *handlers) MyTop(c *gin.Context) {
 		return
 	}
 
-	timeRange := c.DefaultQuery("time_range", "medium_term")
-	switch timeRange {
-	case "short_term", "medium_term", "long_term":
-		// valid
-	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "time_range must be short_term, medium_term, or long_term"})
-		return
-	}
-
 	limit := 50
 	if l := c.Query("limit"); l != "" {
 		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
@@ -329,40 +374,81 @@ func (h *handlers) MyTop(c *gin.Context) {
 		}
 	}
 
-	ctx := c.Request.Context()
-	syncListeningHistory(ctx, h.db, currentUser)
+	loc, ok := statsLocation(c, currentUser)
+	if !ok {
+		return
+	}
+	now := time.Now().In(loc)
+
+	// The range is, in order of precedence: from/to, a named period as in
+	// StatsOverview, or a Spotify-style time_range cutoff.
+	period := c.Query("period")
+	var timeRange string
+	window, found, err := customWindow(c, "from", "to", loc, now)
+	switch {
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case found:
+		period = "custom"
+	case period != "":
+		var valid bool
+		if window, _, valid = periodWindows(period, now); !valid {
+			c.JSON(http.StatusBadRequest, gin.H{"error": statsPeriodError})
+			return
+		}
+	default:
+		timeRange = c.DefaultQuery("time_range", "medium_term")
+		switch timeRange {
+		case "short_term", "medium_term", "long_term":
+			// valid
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "time_range must be short_term, medium_term, or long_term"})
+			return
+		}
+		window = statsWindow{timeRangeToCutoff(timeRange, now), now}
+	}
 
-	dateCutoff := timeRangeToCutoff(timeRange)
+	minCompletion, ok := h.minCompletion(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
 
 	var items []topItem
-	var err error
 
 	switch typ {
 	case "tracks":
-		items, err = h.myTopTracks(ctx, currentUser, timeRange, dateCutoff, limit)
+		items, err = h.myTopTracks(ctx, currentUser, window, minCompletion, limit)
 	case "artists":
-		items, err = h.myTopArtists(ctx, currentUser, timeRange, dateCutoff, limit)
+		items, err = h.myTopArtists(ctx, currentUser, window, minCompletion, limit)
 	case "albums":
-		items, err = h.statsTopAlbums(c, currentUser, dateCutoff, limit)
+		items, err = h.statsTopAlbums(c, currentUser, window, minCompletion, limit)
 	case "genres":
-		items, err = h.myTopGenres(ctx, currentUser, timeRange, dateCutoff, limit)
+		items, err = h.myTopGenres(ctx, currentUser, timeRange, window, minCompletion, limit)
 	}
 
 	if err != nil {
-		log.Printf("my top %s failed for user %d: %v", typ, currentUser.ID, err)
+		slog.ErrorContext(ctx, "my top query failed", "type", typ, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to load top %s", typ)})
 		return
 	}
 
+	rangeJSON := window.toJSON(loc)
 	c.JSON(http.StatusOK, statsTopResponse{
-		Type:      typ,
-		TimeRange: timeRange,
-		Items:     items,
+		Type:          typ,
+		TimeRange:     timeRange,
+		Period:        period,
+		Range:         &rangeJSON,
+		MinCompletion: minCompletion,
+		Items:         items,
 	})
 }
 
-func timeRangeToCutoff(timeRange string) time.Time {
-	now := time.Now().UTC()
+// timeRangeToCutoff returns the start of a Spotify-style time_range ending at
+// now, so the window's bounds come from the same clock reading.
+func timeRangeToCutoff(timeRange string, now time.Time) time.Time {
 	switch timeRange {
 	case "short_term":
 		return now.AddDate(0, 0, -28)
@@ -376,17 +462,19 @@ func timeRangeToCutoff(timeRange string) time.Time {
 // --- tracks ---
 
 type dbTrackRow struct {
-	TrackID    string
-	TrackName  string
-	ArtistName string
-	AlbumID    string
-	AlbumName  string
-	PlayCount  int
-	TotalMs    int64
+	TrackID       string
+	TrackName     string
+	ArtistName    string
+	AlbumID       string
+	AlbumName     string
+	PlayCount     int
+	TotalMs       int64
+	AvgCompletion *float64
+	SkipRate      *float64
 }
 
-func (h *handlers) myTopTracks(ctx context.Context, currentUser *user.User, timeRange string, dateCutoff time.Time, limit int) ([]topItem, error) {
-	dbRows, err := queryTopTracks(ctx, h.db, currentUser.ID, dateCutoff, limit)
+func (h *handlers) myTopTracks(ctx context.Context, currentUser *user.User, window statsWindow, minCompletion float64, limit int) ([]topItem, error) {
+	dbRows, err := queryTopTracks(ctx, h.db, currentUser.ID, window, minCompletion, h.cfg.ListenThreshold, limit)
 	if err != nil {
 		return nil, fmt.Errorf("querying top tracks: %w", err)
 	}
@@ -409,6 +497,9 @@ func (h *handlers) myTopTracks(ctx context.Context, currentUser *user.User, time
 			Subtitle:  r.ArtistName,
 			PlayCount: r.PlayCount,
 			TotalMs:   r.TotalMs,
+
+			AvgCompletion: r.AvgCompletion,
+			SkipRate:      r.SkipRate,
 		}
 		if url, ok := imageMap[r.TrackID]; ok {
 			item.ImageURL = url
@@ -423,23 +514,27 @@ func (h *handlers) myTopTracks(ctx context.Context, currentUser *user.User, time
 	return items, nil
 }
 
-func queryTopTracks(ctx context.Context, db *sql.DB, userID int64, cutoff time.Time, limit int) ([]dbTrackRow, error) {
+// queryTopTracks ranks tracks by plays with at least minCompletion of the
+// track played. Average completion and skip rate (the share of plays below
+// skipThreshold) cover every play in the window whose completion is known,
+// so they still show skips when only real listens are counted.
+func queryTopTracks(ctx context.Context, db *sql.DB, userID int64, window statsWindow, minCompletion, skipThreshold float64, limit int) ([]dbTrackRow, error) {
 	rows, err := db.QueryContext(ctx,
-		`WITH plays AS (
-			SELECT DISTINCT ON (track_id, played_at)
-				track_id, track_name, artist_name, album_id, album_name, duration_ms
-			FROM listening_history
-			WHERE user_id = $1 AND played_at >= $2
-		)
-		SELECT track_id, MAX(track_name) AS track_name,
-			   MAX(artist_name) AS artist_name,
-			   MAX(album_id) AS album_id, MAX(album_name) AS album_name,
-			   COUNT(*) AS play_count, SUM(duration_ms) AS total_ms
-		FROM plays
-		GROUP BY track_id
+		`SELECT p.track_id, MAX(p.track_name) AS track_name,
+			   COALESCE(MAX(pa.artist_name), '') AS artist_name,
+			   MAX(p.album_id) AS album_id, MAX(p.album_name) AS album_name,
+			   COUNT(*) FILTER (WHERE COALESCE(p.completion, 1) >= $4) AS play_count,
+			   COALESCE(SUM(COALESCE(p.ms_played, p.duration_ms)) FILTER (WHERE COALESCE(p.completion, 1) >= $4), 0) AS total_ms,
+			   ROUND(AVG(p.completion)::numeric, 3)::float8 AS avg_completion,
+			   ROUND(AVG(CASE WHEN p.completion < $5 THEN 1 ELSE 0 END) FILTER (WHERE p.completion IS NOT NULL), 3)::float8 AS skip_rate
+		FROM plays p
+		LEFT JOIN play_artists pa ON pa.play_id = p.id AND pa.position = 0
+		WHERE p.user_id = $1 AND p.played_at >= $2 AND p.played_at < $3 AND p.track_id != ''
+		GROUP BY p.track_id
+		HAVING COUNT(*) FILTER (WHERE COALESCE(p.completion, 1) >= $4) > 0
 		ORDER BY play_count DESC
-		LIMIT $3`,
-		userID, cutoff, limit,
+		LIMIT $6`,
+		userID, window.From, window.To, minCompletion, skipThreshold, limit,
 	)
 	if err != nil {
 		return nil, err
@@ -449,7 +544,7 @@ func queryTopTracks(ctx context.Context, db *sql.DB, userID int64, cutoff time.T
 	var result []dbTrackRow
 	for rows.Next() {
 		var r dbTrackRow
-		if err := rows.Scan(&r.TrackID, &r.TrackName, &r.ArtistName, &r.AlbumID, &r.AlbumName, &r.PlayCount, &r.TotalMs); err != nil {
+		if err := rows.Scan(&r.TrackID, &r.TrackName, &r.ArtistName, &r.AlbumID, &r.AlbumName, &r.PlayCount, &r.TotalMs, &r.AvgCompletion, &r.SkipRate); err != nil {
 			return nil, err
 		}
 		result = append(result, r)
@@ -466,8 +561,8 @@ type dbArtistRow struct {
 	TotalMs    int64
 }
 
-func (h *handlers) myTopArtists(ctx context.Context, currentUser *user.User, timeRange string, dateCutoff time.Time, limit int) ([]topItem, error) {
-	dbRows, err := queryTopArtists(ctx, h.db, currentUser.ID, dateCutoff, limit)
+func (h *handlers) myTopArtists(ctx context.Context, currentUser *user.User, window statsWindow, minCompletion float64, limit int) ([]topItem, error) {
+	dbRows, err := queryTopArtists(ctx, h.db, currentUser.ID, window, minCompletion, limit)
 	if err != nil {
 		return nil, fmt.Errorf("querying top artists: %w", err)
 	}
@@ -480,25 +575,16 @@ func (h *handlers) myTopArtists(ctx context.Context, currentUser *user.User, tim
 
 	imageMap := h.fetchEntityImages(ctx, "artist", artistIDs)
 
-	// For artists missing images, do individual Spotify lookups (up to 10).
-	fetched := 0
-	for _, r := range dbRows {
-		if _, ok := imageMap[r.ArtistID]; ok {
-			continue
-		}
-		if fetched >= 10 {
-			break
-		}
-		artist, apiErr := spotify.GetArtist(ctx, currentUser.AccessToken, r.ArtistID)
-		if apiErr != nil {
-			log.Printf("failed to fetch artist %s (non-fatal): %v", r.ArtistID, apiErr)
-			continue
-		}
-		if url := artist.ImageURL(); url != "" {
-			imageMap[r.ArtistID] = url
-			fetched++
+	// Artists missing images are looked up on Spotify in one batch.
+	var missing []string
+	for _, id := range artistIDs {
+		if _, ok := imageMap[id]; !ok {
+			missing = append(missing, id)
 		}
 	}
+	for id, url := range h.fetchArtistImages(ctx, currentUser.AccessToken, missing) {
+		imageMap[id] = url
+	}
 
 	items := make([]topItem, 0, len(dbRows))
 	for i, r := range dbRows {
@@ -522,16 +608,18 @@ func (h *handlers) myTopArtists(ctx context.Context, currentUser *user.User, tim
 	return items, nil
 }
 
-func queryTopArtists(ctx context.Context, db *sql.DB, userID int64, cutoff time.Time, limit int) ([]dbArtistRow, error) {
+func queryTopArtists(ctx context.Context, db *sql.DB, userID int64, window statsWindow, minCompletion float64, limit int) ([]dbArtistRow, error) {
 	rows, err := db.QueryContext(ctx,
-		`SELECT artist_id, MAX(artist_name) AS artist_name,
-				COUNT(*) AS play_count, SUM(duration_ms) AS total_ms
-		 FROM listening_history
-		 WHERE user_id = $1 AND played_at >= $2
-		 GROUP BY artist_id
+		`SELECT pa.artist_id, MAX(pa.artist_name) AS artist_name,
+				COUNT(*) AS play_count, SUM(COALESCE(p.ms_played, p.duration_ms)) AS total_ms
+		 FROM plays p
+		 JOIN play_artists pa ON pa.play_id = p.id
+		 WHERE p.user_id = $1 AND p.played_at >= $2 AND p.played_at < $3 AND pa.artist_id != ''
+		   AND COALESCE(p.completion, 1) >= $4
+		 GROUP BY pa.artist_id
 		 ORDER BY play_count DESC
-		 LIMIT $3`,
-		userID, cutoff, limit,
+		 LIMIT $5`,
+		userID, window.From, window.To, minCompletion, limit,
 	)
 	if err != nil {
 		return nil, err
@@ -558,23 +646,19 @@ type dbAlbumRow struct {
 	TotalMs   int64
 }
 
-func (h *handlers) statsTopAlbums(c *gin.Context, currentUser *user.User, dateCutoff time.Time, limit int) ([]topItem, error) {
+func (h *handlers) statsTopAlbums(c *gin.Context, currentUser *user.User, window statsWindow, minCompletion float64, limit int) ([]topItem, error) {
 	ctx := c.Request.Context()
 
 	rows, err := h.db.QueryContext(ctx,
-		`WITH plays AS (
-			SELECT DISTINCT ON (track_id, played_at)
-				album_id, album_name, duration_ms
-			FROM listening_history
-			WHERE user_id = $1 AND played_at >= $2 AND album_id != ''
-		)
-		SELECT album_id, MAX(album_name) AS album_name,
-			   COUNT(*) AS play_count, SUM(duration_ms) AS total_ms
+		`SELECT album_id, MAX(album_name) AS album_name,
+			   COUNT(*) AS play_count, SUM(COALESCE(ms_played, duration_ms)) AS total_ms
 		FROM plays
+		WHERE user_id = $1 AND played_at >= $2 AND played_at < $3 AND album_id != ''
+		  AND COALESCE(completion, 1) >= $4
 		GROUP BY album_id
 		ORDER BY play_count DESC
-		LIMIT $3`,
-		currentUser.ID, dateCutoff, limit,
+		LIMIT $5`,
+		currentUser.ID, window.From, window.To, minCompletion, limit,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("querying top albums: %w", err)
@@ -593,8 +677,24 @@ func (h *handlers) statsTopAlbums(c *gin.Context, currentUser *user.User, dateCu
 		return nil, err
 	}
 
-	// Build items with image lookups.
-	fetched := 0
+	albumIDs := make([]string, len(dbRows))
+	for i, r := range dbRows {
+		albumIDs[i] = r.AlbumID
+	}
+
+	// Images come from entity_metadata, then one batched Spotify lookup for
+	// the rest.
+	imageMap := h.fetchEntityImages(ctx, "album", albumIDs)
+	var missing []string
+	for _, id := range albumIDs {
+		if _, ok := imageMap[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	for id, url := range h.fetchAlbumImages(ctx, currentUser.AccessToken, missing) {
+		imageMap[id] = url
+	}
+
 	items := make([]topItem, 0, len(dbRows))
 	for i, r := range dbRows {
 		item := topItem{
@@ -604,26 +704,9 @@ func (h *handlers) statsTopAlbums(c *gin.Context, currentUser *user.User, dateCu
 			PlayCount: r.PlayCount,
 			TotalMs:   r.TotalMs,
 		}
-
-		// Try entity_metadata first.
-		var imgURL sql.NullString
-		_ = h.db.QueryRowContext(ctx,
-			`SELECT image_url FROM entity_metadata WHERE entity_type = 'album' AND entity_id = $1`,
-			r.AlbumID,
-		).Scan(&imgURL)
-		if imgURL.Valid && imgURL.String != "" {
-			item.ImageURL = imgURL.String
-		} else if fetched < 10 {
-			// Fallback: Spotify API lookup.
-			album, apiErr := spotify.GetAlbum(ctx, currentUser.AccessToken, r.AlbumID)
-			if apiErr != nil {
-				log.Printf("failed to fetch album %s (non-fatal): %v", r.AlbumID, apiErr)
-			} else if len(album.Images) > 0 {
-				item.ImageURL = album.Images[0].URL
-				fetched++
-			}
+		if url, ok := imageMap[r.AlbumID]; ok {
+			item.ImageURL = url
 		}
-
 		items = append(items, item)
 	}
 
@@ -636,7 +719,14 @@ func (h *handlers) statsTopAlbums(c *gin.Context, currentUser *user.User, dateCu
 
 // --- genres ---
 
-func (h *handlers) myTopGenres(ctx context.Context, currentUser *user.User, timeRange string, dateCutoff time.Time, limit int) ([]topItem, error) {
+// myTopGenres ranks genres by the plays of the window's top artists. Genres
+// come from Spotify's top artists for timeRange, or long_term for explicit
+// ranges.
+func (h *handlers) myTopGenres(ctx context.Context, currentUser *user.User, timeRange string, window statsWindow, minCompletion float64, limit int) ([]topItem, error) {
+	if timeRange == "" {
+		timeRange = "long_term"
+	}
+
 	var (
 		wg         sync.WaitGroup
 		dbRows     []dbArtistRow
@@ -650,11 +740,11 @@ func (h *handlers) myTopGenres(ctx context.Context, currentUser *user.User, time
 	wg.Add(2)
 	go func() {
 		defer wg.Done()
-		dbRows, dbErr = queryTopArtists(ctx, h.db, currentUser.ID, dateCutoff, 200)
+		dbRows, dbErr = queryTopArtists(ctx, h.db, currentUser.ID, window, minCompletion, 200)
 	}()
 	go func() {
 		defer wg.Done()
-		topArtists, topErr = spotify.GetTopArtists(ctx, currentUser.AccessToken, timeRange, 50)
+		topArtists, topErr = h.sp.GetTopArtists(ctx, currentUser.AccessToken, timeRange, 50)
 	}()
 	wg.Wait()
 
@@ -662,7 +752,7 @@ func (h *handlers) myTopGenres(ctx context.Context, currentUser *user.User, time
 		return nil, fmt.Errorf("querying artist stats for genres: %w", dbErr)
 	}
 	if topErr != nil {
-		log.Printf("failed to fetch spotify top artists for genres (non-fatal): %v", topErr)
+		slog.WarnContext(ctx, "failed to fetch spotify top artists for genres", "error", topErr)
 	}
 
 	// Build artist -> genres map from Spotify data.
@@ -762,28 +852,30 @@ func (h *handlers) StatsClock(c *gin.Context) {
 		return
 	}
 	currentUser := u.(*user.User)
+	loc, ok := statsLocation(c, currentUser)
+	if !ok {
+		return
+	}
+	minCompletion, ok := h.minCompletion(c)
+	if !ok {
+		return
+	}
 
 	ctx := c.Request.Context()
-	syncListeningHistory(ctx, h.db, currentUser)
 
 	rows, err := h.db.QueryContext(ctx,
-		`WITH plays AS (
-			SELECT DISTINCT ON (track_id, played_at)
-				duration_ms, played_at
-			FROM listening_history
-			WHERE user_id = $1
-		)
-		SELECT
-			EXTRACT(HOUR FROM played_at) AS hour,
+		`SELECT
+			EXTRACT(HOUR FROM played_at AT TIME ZONE $2) AS hour,
 			COUNT(*) AS stream_count,
-			COALESCE(SUM(duration_ms), 0) AS total_ms
+			COALESCE(SUM(COALESCE(ms_played, duration_ms)), 0) AS total_ms
 		FROM plays
-		GROUP BY EXTRACT(HOUR FROM played_at)
+		WHERE user_id = $1 AND COALESCE(completion, 1) >= $3
+		GROUP BY EXTRACT(HOUR FROM played_at AT TIME ZONE $2)
 		ORDER BY hour`,
-		currentUser.ID,
+		currentUser.ID, loc.String(), minCompletion,
 	)
 	if err != nil {
-		log.Printf("stats clock query failed for user %d: %v", currentUser.ID, err)
+		slog.ErrorContext(ctx, "stats clock query failed", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load listening clock"})
 		return
 	}
@@ -795,7 +887,7 @@ func (h *handlers) StatsClock(c *gin.Context) {
 		var streams int
 		var totalMs int64
 		if err := rows.Scan(&hour, &streams, &totalMs); err != nil {
-			log.Printf("stats clock row scan failed: %v", err)
+			slog.ErrorContext(ctx, "stats clock row scan failed", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load listening clock"})
 			return
 		}
@@ -807,7 +899,7 @@ func (h *handlers) StatsClock(c *gin.Context) {
 		}
 	}
 	if err := rows.Err(); err != nil {
-		log.Printf("stats clock rows error: %v", err)
+		slog.ErrorContext(ctx, "stats clock rows error", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load listening clock"})
 		return
 	}
@@ -822,7 +914,7 @@ func (h *handlers) StatsClock(c *gin.Context) {
 		}
 	}
 
-	c.JSON(http.StatusOK, statsClockResponse{Hours: hours})
+	c.JSON(http.StatusOK, statsClockResponse{Timezone: loc.String(), MinCompletion: minCompletion, Hours: hours})
 }
 
 // ---------------------------------------------------------------------------
@@ -841,14 +933,72 @@ func joinArtistNames(artists []spotify.Artist) string {
 // entity type and IDs. Returns a map of entityID -> imageURL.
 func (h *handlers) fetchEntityImages(ctx context.Context, entityType string, ids []string) map[string]string {
 	result := make(map[string]string, len(ids))
-	for _, id := range ids {
-		var imgURL sql.NullString
-		_ = h.db.QueryRowContext(ctx,
-			`SELECT image_url FROM entity_metadata WHERE entity_type = $1 AND entity_id = $2`,
-			entityType, id,
-		).Scan(&imgURL)
-		if imgURL.Valid && imgURL.String != "" {
-			result[id] = imgURL.String
+	if len(ids) == 0 {
+		return result
+	}
+
+	rows, err := h.db.QueryContext(ctx,
+		`SELECT entity_id, image_url FROM entity_metadata
+		 WHERE entity_type = $1 AND entity_id = ANY($2) AND image_url IS NOT NULL AND image_url != ''`,
+		entityType, ids,
+	)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to query entity images", "entity_type", entityType, "error", err)
+		return result
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, imgURL string
+		if err := rows.Scan(&id, &imgURL); err != nil {
+			slog.WarnContext(ctx, "failed to scan entity image", "entity_type", entityType, "error", err)
+			return result
+		}
+		result[id] = imgURL
+	}
+	return result
+}
+
+// fetchArtistImages looks up image URLs for the given artists on Spotify in
+// a single batch (served from the catalog cache where possible). Failures are
+// logged and leave the images out.
+func (h *handlers) fetchArtistImages(ctx context.Context, accessToken string, ids []string) map[string]string {
+	result := make(map[string]string, len(ids))
+	if len(ids) == 0 {
+		return result
+	}
+
+	artists, err := h.sp.GetArtists(ctx, accessToken, ids)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to fetch artist images", "error", err)
+		return result
+	}
+	for i, a := range artists {
+		if a == nil {
+			continue
+		}
+		if url := a.ImageURL(); url != "" {
+			result[ids[i]] = url
+		}
+	}
+	return result
+}
+
+// fetchAlbumImages is fetchArtistImages for albums.
+func (h *handlers) fetchAlbumImages(ctx context.Context, accessToken string, ids []string) map[string]string {
+	result := make(map[string]string, len(ids))
+	if len(ids) == 0 {
+		return result
+	}
+
+	albums, err := h.sp.GetAlbums(ctx, accessToken, ids)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to fetch album images", "error", err)
+		return result
+	}
+	for i, a := range albums {
+		if a != nil && len(a.Images) > 0 {
+			result[ids[i]] = a.Images[0].URL
 		}
 	}
 	return result
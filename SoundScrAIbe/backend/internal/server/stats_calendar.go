@@ -0,0 +1,337 @@
+package server
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"soundscraibe/internal/user"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ---------------------------------------------------------------------------
+// Response types
+// ---------------------------------------------------------------------------
+
+type calendarDay struct {
+	Date    string  `json:"date"` // YYYY-MM-DD in the stats timezone
+	Streams int     `json:"streams"`
+	Minutes float64 `json:"minutes"`
+}
+
+type calendarStreak struct {
+	Days int    `json:"days"`
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+type calendarSummary struct {
+	Streams       int            `json:"streams"`
+	Minutes       float64        `json:"minutes"`
+	ActiveDays    int            `json:"active_days"`
+	LongestStreak calendarStreak `json:"longest_streak"`
+	CurrentStreak calendarStreak `json:"current_streak"` // ends today (or yesterday if nothing's been played yet today)
+	MostActiveDay *calendarDay   `json:"most_active_day"`
+}
+
+type statsCalendarResponse struct {
+	Timezone      string          `json:"timezone"`
+	Range         statsWindowJSON `json:"range"`
+	MinCompletion float64         `json:"min_completion,omitempty"`
+	Days          []calendarDay   `json:"days"`
+	Summary       calendarSummary `json:"summary"`
+}
+
+type weekdayHours struct {
+	Weekday int         `json:"weekday"` // ISO: 1 = Monday … 7 = Sunday
+	Name    string      `json:"name"`
+	Streams int         `json:"streams"`
+	Minutes float64     `json:"minutes"`
+	Hours   []clockHour `json:"hours,omitempty"`
+}
+
+type dayTypeTotals struct {
+	Streams         int     `json:"streams"`
+	Minutes         float64 `json:"minutes"`
+	AvgDailyStreams float64 `json:"avg_daily_streams"` // per day of the week, so work week and weekend compare
+	AvgDailyMinutes float64 `json:"avg_daily_minutes"`
+}
+
+type busiestHour struct {
+	Weekday int     `json:"weekday"`
+	Name    string  `json:"name"`
+	Hour    int     `json:"hour"`
+	Streams int     `json:"streams"`
+	Minutes float64 `json:"minutes"`
+}
+
+type weekdaySummary struct {
+	MostActiveWeekday *weekdayHours `json:"most_active_weekday"` // hours left out
+	MostActiveHour    *busiestHour  `json:"most_active_hour"`
+	Workweek          dayTypeTotals `json:"workweek"` // Monday to Friday
+	Weekend           dayTypeTotals `json:"weekend"`
+}
+
+type statsWeekdayResponse struct {
+	Period        string          `json:"period"`
+	Timezone      string          `json:"timezone"`
+	Range         statsWindowJSON `json:"range"`
+	MinCompletion float64         `json:"min_completion,omitempty"`
+	Days          []weekdayHours  `json:"days"`
+	Summary       weekdaySummary  `json:"summary"`
+}
+
+// roundMinutes converts milliseconds to minutes with one decimal.
+func roundMinutes(ms int64) float64 {
+	return math.Round(float64(ms)/60000.0*10) / 10
+}
+
+// ---------------------------------------------------------------------------
+// StatsCalendar — per-day counts for a heatmap
+// ---------------------------------------------------------------------------
+
+// StatsCalendar handles GET /api/stats/calendar: streams and minutes for every
+// day of a calendar year (?year=2026) or, by default, the year up to today,
+// with streak and busiest-day summaries. Days follow the stats timezone;
+// real/min_completion count only real listens.
+func (h *handlers) StatsCalendar(c *gin.Context) {
+	u, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	currentUser := u.(*user.User)
+	loc, ok := statsLocation(c, currentUser)
+	if !ok {
+		return
+	}
+
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	window := statsWindow{today.AddDate(-1, 0, 1), today.AddDate(0, 0, 1)}
+	if y := c.Query("year"); y != "" {
+		year, err := strconv.Atoi(y)
+		if err != nil || year < 1970 || year > now.Year() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "year must be between 1970 and the current year"})
+			return
+		}
+		start := time.Date(year, 1, 1, 0, 0, 0, 0, loc)
+		window = statsWindow{start, start.AddDate(1, 0, 0)}
+	}
+	minCompletion, ok := h.minCompletion(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	rows, err := h.db.QueryContext(ctx,
+		`SELECT to_char(played_at AT TIME ZONE $4, 'YYYY-MM-DD') AS day,
+			COUNT(*) AS stream_count,
+			COALESCE(SUM(COALESCE(ms_played, duration_ms)), 0) AS total_ms
+		FROM plays
+		WHERE user_id = $1 AND played_at >= $2 AND played_at < $3 AND COALESCE(completion, 1) >= $5
+		GROUP BY day`,
+		currentUser.ID, window.From, window.To, loc.String(), minCompletion,
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "stats calendar query failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load listening calendar"})
+		return
+	}
+	defer rows.Close()
+
+	dayMap := make(map[string]calendarDay)
+	for rows.Next() {
+		var d calendarDay
+		var totalMs int64
+		if err := rows.Scan(&d.Date, &d.Streams, &totalMs); err != nil {
+			slog.ErrorContext(ctx, "stats calendar row scan failed", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load listening calendar"})
+			return
+		}
+		d.Minutes = roundMinutes(totalMs)
+		dayMap[d.Date] = d
+	}
+	if err := rows.Err(); err != nil {
+		slog.ErrorContext(ctx, "stats calendar rows error", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load listening calendar"})
+		return
+	}
+
+	// Fill every day of the window, stepping by calendar day so DST changes
+	// don't skip or repeat one.
+	var days []calendarDay
+	for d := window.From; d.Before(window.To); d = d.AddDate(0, 0, 1) {
+		date := d.Format(time.DateOnly)
+		if cd, ok := dayMap[date]; ok {
+			days = append(days, cd)
+		} else {
+			days = append(days, calendarDay{Date: date})
+		}
+	}
+
+	c.JSON(http.StatusOK, statsCalendarResponse{
+		Timezone:      loc.String(),
+		Range:         window.toJSON(loc),
+		MinCompletion: minCompletion,
+		Days:          days,
+		Summary:       summarizeCalendar(days, today.Format(time.DateOnly)),
+	})
+}
+
+// summarizeCalendar computes totals, streaks and the busiest day of a run of
+// consecutive days. today is used for the current streak.
+func summarizeCalendar(days []calendarDay, today string) calendarSummary {
+	var s calendarSummary
+	var run calendarStreak
+	for i, d := range days {
+		s.Streams += d.Streams
+		s.Minutes += d.Minutes
+
+		if d.Streams == 0 {
+			run = calendarStreak{}
+			continue
+		}
+		s.ActiveDays++
+		if s.MostActiveDay == nil || d.Streams > s.MostActiveDay.Streams {
+			s.MostActiveDay = &days[i]
+		}
+
+		if run.Days == 0 {
+			run.From = d.Date
+		}
+		run.Days++
+		run.To = d.Date
+		if run.Days > s.LongestStreak.Days {
+			s.LongestStreak = run
+		}
+		// A streak still counts as current until today is over.
+		if d.Date == today || (i+1 < len(days) && days[i+1].Date == today && days[i+1].Streams == 0) {
+			s.CurrentStreak = run
+		}
+	}
+	s.Minutes = math.Round(s.Minutes*10) / 10
+	return s
+}
+
+// ---------------------------------------------------------------------------
+// StatsWeekdayHours — 7×24 weekday-by-hour matrix
+// ---------------------------------------------------------------------------
+
+// StatsWeekdayHours handles GET /api/stats/weekday-hours: streams and minutes
+// for every weekday and hour in the stats timezone over a range (from/to or
+// period, lifetime by default), with work week vs weekend totals.
+// real/min_completion count only real listens.
+func (h *handlers) StatsWeekdayHours(c *gin.Context) {
+	u, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	currentUser := u.(*user.User)
+	loc, ok := statsLocation(c, currentUser)
+	if !ok {
+		return
+	}
+	window, period, ok := requestWindow(c, loc, time.Now().In(loc), "lifetime")
+	if !ok {
+		return
+	}
+	minCompletion, ok := h.minCompletion(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	rows, err := h.db.QueryContext(ctx,
+		`SELECT
+			EXTRACT(ISODOW FROM played_at AT TIME ZONE $4)::int AS weekday,
+			EXTRACT(HOUR FROM played_at AT TIME ZONE $4)::int AS hour,
+			COUNT(*) AS stream_count,
+			COALESCE(SUM(COALESCE(ms_played, duration_ms)), 0) AS total_ms
+		FROM plays
+		WHERE user_id = $1 AND played_at >= $2 AND played_at < $3 AND COALESCE(completion, 1) >= $5
+		GROUP BY weekday, hour`,
+		currentUser.ID, window.From, window.To, loc.String(), minCompletion,
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "stats weekday hours query failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load weekday activity"})
+		return
+	}
+	defer rows.Close()
+
+	days := make([]weekdayHours, 7)
+	dayMs := make([]int64, 7)
+	for i := range days {
+		weekday := i + 1
+		days[i] = weekdayHours{Weekday: weekday, Name: time.Weekday(weekday % 7).String(), Hours: make([]clockHour, 24)}
+		for hour := range days[i].Hours {
+			days[i].Hours[hour] = clockHour{Hour: hour}
+		}
+	}
+
+	var summary weekdaySummary
+	var workweekMs, weekendMs int64
+	for rows.Next() {
+		var weekday, hour, streams int
+		var totalMs int64
+		if err := rows.Scan(&weekday, &hour, &streams, &totalMs); err != nil {
+			slog.ErrorContext(ctx, "stats weekday hours row scan failed", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load weekday activity"})
+			return
+		}
+		if weekday < 1 || weekday > 7 || hour < 0 || hour > 23 {
+			continue
+		}
+		d := &days[weekday-1]
+		d.Hours[hour] = clockHour{Hour: hour, Streams: streams, Minutes: roundMinutes(totalMs)}
+		d.Streams += streams
+		dayMs[weekday-1] += totalMs
+
+		if summary.MostActiveHour == nil || streams > summary.MostActiveHour.Streams {
+			summary.MostActiveHour = &busiestHour{Weekday: weekday, Name: d.Name, Hour: hour, Streams: streams, Minutes: roundMinutes(totalMs)}
+		}
+		if weekday <= 5 {
+			summary.Workweek.Streams += streams
+			workweekMs += totalMs
+		} else {
+			summary.Weekend.Streams += streams
+			weekendMs += totalMs
+		}
+	}
+	if err := rows.Err(); err != nil {
+		slog.ErrorContext(ctx, "stats weekday hours rows error", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load weekday activity"})
+		return
+	}
+
+	for i := range days {
+		days[i].Minutes = roundMinutes(dayMs[i])
+		if days[i].Streams > 0 && (summary.MostActiveWeekday == nil || days[i].Streams > summary.MostActiveWeekday.Streams) {
+			busiest := days[i]
+			busiest.Hours = nil
+			summary.MostActiveWeekday = &busiest
+		}
+	}
+	summary.Workweek.Minutes = roundMinutes(workweekMs)
+	summary.Workweek.AvgDailyStreams = math.Round(float64(summary.Workweek.Streams)/5*10) / 10
+	summary.Workweek.AvgDailyMinutes = roundMinutes(workweekMs / 5)
+	summary.Weekend.Minutes = roundMinutes(weekendMs)
+	summary.Weekend.AvgDailyStreams = math.Round(float64(summary.Weekend.Streams)/2*10) / 10
+	summary.Weekend.AvgDailyMinutes = roundMinutes(weekendMs / 2)
+
+	c.JSON(http.StatusOK, statsWeekdayResponse{
+		Period:        period,
+		Timezone:      loc.String(),
+		Range:         window.toJSON(loc),
+		MinCompletion: minCompletion,
+		Days:          days,
+		Summary:       summary,
+	})
+}
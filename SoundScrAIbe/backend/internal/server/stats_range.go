@@ -0,0 +1,154 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statsWindow is a half-open [From, To) span of played_at.
+type statsWindow struct {
+	From time.Time
+	To   time.Time
+}
+
+// empty reports whether the window can't contain any play.
+func (w statsWindow) empty() bool {
+	return !w.To.After(w.From)
+}
+
+// statsWindowJSON is how a window is reported back, in the stats timezone.
+type statsWindowJSON struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+func (w statsWindow) toJSON(loc *time.Location) statsWindowJSON {
+	return statsWindowJSON{
+		From: w.From.In(loc).Format(time.RFC3339),
+		To:   w.To.In(loc).Format(time.RFC3339),
+	}
+}
+
+// epoch is the start of the "lifetime" period.
+var epoch = time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// statsPeriodError lists the accepted period names.
+const statsPeriodError = "period must be day, week, month, year, lifetime, this_week, this_month, or this_year"
+
+// periodWindows returns the window of a named period ending at now (in the
+// stats timezone) and the window it is compared with. Rolling periods (day,
+// week, month, year) are compared with the window right before; calendar
+// periods (this_week from Monday, this_month, this_year to date) with the
+// same stretch of the previous week, month or year. lifetime has no
+// comparison. ok is false for unknown names.
+func periodWindows(period string, now time.Time) (current, previous statsWindow, ok bool) {
+	loc := now.Location()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+
+	// calendar compares [start, now) with the same span one step back, cut
+	// off at start where the previous month is shorter.
+	calendar := func(start time.Time, years, months, days int) (statsWindow, statsWindow, bool) {
+		prevEnd := now.AddDate(years, months, days)
+		if prevEnd.After(start) {
+			prevEnd = start
+		}
+		return statsWindow{start, now}, statsWindow{start.AddDate(years, months, days), prevEnd}, true
+	}
+	rolling := func(days int) (statsWindow, statsWindow, bool) {
+		start := now.AddDate(0, 0, -days)
+		return statsWindow{start, now}, statsWindow{start.AddDate(0, 0, -days), start}, true
+	}
+
+	switch period {
+	case "day":
+		// Today so far, against yesterday up to the same time of day.
+		return calendar(today, 0, 0, -1)
+	case "week":
+		return rolling(7)
+	case "month":
+		return rolling(30)
+	case "year":
+		return rolling(365)
+	case "lifetime":
+		return statsWindow{epoch, now}, statsWindow{epoch, epoch}, true
+	case "this_week":
+		daysSinceMonday := (int(now.Weekday()) + 6) % 7
+		return calendar(today.AddDate(0, 0, -daysSinceMonday), 0, 0, -7)
+	case "this_month":
+		return calendar(time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc), 0, -1, 0)
+	case "this_year":
+		return calendar(time.Date(now.Year(), 1, 1, 0, 0, 0, 0, loc), -1, 0, 0)
+	}
+	return statsWindow{}, statsWindow{}, false
+}
+
+// customWindow reads a window from the fromKey/toKey query parameters. Each is
+// a date (YYYY-MM-DD, in loc; to is inclusive) or an RFC 3339 timestamp (to is
+// exclusive). to defaults to now. found is false if neither is given.
+func customWindow(c *gin.Context, fromKey, toKey string, loc *time.Location, now time.Time) (w statsWindow, found bool, err error) {
+	fromStr, toStr := c.Query(fromKey), c.Query(toKey)
+	if fromStr == "" && toStr == "" {
+		return statsWindow{}, false, nil
+	}
+	if fromStr == "" {
+		return statsWindow{}, true, fmt.Errorf("%s requires %s", toKey, fromKey)
+	}
+
+	w.From, err = parseRangeBound(fromStr, loc, false)
+	if err != nil {
+		return statsWindow{}, true, fmt.Errorf("invalid %s: %w", fromKey, err)
+	}
+	w.To = now
+	if toStr != "" {
+		w.To, err = parseRangeBound(toStr, loc, true)
+		if err != nil {
+			return statsWindow{}, true, fmt.Errorf("invalid %s: %w", toKey, err)
+		}
+	}
+	if w.empty() {
+		return statsWindow{}, true, fmt.Errorf("%s must be before %s", fromKey, toKey)
+	}
+	return w, true, nil
+}
+
+// parseRangeBound parses a date or RFC 3339 timestamp. An end date means the
+// end of that day, i.e. the next midnight.
+func parseRangeBound(s string, loc *time.Location, end bool) (time.Time, error) {
+	if d, err := time.ParseInLocation(time.DateOnly, s, loc); err == nil {
+		if end {
+			d = d.AddDate(0, 0, 1)
+		}
+		return d, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a YYYY-MM-DD date or RFC 3339 timestamp", s)
+	}
+	return t, nil
+}
+
+// requestWindow resolves the window of a stats request from from/to or the
+// period parameter (defaultPeriod if absent), and returns it with the period
+// name ("custom" for from/to). Bad parameters are answered with a 400 and ok
+// is false.
+func requestWindow(c *gin.Context, loc *time.Location, now time.Time, defaultPeriod string) (w statsWindow, period string, ok bool) {
+	w, found, err := customWindow(c, "from", "to", loc, now)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return statsWindow{}, "", false
+	}
+	if found {
+		return w, "custom", true
+	}
+
+	period = c.DefaultQuery("period", defaultPeriod)
+	w, _, valid := periodWindows(period, now)
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": statsPeriodError})
+		return statsWindow{}, "", false
+	}
+	return w, period, true
+}
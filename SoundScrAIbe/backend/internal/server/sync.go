@@ -0,0 +1,26 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+
+	"soundscraibe/internal/history"
+	"soundscraibe/internal/user"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SyncStatus returns the background listening-history sync state for the
+// authenticated user: the last cursor, when it last succeeded, and the last error.
+func (h *handlers) SyncStatus(c *gin.Context) {
+	currentUser := c.MustGet("user").(*user.User)
+
+	state, err := history.GetState(c.Request.Context(), h.db, currentUser.ID)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to load sync state", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load sync status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, state)
+}
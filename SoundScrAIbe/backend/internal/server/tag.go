@@ -1,7 +1,7 @@
 package server
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
 
@@ -27,9 +27,7 @@ func (h *handlers) SetTags(c *gin.Context) {
 	}
 
 	var body struct {
-		Tags     []string `json:"tags" binding:"required"`
-		Name     string   `json:"name"`
-		ImageURL string   `json:"image_url"`
+		Tags []string `json:"tags" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "tags array is required"})
@@ -45,11 +43,15 @@ func (h *handlers) SetTags(c *gin.Context) {
 		}
 	}
 
+	if !h.ensureMetadata(c, currentUser, entityType, entityID) {
+		return
+	}
+
 	ctx := c.Request.Context()
 
 	tx, err := h.db.BeginTx(ctx, nil)
 	if err != nil {
-		log.Printf("failed to begin transaction for tags: %v", err)
+		slog.ErrorContext(ctx, "failed to begin transaction for tags", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save tags"})
 		return
 	}
@@ -61,7 +63,7 @@ func (h *handlers) SetTags(c *gin.Context) {
 		currentUser.ID, entityType, entityID,
 	)
 	if err != nil {
-		log.Printf("failed to delete existing item_tags for user %d, %s/%s: %v", currentUser.ID, entityType, entityID, err)
+		slog.ErrorContext(ctx, "failed to delete existing item tags", "entity_type", entityType, "entity_id", entityID, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save tags"})
 		return
 	}
@@ -75,7 +77,7 @@ func (h *handlers) SetTags(c *gin.Context) {
 			currentUser.ID, tagName,
 		)
 		if err != nil {
-			log.Printf("failed to upsert tag %q for user %d: %v", tagName, currentUser.ID, err)
+			slog.ErrorContext(ctx, "failed to upsert tag", "tag", tagName, "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save tags"})
 			return
 		}
@@ -87,7 +89,7 @@ func (h *handlers) SetTags(c *gin.Context) {
 			currentUser.ID, tagName,
 		).Scan(&tagID)
 		if err != nil {
-			log.Printf("failed to get tag id for %q, user %d: %v", tagName, currentUser.ID, err)
+			slog.ErrorContext(ctx, "failed to get tag id", "tag", tagName, "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save tags"})
 			return
 		}
@@ -99,28 +101,14 @@ func (h *handlers) SetTags(c *gin.Context) {
 			currentUser.ID, entityType, entityID, tagID,
 		)
 		if err != nil {
-			log.Printf("failed to insert item_tag for user %d, %s/%s, tag %d: %v", currentUser.ID, entityType, entityID, tagID, err)
+			slog.ErrorContext(ctx, "failed to insert item tag", "entity_type", entityType, "entity_id", entityID, "tag_id", tagID, "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save tags"})
 			return
 		}
 	}
 
-	// Upsert entity_metadata
-	if body.Name != "" {
-		_, err = tx.ExecContext(ctx,
-			`INSERT INTO entity_metadata (entity_type, entity_id, name, image_url)
-			 VALUES ($1, $2, $3, $4)
-			 ON CONFLICT ON CONSTRAINT uq_entity_meta
-			 DO UPDATE SET name = $3, image_url = $4, updated_at = now()`,
-			entityType, entityID, body.Name, body.ImageURL,
-		)
-		if err != nil {
-			log.Printf("failed to upsert entity_metadata for %s/%s: %v", entityType, entityID, err)
-		}
-	}
-
 	if err := tx.Commit(); err != nil {
-		log.Printf("failed to commit tags transaction for user %d, %s/%s: %v", currentUser.ID, entityType, entityID, err)
+		slog.ErrorContext(ctx, "failed to commit tags transaction", "entity_type", entityType, "entity_id", entityID, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save tags"})
 		return
 	}
@@ -150,7 +138,7 @@ func (h *handlers) GetTags(c *gin.Context) {
 		currentUser.ID, entityType, entityID,
 	)
 	if err != nil {
-		log.Printf("failed to query tags for user %d, %s/%s: %v", currentUser.ID, entityType, entityID, err)
+		slog.ErrorContext(c.Request.Context(), "failed to query tags", "entity_type", entityType, "entity_id", entityID, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get tags"})
 		return
 	}
@@ -160,7 +148,7 @@ func (h *handlers) GetTags(c *gin.Context) {
 	for rows.Next() {
 		var name string
 		if err := rows.Scan(&name); err != nil {
-			log.Printf("failed to scan tag name: %v", err)
+			slog.ErrorContext(c.Request.Context(), "failed to scan tag name", "error", err)
 			continue
 		}
 		tags = append(tags, name)
@@ -182,7 +170,7 @@ func (h *handlers) GetUserTags(c *gin.Context) {
 		currentUser.ID,
 	)
 	if err != nil {
-		log.Printf("failed to query user tags for user %d: %v", currentUser.ID, err)
+		slog.ErrorContext(c.Request.Context(), "failed to query user tags", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get tags"})
 		return
 	}
@@ -197,7 +185,7 @@ func (h *handlers) GetUserTags(c *gin.Context) {
 	for rows.Next() {
 		var t tagItem
 		if err := rows.Scan(&t.ID, &t.Name); err != nil {
-			log.Printf("failed to scan user tag: %v", err)
+			slog.ErrorContext(c.Request.Context(), "failed to scan user tag", "error", err)
 			continue
 		}
 		tags = append(tags, t)
@@ -2,12 +2,11 @@ package server
 
 import (
 	"database/sql"
-	"fmt"
-	"log"
+	"log/slog"
+	"math"
 	"net/http"
 	"time"
 
-	"soundscraibe/internal/spotify"
 	"soundscraibe/internal/user"
 
 	"github.com/gin-gonic/gin"
@@ -24,20 +23,20 @@ func (h *handlers) TrackDetail(c *gin.Context) {
 	trackID := c.Param("id")
 	ctx := c.Request.Context()
 
-	track, err := spotify.GetTrack(ctx, currentUser.AccessToken, trackID)
+	track, err := h.sp.GetTrack(ctx, currentUser.AccessToken, trackID)
 	if err != nil {
-		log.Printf("failed to fetch track %s for user %d: %v", trackID, currentUser.ID, err)
+		slog.ErrorContext(ctx, "failed to fetch track", "track_id", trackID, "error", err)
 		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to fetch track"})
 		return
 	}
 
-	audioFeatures, err := spotify.GetAudioFeatures(ctx, currentUser.AccessToken, trackID)
+	audioFeatures, err := h.sp.GetAudioFeatures(ctx, currentUser.AccessToken, trackID)
 	if err != nil {
-		log.Printf("failed to fetch audio features for track %s: %v", trackID, err)
+		slog.WarnContext(ctx, "failed to fetch audio features", "track_id", trackID, "error", err)
 		audioFeatures = nil
 	}
 
-	saved, _ := spotify.CheckSavedTracks(ctx, currentUser.AccessToken, []string{trackID})
+	saved, _ := h.sp.CheckSavedTracks(ctx, currentUser.AccessToken, []string{trackID})
 	isLiked := len(saved) > 0 && saved[0]
 
 	type artistItem struct {
@@ -72,17 +71,20 @@ func (h *handlers) TrackDetail(c *gin.Context) {
 		"is_liked":     isLiked,
 	}
 
-	// Query listening history stats from local DB
-	var playCount int
+	// Query listening history stats from local DB. A skip is a play with
+	// less than the listen threshold of the track played.
+	var playCount, knownCount, skips int
 	var firstPlayed, lastPlayed *time.Time
+	var avgCompletion *float64
 	err = h.db.QueryRowContext(ctx,
-		`SELECT COUNT(*), MIN(played_at), MAX(played_at)
-		 FROM listening_history
+		`SELECT COUNT(*), MIN(played_at), MAX(played_at),
+			COUNT(completion), COUNT(*) FILTER (WHERE completion < $3), AVG(completion)
+		 FROM plays
 		 WHERE user_id = $1 AND track_id = $2`,
-		currentUser.ID, trackID,
-	).Scan(&playCount, &firstPlayed, &lastPlayed)
+		currentUser.ID, trackID, h.cfg.ListenThreshold,
+	).Scan(&playCount, &firstPlayed, &lastPlayed, &knownCount, &skips, &avgCompletion)
 	if err != nil {
-		log.Printf("failed to query listening history for track %s: %v", trackID, err)
+		slog.ErrorContext(ctx, "failed to query listening history", "track_id", trackID, "error", err)
 		playCount = 0
 	}
 
@@ -93,6 +95,12 @@ func (h *handlers) TrackDetail(c *gin.Context) {
 	if lastPlayed != nil {
 		stats["last_played"] = lastPlayed.Format(time.RFC3339)
 	}
+	if knownCount > 0 && avgCompletion != nil {
+		stats["avg_completion"] = math.Round(*avgCompletion*1000) / 1000
+		stats["skip_rate"] = math.Round(float64(skips)/float64(knownCount)*1000) / 1000
+		stats["skips"] = skips
+		stats["real_listens"] = playCount - skips
+	}
 	response["listening_stats"] = stats
 
 	// Query rating
@@ -102,7 +110,7 @@ func (h *handlers) TrackDetail(c *gin.Context) {
 		currentUser.ID, trackID,
 	).Scan(&ratingScore)
 	if err != nil && err != sql.ErrNoRows {
-		log.Printf("failed to query rating for track %s: %v", trackID, err)
+		slog.ErrorContext(ctx, "failed to query rating", "track_id", trackID, "error", err)
 	}
 
 	// Query shelf
@@ -112,7 +120,7 @@ func (h *handlers) TrackDetail(c *gin.Context) {
 		currentUser.ID, trackID,
 	).Scan(&shelfStatus)
 	if err != nil && err != sql.ErrNoRows {
-		log.Printf("failed to query shelf for track %s: %v", trackID, err)
+		slog.ErrorContext(ctx, "failed to query shelf", "track_id", trackID, "error", err)
 	}
 
 	// Query tags
@@ -139,15 +147,7 @@ func (h *handlers) TrackDetail(c *gin.Context) {
 	response["shelf"] = shelfStatus
 	response["tags"] = trackTags
 
-	// Upsert entity metadata for library
-	_, _ = h.db.ExecContext(ctx,
-		`INSERT INTO entity_metadata (entity_type, entity_id, name, image_url, extra_json)
-		 VALUES ('track', $1, $2, $3, $4)
-		 ON CONFLICT ON CONSTRAINT uq_entity_meta
-		 DO UPDATE SET name = $2, image_url = $3, extra_json = $4, updated_at = now()`,
-		trackID, track.Name, albumCover,
-		fmt.Sprintf(`{"artist_name": %q}`, track.Artists[0].Name),
-	)
+	h.refreshMetadata(ctx, currentUser, "track", trackID)
 
 	if audioFeatures != nil {
 		response["audio_features"] = gin.H{
@@ -6,20 +6,54 @@ import (
 	"database/sql"
 	"encoding/hex"
 	"fmt"
+	"log/slog"
 	"time"
 )
 
 const sessionDuration = 7 * 24 * time.Hour // 7 days
 
+// renewWithin is how close to expiry a session gets before use extends it by
+// another sessionDuration.
+const renewWithin = sessionDuration / 2
+
+// maxLifetime caps rolling renewal; after this the user has to log in again.
+const maxLifetime = 90 * 24 * time.Hour
+
+// touchInterval throttles last-seen updates so not every request writes.
+const touchInterval = 5 * time.Minute
+
+// maxUserAgent bounds the stored User-Agent header.
+const maxUserAgent = 512
+
+// Duration is the lifetime of a new or renewed session, for cookie max-age.
+const Duration = sessionDuration
+
 type Session struct {
-	Token     string
-	UserID    int64
-	ExpiresAt time.Time
-	CreatedAt time.Time
+	ID         int64     `json:"id"`
+	Token      string    `json:"-"`
+	UserID     int64     `json:"-"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Client describes where a request came from.
+type Client struct {
+	UserAgent string
+	IP        string
+}
+
+func (cl Client) userAgent() string {
+	if len(cl.UserAgent) > maxUserAgent {
+		return cl.UserAgent[:maxUserAgent]
+	}
+	return cl.UserAgent
 }
 
 // Create generates a new session for the given user and stores it in the database.
-func Create(ctx context.Context, db *sql.DB, userID int64) (string, error) {
+func Create(ctx context.Context, db *sql.DB, userID int64, client Client) (string, error) {
 	bytes := make([]byte, 32)
 	if _, err := rand.Read(bytes); err != nil {
 		return "", fmt.Errorf("generating session token: %w", err)
@@ -28,8 +62,8 @@ func Create(ctx context.Context, db *sql.DB, userID int64) (string, error) {
 	expiresAt := time.Now().Add(sessionDuration)
 
 	_, err := db.ExecContext(ctx, `
-		INSERT INTO sessions (token, user_id, expires_at) VALUES ($1, $2, $3)`,
-		token, userID, expiresAt,
+		INSERT INTO sessions (token, user_id, expires_at, user_agent, ip) VALUES ($1, $2, $3, $4, $5)`,
+		token, userID, expiresAt, client.userAgent(), client.IP,
 	)
 	if err != nil {
 		return "", fmt.Errorf("creating session: %w", err)
@@ -42,15 +76,77 @@ func Create(ctx context.Context, db *sql.DB, userID int64) (string, error) {
 func GetByToken(ctx context.Context, db *sql.DB, token string) (*Session, error) {
 	s := &Session{}
 	err := db.QueryRowContext(ctx, `
-		SELECT token, user_id, expires_at, created_at
+		SELECT id, token, user_id, user_agent, ip, expires_at, last_seen_at, created_at
 		FROM sessions WHERE token = $1 AND expires_at > now()`, token,
-	).Scan(&s.Token, &s.UserID, &s.ExpiresAt, &s.CreatedAt)
+	).Scan(&s.ID, &s.Token, &s.UserID, &s.UserAgent, &s.IP, &s.ExpiresAt, &s.LastSeenAt, &s.CreatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("getting session: %w", err)
 	}
 	return s, nil
 }
 
+// Touch records that s was just used from client and, once it's within
+// renewWithin of expiring, extends it (up to maxLifetime after creation).
+// Writes are throttled to one per touchInterval unless a renewal is due.
+// It updates s in place and reports whether the expiry moved, in which case
+// the cookie should be re-issued.
+func Touch(ctx context.Context, db *sql.DB, s *Session, client Client) (bool, error) {
+	now := time.Now()
+	renew := time.Until(s.ExpiresAt) < renewWithin
+	if !renew && now.Sub(s.LastSeenAt) < touchInterval {
+		return false, nil
+	}
+
+	expiresAt := s.ExpiresAt
+	if renew {
+		expiresAt = now.Add(sessionDuration)
+		if limit := s.CreatedAt.Add(maxLifetime); expiresAt.After(limit) {
+			expiresAt = limit
+		}
+	}
+
+	_, err := db.ExecContext(ctx, `
+		UPDATE sessions SET last_seen_at = $2, user_agent = $3, ip = $4, expires_at = GREATEST(expires_at, $5)
+		WHERE token = $1`,
+		s.Token, now, client.userAgent(), client.IP, expiresAt,
+	)
+	if err != nil {
+		return false, fmt.Errorf("touching session: %w", err)
+	}
+
+	renewed := expiresAt.After(s.ExpiresAt)
+	s.LastSeenAt, s.UserAgent, s.IP = now, client.userAgent(), client.IP
+	if renewed {
+		s.ExpiresAt = expiresAt
+	}
+	return renewed, nil
+}
+
+// ListByUser returns the user's active sessions, most recently used first.
+func ListByUser(ctx context.Context, db *sql.DB, userID int64) ([]Session, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, token, user_id, user_agent, ip, expires_at, last_seen_at, created_at
+		FROM sessions WHERE user_id = $1 AND expires_at > now()
+		ORDER BY last_seen_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("listing sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []Session{}
+	for rows.Next() {
+		var s Session
+		if err := rows.Scan(&s.ID, &s.Token, &s.UserID, &s.UserAgent, &s.IP, &s.ExpiresAt, &s.LastSeenAt, &s.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scanning session: %w", err)
+		}
+		sessions = append(sessions, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating sessions: %w", err)
+	}
+	return sessions, nil
+}
+
 // Delete removes a session by its token (for logout).
 func Delete(ctx context.Context, db *sql.DB, token string) error {
 	_, err := db.ExecContext(ctx, `DELETE FROM sessions WHERE token = $1`, token)
@@ -59,3 +155,67 @@ func Delete(ctx context.Context, db *sql.DB, token string) error {
 	}
 	return nil
 }
+
+// DeleteByID revokes one of the user's sessions. It reports false if the user
+// has no session with that ID.
+func DeleteByID(ctx context.Context, db *sql.DB, userID, id int64) (bool, error) {
+	res, err := db.ExecContext(ctx, `DELETE FROM sessions WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("revoking session: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("revoking session: %w", err)
+	}
+	return n > 0, nil
+}
+
+// DeleteOthers revokes all of the user's sessions except keepToken and returns
+// how many were removed.
+func DeleteOthers(ctx context.Context, db *sql.DB, userID int64, keepToken string) (int64, error) {
+	res, err := db.ExecContext(ctx, `DELETE FROM sessions WHERE user_id = $1 AND token != $2`, userID, keepToken)
+	if err != nil {
+		return 0, fmt.Errorf("revoking other sessions: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("revoking other sessions: %w", err)
+	}
+	return n, nil
+}
+
+// PurgeExpired deletes expired sessions and returns how many were removed.
+func PurgeExpired(ctx context.Context, db *sql.DB) (int64, error) {
+	res, err := db.ExecContext(ctx, `DELETE FROM sessions WHERE expires_at <= now()`)
+	if err != nil {
+		return 0, fmt.Errorf("purging expired sessions: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("purging expired sessions: %w", err)
+	}
+	return n, nil
+}
+
+// RunPurger deletes expired sessions every interval until ctx is cancelled.
+func RunPurger(ctx context.Context, db *sql.DB, interval time.Duration) {
+	slog.InfoContext(ctx, "session purger started", "interval", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := PurgeExpired(ctx, db); err != nil {
+			slog.ErrorContext(ctx, "session purge failed", "error", err)
+		} else if n > 0 {
+			slog.InfoContext(ctx, "purged expired sessions", "count", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "session purger stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
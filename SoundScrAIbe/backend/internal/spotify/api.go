@@ -0,0 +1,155 @@
+package spotify
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"soundscraibe/internal/metrics"
+)
+
+// API is the subset of the Spotify Web API the app uses. *Client implements it;
+// handlers, the recommendation service and the importers depend on this
+// interface so they can run against a fake.
+type API interface {
+	GetProfile(ctx context.Context, accessToken string) (*Profile, error)
+	GetTopArtists(ctx context.Context, accessToken, timeRange string, limit int) (*TopArtistsResponse, error)
+	GetTopTracks(ctx context.Context, accessToken, timeRange string, limit int) (*TopTracksResponse, error)
+	GetRecentlyPlayed(ctx context.Context, accessToken string, after int64) (*RecentlyPlayedResponse, error)
+
+	GetArtist(ctx context.Context, accessToken, artistID string) (*TopArtist, error)
+	GetArtists(ctx context.Context, accessToken string, artistIDs []string) ([]*TopArtist, error)
+	GetTrack(ctx context.Context, accessToken, trackID string) (*FullTrack, error)
+	GetTracks(ctx context.Context, accessToken string, trackIDs []string) ([]*FullTrack, error)
+	GetAlbum(ctx context.Context, accessToken, albumID string) (*FullAlbum, error)
+	GetAlbums(ctx context.Context, accessToken string, albumIDs []string) ([]*FullAlbum, error)
+	GetAudioFeatures(ctx context.Context, accessToken, trackID string) (*AudioFeatures, error)
+	Search(ctx context.Context, accessToken, query, types string, limit int) (*SearchResponse, error)
+
+	CheckSavedTracks(ctx context.Context, accessToken string, ids []string) ([]bool, error)
+	SaveTracks(ctx context.Context, accessToken string, ids []string) error
+	RemoveTracks(ctx context.Context, accessToken string, ids []string) error
+	GetSavedTracks(ctx context.Context, accessToken string, limit, offset int) (*SavedTracksResponse, error)
+	GetSavedAlbums(ctx context.Context, accessToken string, limit, offset int) (*SavedAlbumsResponse, error)
+	GetFollowedArtists(ctx context.Context, accessToken string, limit int, after string) (*FollowedArtistsResponse, error)
+}
+
+var _ API = (*Client)(nil)
+
+// sharedTransport is reused by every Client without a custom HTTP client, so
+// connections to Spotify are pooled across requests.
+var sharedTransport = &http.Transport{
+	Proxy:               http.ProxyFromEnvironment,
+	MaxIdleConns:        100,
+	MaxIdleConnsPerHost: 20,
+	IdleConnTimeout:     90 * time.Second,
+	TLSHandshakeTimeout: 10 * time.Second,
+	ForceAttemptHTTP2:   true,
+}
+
+// ClientOptions configures a Client. Zero values use Spotify's real endpoints
+// and the shared transport, without rate limiting.
+type ClientOptions struct {
+	APIBaseURL      string // default https://api.spotify.com/v1
+	AccountsBaseURL string // default https://accounts.spotify.com
+	HTTPClient      *http.Client
+
+	// RateLimit caps requests per second across all users of the Client
+	// (Spotify's limit is per app), with bursts of up to RateBurst.
+	RateLimit float64
+	RateBurst int
+}
+
+// Client talks to the Spotify Web API and accounts service.
+type Client struct {
+	apiBaseURL      string
+	accountsBaseURL string
+	http            *http.Client
+	limiter         *tokenBucket        // nil: unlimited
+	breakers        map[string]*breaker // by host
+}
+
+// NewClient returns a Client for the given base URLs (e.g. a fake server's).
+func NewClient(opts ClientOptions) *Client {
+	if opts.APIBaseURL == "" {
+		opts.APIBaseURL = defaultAPIBaseURL
+	}
+	if opts.AccountsBaseURL == "" {
+		opts.AccountsBaseURL = defaultAccountsBaseURL
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: 10 * time.Second, Transport: sharedTransport}
+	}
+	c := &Client{
+		apiBaseURL:      strings.TrimRight(opts.APIBaseURL, "/"),
+		accountsBaseURL: strings.TrimRight(opts.AccountsBaseURL, "/"),
+		http:            opts.HTTPClient,
+		limiter:         newTokenBucket(opts.RateLimit, opts.RateBurst),
+		breakers:        map[string]*breaker{},
+	}
+	for _, base := range []string{c.apiBaseURL, c.accountsBaseURL} {
+		if u, err := url.Parse(base); err == nil {
+			c.breakers[u.Host] = &breaker{host: u.Host}
+		}
+	}
+	return c
+}
+
+// do sends req through the outbound layer (rate limit, circuit breaker,
+// retries), records it in the Spotify metrics, and logs the call against the
+// request's context so it carries the originating request ID.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := c.send(req)
+
+	endpoint := endpointLabel(req.URL.Path)
+	status := "error"
+	switch {
+	case errors.Is(err, ErrUnavailable):
+		status = "circuit_open"
+	case err == nil:
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	metrics.SpotifyRequests.WithLabelValues(endpoint, status).Inc()
+	metrics.SpotifyRequestDuration.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("path", req.URL.Path),
+		slog.Int64("duration_ms", time.Since(start).Milliseconds()),
+	}
+	level := slog.LevelInfo
+	if err != nil {
+		level = slog.LevelWarn
+		attrs = append(attrs, slog.String("error", err.Error()))
+	} else {
+		if resp.StatusCode >= 400 {
+			level = slog.LevelWarn
+		}
+		attrs = append(attrs, slog.Int("status", resp.StatusCode))
+	}
+	slog.LogAttrs(req.Context(), level, "spotify request", attrs...)
+
+	return resp, err
+}
+
+// endpointLabel turns a request path into a low-cardinality metrics label by
+// replacing the ID after /artists/, /tracks/, /albums/ and /audio-features/
+// with {id}.
+func endpointLabel(path string) string {
+	segments := strings.Split(path, "/")
+	for i := 1; i < len(segments); i++ {
+		switch segments[i-1] {
+		case "artists", "tracks", "albums", "audio-features":
+			if segments[i] != "" {
+				segments[i] = "{id}"
+			}
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -3,75 +3,55 @@ package spotify
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
-	"time"
 )
 
-// doWithRetry executes an HTTP request, retrying on 429 with Retry-After backoff.
-// Retries up to 3 times.
-func doWithRetry(client *http.Client, req *http.Request) (*http.Response, error) {
-	for attempt := 0; attempt < 3; attempt++ {
-		resp, err := client.Do(req)
-		if err != nil {
-			return nil, err
-		}
-		if resp.StatusCode != http.StatusTooManyRequests {
-			return resp, nil
-		}
-		resp.Body.Close()
-
-		// Parse Retry-After header (seconds), default to 2^attempt seconds
-		wait := time.Duration(1<<uint(attempt)) * time.Second
-		if ra := resp.Header.Get("Retry-After"); ra != "" {
-			if secs, err := strconv.Atoi(ra); err == nil {
-				wait = time.Duration(secs) * time.Second
-			}
-		}
-		log.Printf("spotify rate limited, retrying in %v (attempt %d/3)", wait, attempt+1)
-		time.Sleep(wait)
-
-		// Rebuild request body for retry since it was already consumed
-		// For GET requests, body is nil so no issue
-		// For POST requests with form data, we need the original body
-		if req.GetBody != nil {
-			body, err := req.GetBody()
-			if err != nil {
-				return nil, fmt.Errorf("rebuilding request body for retry: %w", err)
-			}
-			req.Body = body
-		}
-	}
-	return nil, fmt.Errorf("spotify rate limit: max retries exceeded")
-}
-
 const (
-	tokenURL          = "https://accounts.spotify.com/api/token"
-	profileURL        = "https://api.spotify.com/v1/me"
-	recentlyPlayedURL  = "https://api.spotify.com/v1/me/player/recently-played"
-	libraryURL         = "https://api.spotify.com/v1/me/library"
-	libraryContainsURL = "https://api.spotify.com/v1/me/library/contains"
-	topArtistsURL      = "https://api.spotify.com/v1/me/top/artists"
-	topTracksURL       = "https://api.spotify.com/v1/me/top/tracks"
-	artistURL          = "https://api.spotify.com/v1/artists/"
-	trackURL           = "https://api.spotify.com/v1/tracks/"
-	albumURL           = "https://api.spotify.com/v1/albums/"
-	audioFeaturesURL   = "https://api.spotify.com/v1/audio-features/"
-	searchURL          = "https://api.spotify.com/v1/search"
-	savedTracksURL     = "https://api.spotify.com/v1/me/tracks"
-	savedAlbumsURL     = "https://api.spotify.com/v1/me/albums"
-	followedArtistsURL = "https://api.spotify.com/v1/me/following"
+	defaultAPIBaseURL      = "https://api.spotify.com/v1"
+	defaultAccountsBaseURL = "https://accounts.spotify.com"
+
+	tokenPath           = "/api/token"
+	profilePath         = "/me"
+	recentlyPlayedPath  = "/me/player/recently-played"
+	libraryPath         = "/me/library"
+	libraryContainsPath = "/me/library/contains"
+	topArtistsPath      = "/me/top/artists"
+	topTracksPath       = "/me/top/tracks"
+	artistPath          = "/artists/"
+	trackPath           = "/tracks/"
+	albumPath           = "/albums/"
+	audioFeaturesPath   = "/audio-features/"
+	searchPath          = "/search"
+	savedTracksPath     = "/me/tracks"
+	savedAlbumsPath     = "/me/albums"
+	followedArtistsPath = "/me/following"
 )
 
+// Config holds the app's OAuth credentials. Token requests go through Client,
+// or a default Client when nil.
 type Config struct {
 	ClientID     string
 	ClientSecret string
 	RedirectURI  string
+	Client       *Client
+}
+
+func (c *Config) client() *Client {
+	if c.Client == nil {
+		return NewClient(ClientOptions{})
+	}
+	return c.Client
+}
+
+// AuthorizeURL returns the accounts-service URL the browser is sent to for login.
+func (c *Config) AuthorizeURL() string {
+	return c.client().accountsBaseURL + "/authorize"
 }
 
 type TokenResponse struct {
@@ -123,7 +103,7 @@ func (c *Config) ExchangeCode(ctx context.Context, code, codeVerifier string) (*
 		data.Set("client_id", c.ClientID)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.client().accountsBaseURL+tokenPath, strings.NewReader(data.Encode()))
 	if err != nil {
 		return nil, fmt.Errorf("creating token request: %w", err)
 	}
@@ -133,8 +113,7 @@ func (c *Config) ExchangeCode(ctx context.Context, code, codeVerifier string) (*
 		req.SetBasicAuth(c.ClientID, c.ClientSecret)
 	}
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := doWithRetry(client, req)
+	resp, err := c.client().do(req)
 	if err != nil {
 		return nil, fmt.Errorf("exchanging code: %w", err)
 	}
@@ -157,6 +136,11 @@ func (c *Config) ExchangeCode(ctx context.Context, code, codeVerifier string) (*
 	return &tokenResp, nil
 }
 
+// ErrInvalidGrant is returned (wrapped) by RefreshAccessToken when Spotify
+// rejects the refresh token itself, e.g. because the user revoked access. The
+// user has to log in again; retrying won't help.
+var ErrInvalidGrant = errors.New("spotify rejected the refresh token")
+
 // RefreshAccessToken uses a refresh token to get a new access token.
 func (c *Config) RefreshAccessToken(ctx context.Context, refreshToken string) (*TokenResponse, error) {
 	data := url.Values{
@@ -169,7 +153,7 @@ func (c *Config) RefreshAccessToken(ctx context.Context, refreshToken string) (*
 		data.Set("client_id", c.ClientID)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.client().accountsBaseURL+tokenPath, strings.NewReader(data.Encode()))
 	if err != nil {
 		return nil, fmt.Errorf("creating refresh request: %w", err)
 	}
@@ -179,8 +163,7 @@ func (c *Config) RefreshAccessToken(ctx context.Context, refreshToken string) (*
 		req.SetBasicAuth(c.ClientID, c.ClientSecret)
 	}
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := doWithRetry(client, req)
+	resp, err := c.client().do(req)
 	if err != nil {
 		return nil, fmt.Errorf("refreshing token: %w", err)
 	}
@@ -192,6 +175,12 @@ func (c *Config) RefreshAccessToken(ctx context.Context, refreshToken string) (*
 	}
 
 	if resp.StatusCode != http.StatusOK {
+		var oauthErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(body, &oauthErr) == nil && oauthErr.Error == "invalid_grant" {
+			return nil, fmt.Errorf("spotify refresh error (status %d): %s: %w", resp.StatusCode, string(body), ErrInvalidGrant)
+		}
 		return nil, fmt.Errorf("spotify refresh error (status %d): %s", resp.StatusCode, string(body))
 	}
 
@@ -203,16 +192,56 @@ func (c *Config) RefreshAccessToken(ctx context.Context, refreshToken string) (*
 	return &tokenResp, nil
 }
 
+// ClientCredentialsToken gets an app access token (client credentials flow)
+// for catalog lookups made outside any user's request. It needs a client
+// secret.
+func (c *Config) ClientCredentialsToken(ctx context.Context) (*TokenResponse, error) {
+	if c.ClientSecret == "" {
+		return nil, errors.New("client credentials flow needs a spotify client secret")
+	}
+	data := url.Values{
+		"grant_type": {"client_credentials"},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.client().accountsBaseURL+tokenPath, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("creating client credentials request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(c.ClientID, c.ClientSecret)
+
+	resp, err := c.client().do(req)
+	if err != nil {
+		return nil, fmt.Errorf("requesting client credentials token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading client credentials response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("spotify client credentials error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var tokenResp TokenResponse
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return nil, fmt.Errorf("parsing client credentials response: %w", err)
+	}
+
+	return &tokenResp, nil
+}
+
 // GetProfile fetches the current user's Spotify profile.
-func GetProfile(ctx context.Context, accessToken string) (*Profile, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, profileURL, nil)
+func (c *Client) GetProfile(ctx context.Context, accessToken string) (*Profile, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.apiBaseURL+profilePath, nil)
 	if err != nil {
 		return nil, fmt.Errorf("creating profile request: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := doWithRetry(client, req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("fetching profile: %w", err)
 	}
@@ -236,7 +265,12 @@ func GetProfile(ctx context.Context, accessToken string) (*Profile, error) {
 }
 
 type RecentlyPlayedResponse struct {
-	Items []PlayHistoryItem `json:"items"`
+	Items   []PlayHistoryItem `json:"items"`
+	Next    *string           `json:"next"`
+	Cursors *struct {
+		After  string `json:"after"`
+		Before string `json:"before"`
+	} `json:"cursors"`
 }
 
 type PlayHistoryItem struct {
@@ -258,9 +292,10 @@ type Artist struct {
 }
 
 type Album struct {
-	ID     string  `json:"id"`
-	Name   string  `json:"name"`
-	Images []Image `json:"images"`
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	ReleaseDate string  `json:"release_date,omitempty"`
+	Images      []Image `json:"images"`
 }
 
 type ExternalURLs struct {
@@ -294,6 +329,7 @@ type FullTrack struct {
 	TrackNumber  int          `json:"track_number"`
 	DiscNumber   int          `json:"disc_number"`
 	PreviewURL   *string      `json:"preview_url"`
+	Popularity   int          `json:"popularity"`
 	Artists      []FullArtist `json:"artists"`
 	Album        FullAlbum    `json:"album"`
 	ExternalURLs ExternalURLs `json:"external_urls"`
@@ -354,16 +390,15 @@ type TopTracksResponse struct {
 }
 
 // GetTopArtists fetches the user's top artists for the given time range.
-func GetTopArtists(ctx context.Context, accessToken, timeRange string, limit int) (*TopArtistsResponse, error) {
-	u := fmt.Sprintf("%s?time_range=%s&limit=%d", topArtistsURL, timeRange, limit)
+func (c *Client) GetTopArtists(ctx context.Context, accessToken, timeRange string, limit int) (*TopArtistsResponse, error) {
+	u := fmt.Sprintf("%s?time_range=%s&limit=%d", c.apiBaseURL+topArtistsPath, timeRange, limit)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
 	if err != nil {
 		return nil, fmt.Errorf("creating top-artists request: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := doWithRetry(client, req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("fetching top artists: %w", err)
 	}
@@ -387,16 +422,15 @@ func GetTopArtists(ctx context.Context, accessToken, timeRange string, limit int
 }
 
 // GetTopTracks fetches the user's top tracks for the given time range.
-func GetTopTracks(ctx context.Context, accessToken, timeRange string, limit int) (*TopTracksResponse, error) {
-	u := fmt.Sprintf("%s?time_range=%s&limit=%d", topTracksURL, timeRange, limit)
+func (c *Client) GetTopTracks(ctx context.Context, accessToken, timeRange string, limit int) (*TopTracksResponse, error) {
+	u := fmt.Sprintf("%s?time_range=%s&limit=%d", c.apiBaseURL+topTracksPath, timeRange, limit)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
 	if err != nil {
 		return nil, fmt.Errorf("creating top-tracks request: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := doWithRetry(client, req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("fetching top tracks: %w", err)
 	}
@@ -420,15 +454,14 @@ func GetTopTracks(ctx context.Context, accessToken, timeRange string, limit int)
 }
 
 // GetArtist fetches a single artist by ID (public endpoint, no special scope needed).
-func GetArtist(ctx context.Context, accessToken, artistID string) (*TopArtist, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, artistURL+artistID, nil)
+func (c *Client) GetArtist(ctx context.Context, accessToken, artistID string) (*TopArtist, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.apiBaseURL+artistPath+artistID, nil)
 	if err != nil {
 		return nil, fmt.Errorf("creating artist request: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := doWithRetry(client, req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("fetching artist: %w", err)
 	}
@@ -452,20 +485,19 @@ func GetArtist(ctx context.Context, accessToken, artistID string) (*TopArtist, e
 }
 
 // CheckSavedTracks checks if the given track IDs are saved in the user's library.
-func CheckSavedTracks(ctx context.Context, accessToken string, ids []string) ([]bool, error) {
+func (c *Client) CheckSavedTracks(ctx context.Context, accessToken string, ids []string) ([]bool, error) {
 	uris := make([]string, len(ids))
 	for i, id := range ids {
 		uris[i] = "spotify:track:" + id
 	}
-	u := libraryContainsURL + "?uris=" + strings.Join(uris, ",")
+	u := c.apiBaseURL + libraryContainsPath + "?uris=" + strings.Join(uris, ",")
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
 	if err != nil {
 		return nil, fmt.Errorf("creating check-saved request: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := doWithRetry(client, req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("checking saved tracks: %w", err)
 	}
@@ -489,20 +521,19 @@ func CheckSavedTracks(ctx context.Context, accessToken string, ids []string) ([]
 }
 
 // SaveTracks saves the given track IDs to the user's library using the new PUT /me/library endpoint.
-func SaveTracks(ctx context.Context, accessToken string, ids []string) error {
+func (c *Client) SaveTracks(ctx context.Context, accessToken string, ids []string) error {
 	uris := make([]string, len(ids))
 	for i, id := range ids {
 		uris[i] = "spotify:track:" + id
 	}
-	u := libraryURL + "?uris=" + strings.Join(uris, ",")
+	u := c.apiBaseURL + libraryPath + "?uris=" + strings.Join(uris, ",")
 	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u, nil)
 	if err != nil {
 		return fmt.Errorf("creating save-tracks request: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := doWithRetry(client, req)
+	resp, err := c.do(req)
 	if err != nil {
 		return fmt.Errorf("saving tracks: %w", err)
 	}
@@ -517,20 +548,19 @@ func SaveTracks(ctx context.Context, accessToken string, ids []string) error {
 }
 
 // RemoveTracks removes the given track IDs from the user's library using the new DELETE /me/library endpoint.
-func RemoveTracks(ctx context.Context, accessToken string, ids []string) error {
+func (c *Client) RemoveTracks(ctx context.Context, accessToken string, ids []string) error {
 	uris := make([]string, len(ids))
 	for i, id := range ids {
 		uris[i] = "spotify:track:" + id
 	}
-	u := libraryURL + "?uris=" + strings.Join(uris, ",")
+	u := c.apiBaseURL + libraryPath + "?uris=" + strings.Join(uris, ",")
 	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, u, nil)
 	if err != nil {
 		return fmt.Errorf("creating remove-tracks request: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := doWithRetry(client, req)
+	resp, err := c.do(req)
 	if err != nil {
 		return fmt.Errorf("removing tracks: %w", err)
 	}
@@ -545,15 +575,19 @@ func RemoveTracks(ctx context.Context, accessToken string, ids []string) error {
 }
 
 // GetRecentlyPlayed fetches the user's recently played tracks (up to 50).
-func GetRecentlyPlayed(ctx context.Context, accessToken string) (*RecentlyPlayedResponse, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, recentlyPlayedURL+"?limit=50", nil)
+// If after is non-zero (unix milliseconds), only plays after that cursor are returned.
+func (c *Client) GetRecentlyPlayed(ctx context.Context, accessToken string, after int64) (*RecentlyPlayedResponse, error) {
+	u := c.apiBaseURL + recentlyPlayedPath + "?limit=50"
+	if after > 0 {
+		u += "&after=" + strconv.FormatInt(after, 10)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
 	if err != nil {
 		return nil, fmt.Errorf("creating recently-played request: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := doWithRetry(client, req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("fetching recently played: %w", err)
 	}
@@ -577,15 +611,14 @@ func GetRecentlyPlayed(ctx context.Context, accessToken string) (*RecentlyPlayed
 }
 
 // GetTrack fetches a single track by ID.
-func GetTrack(ctx context.Context, accessToken, trackID string) (*FullTrack, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, trackURL+trackID, nil)
+func (c *Client) GetTrack(ctx context.Context, accessToken, trackID string) (*FullTrack, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.apiBaseURL+trackPath+trackID, nil)
 	if err != nil {
 		return nil, fmt.Errorf("creating track request: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := doWithRetry(client, req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("fetching track: %w", err)
 	}
@@ -608,16 +641,97 @@ func GetTrack(ctx context.Context, accessToken, trackID string) (*FullTrack, err
 	return &result, nil
 }
 
+// MaxBatchIDs is the most IDs Spotify accepts in a single batch lookup.
+const MaxBatchIDs = 50
+
+// MaxBatchAlbumIDs is the most IDs Spotify accepts in a batch album lookup.
+const MaxBatchAlbumIDs = 20
+
+// GetTracks fetches up to MaxBatchIDs tracks in one call. Unknown IDs come back
+// as nil entries, in the same position as the requested ID.
+func (c *Client) GetTracks(ctx context.Context, accessToken string, trackIDs []string) ([]*FullTrack, error) {
+	if len(trackIDs) > MaxBatchIDs {
+		return nil, fmt.Errorf("too many track ids: %d (max %d)", len(trackIDs), MaxBatchIDs)
+	}
+	var result struct {
+		Tracks []*FullTrack `json:"tracks"`
+	}
+	if err := c.getBatch(ctx, accessToken, trackPath, "tracks", trackIDs, &result); err != nil {
+		return nil, err
+	}
+	return result.Tracks, nil
+}
+
+// GetArtists fetches up to MaxBatchIDs artists in one call. Unknown IDs come
+// back as nil entries, in the same position as the requested ID.
+func (c *Client) GetArtists(ctx context.Context, accessToken string, artistIDs []string) ([]*TopArtist, error) {
+	if len(artistIDs) > MaxBatchIDs {
+		return nil, fmt.Errorf("too many artist ids: %d (max %d)", len(artistIDs), MaxBatchIDs)
+	}
+	var result struct {
+		Artists []*TopArtist `json:"artists"`
+	}
+	if err := c.getBatch(ctx, accessToken, artistPath, "artists", artistIDs, &result); err != nil {
+		return nil, err
+	}
+	return result.Artists, nil
+}
+
+// GetAlbums fetches up to MaxBatchAlbumIDs albums in one call. Unknown IDs
+// come back as nil entries, in the same position as the requested ID.
+func (c *Client) GetAlbums(ctx context.Context, accessToken string, albumIDs []string) ([]*FullAlbum, error) {
+	if len(albumIDs) > MaxBatchAlbumIDs {
+		return nil, fmt.Errorf("too many album ids: %d (max %d)", len(albumIDs), MaxBatchAlbumIDs)
+	}
+	var result struct {
+		Albums []*FullAlbum `json:"albums"`
+	}
+	if err := c.getBatch(ctx, accessToken, albumPath, "albums", albumIDs, &result); err != nil {
+		return nil, err
+	}
+	return result.Albums, nil
+}
+
+// getBatch requests path?ids=... and decodes the response into out. what
+// names the entity in errors.
+func (c *Client) getBatch(ctx context.Context, accessToken, path, what string, ids []string, out interface{}) error {
+	u := strings.TrimSuffix(c.apiBaseURL+path, "/") + "?ids=" + strings.Join(ids, ",")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("creating %s request: %w", what, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("fetching %s: %w", what, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading %s response: %w", what, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("spotify %s error (status %d): %s", what, resp.StatusCode, string(body))
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("parsing %s response: %w", what, err)
+	}
+	return nil
+}
+
 // GetAlbum fetches a single album by ID.
-func GetAlbum(ctx context.Context, accessToken, albumID string) (*FullAlbum, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, albumURL+albumID, nil)
+func (c *Client) GetAlbum(ctx context.Context, accessToken, albumID string) (*FullAlbum, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.apiBaseURL+albumPath+albumID, nil)
 	if err != nil {
 		return nil, fmt.Errorf("creating album request: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := doWithRetry(client, req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("fetching album: %w", err)
 	}
@@ -641,15 +755,14 @@ func GetAlbum(ctx context.Context, accessToken, albumID string) (*FullAlbum, err
 }
 
 // GetAudioFeatures fetches audio features for a single track by ID.
-func GetAudioFeatures(ctx context.Context, accessToken, trackID string) (*AudioFeatures, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, audioFeaturesURL+trackID, nil)
+func (c *Client) GetAudioFeatures(ctx context.Context, accessToken, trackID string) (*AudioFeatures, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.apiBaseURL+audioFeaturesPath+trackID, nil)
 	if err != nil {
 		return nil, fmt.Errorf("creating audio-features request: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := doWithRetry(client, req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("fetching audio features: %w", err)
 	}
@@ -725,9 +838,9 @@ type SavedTrackItem struct {
 }
 
 type SavedTracksResponse struct {
-	Items  []SavedTrackItem `json:"items"`
-	Total  int              `json:"total"`
-	Next   *string          `json:"next"`
+	Items []SavedTrackItem `json:"items"`
+	Total int              `json:"total"`
+	Next  *string          `json:"next"`
 }
 
 type SavedAlbumItem struct {
@@ -755,13 +868,13 @@ type FollowedArtistsResponse struct {
 }
 
 // Search queries the Spotify search endpoint for tracks, albums, and/or artists.
-func Search(ctx context.Context, accessToken, query, types string, limit int) (*SearchResponse, error) {
+func (c *Client) Search(ctx context.Context, accessToken, query, types string, limit int) (*SearchResponse, error) {
 	params := url.Values{
 		"q":     {query},
 		"type":  {types},
 		"limit": {strconv.Itoa(limit)},
 	}
-	reqURL := searchURL + "?" + params.Encode()
+	reqURL := c.apiBaseURL + searchPath + "?" + params.Encode()
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
 	if err != nil {
@@ -769,8 +882,7 @@ func Search(ctx context.Context, accessToken, query, types string, limit int) (*
 	}
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := doWithRetry(client, req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("searching spotify: %w", err)
 	}
@@ -794,16 +906,15 @@ func Search(ctx context.Context, accessToken, query, types string, limit int) (*
 }
 
 // GetSavedTracks fetches the user's saved/liked tracks with pagination.
-func GetSavedTracks(ctx context.Context, accessToken string, limit, offset int) (*SavedTracksResponse, error) {
-	u := fmt.Sprintf("%s?limit=%d&offset=%d", savedTracksURL, limit, offset)
+func (c *Client) GetSavedTracks(ctx context.Context, accessToken string, limit, offset int) (*SavedTracksResponse, error) {
+	u := fmt.Sprintf("%s?limit=%d&offset=%d", c.apiBaseURL+savedTracksPath, limit, offset)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
 	if err != nil {
 		return nil, fmt.Errorf("creating saved-tracks request: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := doWithRetry(client, req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("fetching saved tracks: %w", err)
 	}
@@ -827,16 +938,15 @@ func GetSavedTracks(ctx context.Context, accessToken string, limit, offset int)
 }
 
 // GetSavedAlbums fetches the user's saved albums with pagination.
-func GetSavedAlbums(ctx context.Context, accessToken string, limit, offset int) (*SavedAlbumsResponse, error) {
-	u := fmt.Sprintf("%s?limit=%d&offset=%d", savedAlbumsURL, limit, offset)
+func (c *Client) GetSavedAlbums(ctx context.Context, accessToken string, limit, offset int) (*SavedAlbumsResponse, error) {
+	u := fmt.Sprintf("%s?limit=%d&offset=%d", c.apiBaseURL+savedAlbumsPath, limit, offset)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
 	if err != nil {
 		return nil, fmt.Errorf("creating saved-albums request: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := doWithRetry(client, req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("fetching saved albums: %w", err)
 	}
@@ -860,8 +970,8 @@ func GetSavedAlbums(ctx context.Context, accessToken string, limit, offset int)
 }
 
 // GetFollowedArtists fetches the user's followed artists with cursor-based pagination.
-func GetFollowedArtists(ctx context.Context, accessToken string, limit int, after string) (*FollowedArtistsResponse, error) {
-	u := fmt.Sprintf("%s?type=artist&limit=%d", followedArtistsURL, limit)
+func (c *Client) GetFollowedArtists(ctx context.Context, accessToken string, limit int, after string) (*FollowedArtistsResponse, error) {
+	u := fmt.Sprintf("%s?type=artist&limit=%d", c.apiBaseURL+followedArtistsPath, limit)
 	if after != "" {
 		u += "&after=" + after
 	}
@@ -871,8 +981,7 @@ func GetFollowedArtists(ctx context.Context, accessToken string, limit int, afte
 	}
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := doWithRetry(client, req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("fetching followed artists: %w", err)
 	}
@@ -0,0 +1,273 @@
+package spotify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"soundscraibe/internal/metrics"
+)
+
+// Outbound request policy. Every call through a Client waits for the
+// process-wide rate limiter, is refused while Spotify's circuit is open, and
+// is retried on 429 (any method) or on 5xx/timeouts (GET and HEAD only).
+const (
+	maxAttempts = 4
+
+	backoffBase = 500 * time.Millisecond
+	backoffMax  = 8 * time.Second
+
+	// maxRetryAfter is the longest Retry-After we wait out; beyond it the
+	// 429 is returned to the caller instead.
+	maxRetryAfter = 30 * time.Second
+
+	// breakerThreshold consecutive failures open the circuit for
+	// breakerCooldown, after which a single probe request is let through.
+	breakerThreshold = 5
+	breakerCooldown  = 30 * time.Second
+)
+
+// ErrUnavailable is returned (wrapped) without contacting Spotify while the
+// circuit breaker is open after repeated failures.
+var ErrUnavailable = errors.New("spotify unavailable")
+
+// send performs req with rate limiting, the circuit breaker and retries. The
+// waits between attempts end early if the request's context is cancelled.
+// After the last attempt, a 429 or 5xx response is returned as is for the
+// caller to turn into an error.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	br := c.breakers[req.URL.Host]
+	endpoint := endpointLabel(req.URL.Path)
+
+	for attempt := 1; ; attempt++ {
+		if err := c.limiter.wait(ctx); err != nil {
+			return nil, err
+		}
+		if br != nil && !br.allow() {
+			return nil, fmt.Errorf("%s %s: %w", req.Method, endpoint, ErrUnavailable)
+		}
+
+		resp, err := c.http.Do(req)
+		if br != nil {
+			switch {
+			case ctx.Err() != nil:
+				br.abort() // our cancellation says nothing about Spotify
+			default:
+				br.record(err == nil && resp.StatusCode < 500)
+			}
+		}
+
+		wait, reason, retry := retryDelay(req, resp, err, attempt)
+		if !retry || attempt == maxAttempts || ctx.Err() != nil || (br != nil && br.open()) {
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		slog.WarnContext(ctx, "spotify request failed, retrying",
+			"endpoint", endpoint, "reason", reason, "wait", wait.String(), "attempt", attempt)
+		metrics.SpotifyRetries.WithLabelValues(endpoint, reason).Inc()
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("rebuilding request body for retry: %w", err)
+			}
+			req.Body = body
+		}
+	}
+}
+
+// retryDelay decides whether a failed attempt is worth repeating, and after
+// how long. reason labels the retry in logs and metrics.
+func retryDelay(req *http.Request, resp *http.Response, err error, attempt int) (time.Duration, string, bool) {
+	idempotent := req.Method == http.MethodGet || req.Method == http.MethodHead
+
+	switch {
+	case err != nil:
+		var netErr net.Error
+		if idempotent && errors.As(err, &netErr) && netErr.Timeout() && req.Context().Err() == nil {
+			return backoff(attempt), "timeout", true
+		}
+		return 0, "", false
+	case resp.StatusCode == http.StatusTooManyRequests:
+		// Spotify didn't process the request, so any method can be retried.
+		wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"))
+		if !ok {
+			wait = backoff(attempt)
+		}
+		if wait > maxRetryAfter {
+			return 0, "", false
+		}
+		return wait, "rate_limited", true
+	case resp.StatusCode >= 500 && idempotent:
+		return backoff(attempt), "server_error", true
+	}
+	return 0, "", false
+}
+
+// backoff returns the exponential delay before retry number attempt, with
+// equal jitter so concurrent callers don't retry in lockstep.
+func backoff(attempt int) time.Duration {
+	d := min(backoffBase<<(attempt-1), backoffMax)
+	return d/2 + rand.N(d/2+1)
+}
+
+// parseRetryAfter reads a Retry-After header in either delay-seconds or
+// HTTP-date form.
+func parseRetryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Rate limiting
+// ---------------------------------------------------------------------------
+
+// tokenBucket spaces out requests to rate per second with bursts of up to
+// burst, across every user of the Client. A nil bucket doesn't limit.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	b := float64(max(burst, 1))
+	return &tokenBucket{rate: rate, burst: b, tokens: b, last: time.Now()}
+}
+
+// wait takes a token, blocking until one is available or ctx is done.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens-- // reserve, possibly going into debt
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mu.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+	if err := sleep(ctx, delay); err != nil {
+		b.mu.Lock()
+		b.tokens++ // give the reservation back
+		b.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// Circuit breaker
+// ---------------------------------------------------------------------------
+
+// breaker fails fast after breakerThreshold consecutive failures (transport
+// errors and 5xx) to one Spotify host. Once breakerCooldown has passed it
+// lets one probe through: success closes the circuit, failure re-opens it.
+type breaker struct {
+	host string
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// allow reports whether a request may be sent now.
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < breakerThreshold {
+		return true
+	}
+	if time.Now().Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// open reports whether the circuit is currently refusing requests.
+func (b *breaker) open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures >= breakerThreshold && time.Now().Before(b.openUntil)
+}
+
+// abort releases an allowed request that ended without an outcome, e.g.
+// because the caller gave up.
+func (b *breaker) abort() {
+	b.mu.Lock()
+	b.probing = false
+	b.mu.Unlock()
+}
+
+// record updates the breaker with the outcome of an allowed request.
+func (b *breaker) record(ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if ok {
+		if b.failures >= breakerThreshold {
+			slog.Info("spotify circuit closed", "host", b.host)
+		}
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.failures >= breakerThreshold {
+		if b.failures == breakerThreshold {
+			slog.Warn("spotify circuit opened", "host", b.host, "cooldown", breakerCooldown.String())
+		}
+		b.openUntil = time.Now().Add(breakerCooldown)
+	}
+}
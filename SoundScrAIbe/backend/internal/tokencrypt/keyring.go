@@ -0,0 +1,236 @@
+package tokencrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ---------------------------------------------------------------------------
+// Envelope encryption for OAuth tokens
+// ---------------------------------------------------------------------------
+//
+// Each write generates a fresh 256-bit data key, encrypts the tokens with it
+// (AES-GCM), and stores the data key wrapped by a master key from config. The
+// row records which master key wrapped it, so master keys can be rotated by
+// re-wrapping data keys without touching the tokens themselves.
+
+// SessionKeyID is the ID of the master key derived from SESSION_SECRET. It is
+// the primary key when TOKEN_ENCRYPTION_KEYS is unset, and stays available for
+// decryption afterwards so existing rows can be re-wrapped.
+const SessionKeyID = "session"
+
+// keySize is the AES-256 key length used for master and data keys.
+const keySize = 32
+
+// ErrUnknownKey is returned when a row was wrapped by a master key that isn't
+// configured.
+var ErrUnknownKey = errors.New("unknown token encryption key")
+
+// Keyring holds the configured master keys. The primary key wraps new data
+// keys; the others are only used to unwrap existing ones.
+type Keyring struct {
+	primary string
+	keys    map[string]cipher.AEAD
+}
+
+// Sealed is an encrypted token pair as stored on a users row. Empty tokens
+// stay empty so "has a refresh token" checks keep working in SQL.
+type Sealed struct {
+	KeyID        string // master key that wrapped DataKey
+	DataKey      string // base64 wrapped data key
+	AccessToken  string // base64 nonce+ciphertext, or ""
+	RefreshToken string
+}
+
+// NewKeyring builds a keyring from a TOKEN_ENCRYPTION_KEYS spec
+// ("id:base64key,id:base64key", first is primary) and the session secret.
+// With an empty spec the session-derived key is primary.
+func NewKeyring(spec, sessionSecret string) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]cipher.AEAD)}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("token key %q: want id:base64key", entry)
+		}
+		if id == SessionKeyID {
+			return nil, fmt.Errorf("token key id %q is reserved", id)
+		}
+		if _, dup := k.keys[id]; dup {
+			return nil, fmt.Errorf("token key id %q listed twice", id)
+		}
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("token key %q: decoding: %w", id, err)
+		}
+		if len(raw) != keySize {
+			return nil, fmt.Errorf("token key %q: want %d bytes, got %d", id, keySize, len(raw))
+		}
+		aead, err := newAEAD(raw)
+		if err != nil {
+			return nil, fmt.Errorf("token key %q: %w", id, err)
+		}
+		k.keys[id] = aead
+		if k.primary == "" {
+			k.primary = id
+		}
+	}
+
+	if sessionSecret != "" {
+		raw, err := hkdf.Key(sha256.New, []byte(sessionSecret), nil, "soundscraibe token encryption", keySize)
+		if err != nil {
+			return nil, fmt.Errorf("deriving session token key: %w", err)
+		}
+		aead, err := newAEAD(raw)
+		if err != nil {
+			return nil, fmt.Errorf("session token key: %w", err)
+		}
+		k.keys[SessionKeyID] = aead
+		if k.primary == "" {
+			k.primary = SessionKeyID
+		}
+	}
+
+	if k.primary == "" {
+		return nil, errors.New("no token encryption key configured (set TOKEN_ENCRYPTION_KEYS or SESSION_SECRET)")
+	}
+	return k, nil
+}
+
+// PrimaryID returns the ID of the key new data keys are wrapped with.
+func (k *Keyring) PrimaryID() string {
+	return k.primary
+}
+
+// Seal encrypts a token pair under a new data key wrapped by the primary key.
+func (k *Keyring) Seal(accessToken, refreshToken string) (Sealed, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return Sealed{}, fmt.Errorf("generating data key: %w", err)
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return Sealed{}, err
+	}
+
+	wrapped, err := seal(k.keys[k.primary], dataKey, []byte("data_key:"+k.primary))
+	if err != nil {
+		return Sealed{}, fmt.Errorf("wrapping data key: %w", err)
+	}
+	s := Sealed{KeyID: k.primary, DataKey: wrapped}
+	if s.AccessToken, err = sealToken(aead, accessToken, "access_token"); err != nil {
+		return Sealed{}, err
+	}
+	if s.RefreshToken, err = sealToken(aead, refreshToken, "refresh_token"); err != nil {
+		return Sealed{}, err
+	}
+	return s, nil
+}
+
+// Open decrypts a sealed token pair.
+func (k *Keyring) Open(s Sealed) (accessToken, refreshToken string, err error) {
+	dataKey, err := k.unwrap(s.KeyID, s.DataKey)
+	if err != nil {
+		return "", "", err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return "", "", err
+	}
+	if accessToken, err = openToken(aead, s.AccessToken, "access_token"); err != nil {
+		return "", "", err
+	}
+	if refreshToken, err = openToken(aead, s.RefreshToken, "refresh_token"); err != nil {
+		return "", "", err
+	}
+	return accessToken, refreshToken, nil
+}
+
+// Rewrap re-wraps a data key with the primary key, leaving the tokens it
+// protects untouched. It returns the new key ID and wrapped data key.
+func (k *Keyring) Rewrap(keyID, wrappedDataKey string) (string, string, error) {
+	dataKey, err := k.unwrap(keyID, wrappedDataKey)
+	if err != nil {
+		return "", "", err
+	}
+	wrapped, err := seal(k.keys[k.primary], dataKey, []byte("data_key:"+k.primary))
+	if err != nil {
+		return "", "", fmt.Errorf("wrapping data key: %w", err)
+	}
+	return k.primary, wrapped, nil
+}
+
+func (k *Keyring) unwrap(keyID, wrappedDataKey string) ([]byte, error) {
+	master, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, keyID)
+	}
+	dataKey, err := open(master, wrappedDataKey, []byte("data_key:"+keyID))
+	if err != nil {
+		return nil, fmt.Errorf("unwrapping data key: %w", err)
+	}
+	return dataKey, nil
+}
+
+func sealToken(aead cipher.AEAD, token, field string) (string, error) {
+	if token == "" {
+		return "", nil
+	}
+	sealed, err := seal(aead, []byte(token), []byte(field))
+	if err != nil {
+		return "", fmt.Errorf("encrypting %s: %w", field, err)
+	}
+	return sealed, nil
+}
+
+func openToken(aead cipher.AEAD, sealed, field string) (string, error) {
+	if sealed == "" {
+		return "", nil
+	}
+	token, err := open(aead, sealed, []byte(field))
+	if err != nil {
+		return "", fmt.Errorf("decrypting %s: %w", field, err)
+	}
+	return string(token), nil
+}
+
+// seal returns base64(nonce || ciphertext). additionalData binds the value to
+// where it's stored, so ciphertexts can't be swapped between fields.
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) (string, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	out := aead.Seal(nonce, nonce, plaintext, additionalData)
+	return base64.StdEncoding.EncodeToString(out), nil
+}
+
+func open(aead cipher.AEAD, encoded string, additionalData []byte) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	return aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], additionalData)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
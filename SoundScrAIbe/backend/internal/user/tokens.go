@@ -0,0 +1,135 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"soundscraibe/internal/tokencrypt"
+)
+
+// RotationSummary reports what RotateTokenKeys did.
+type RotationSummary struct {
+	Encrypted int // plaintext rows encrypted
+	Rewrapped int // rows moved from an older master key to the primary
+	Skipped   int // rows changed concurrently (e.g. a token refresh); already current
+}
+
+// tokenRow is the stored, possibly encrypted token state of one user.
+type tokenRow struct {
+	id     int64
+	sealed tokencrypt.Sealed
+}
+
+// RotateTokenKeys brings every user's tokens under the keyring's primary key:
+// plaintext rows from before token encryption are encrypted, and rows wrapped
+// by an older master key get their data key re-wrapped (the token ciphertext
+// is left as is). Each row is updated only if it hasn't changed since it was
+// read, so it's safe to run while the server is up.
+func RotateTokenKeys(ctx context.Context, db *sql.DB, keys *tokencrypt.Keyring) (RotationSummary, error) {
+	var summary RotationSummary
+
+	rows, err := loadTokenRows(ctx, db, `WHERE token_key_id != $1`, keys.PrimaryID())
+	if err != nil {
+		return summary, err
+	}
+
+	for _, r := range rows {
+		var next tokencrypt.Sealed
+		if r.sealed.KeyID == "" {
+			next, err = keys.Seal(r.sealed.AccessToken, r.sealed.RefreshToken)
+			if err != nil {
+				return summary, fmt.Errorf("encrypting tokens for user %d: %w", r.id, err)
+			}
+		} else {
+			next = r.sealed
+			next.KeyID, next.DataKey, err = keys.Rewrap(r.sealed.KeyID, r.sealed.DataKey)
+			if err != nil {
+				return summary, fmt.Errorf("re-wrapping tokens for user %d: %w", r.id, err)
+			}
+		}
+
+		updated, err := replaceTokens(ctx, db, r, next)
+		if err != nil {
+			return summary, err
+		}
+		switch {
+		case !updated:
+			summary.Skipped++
+		case r.sealed.KeyID == "":
+			summary.Encrypted++
+		default:
+			summary.Rewrapped++
+		}
+	}
+	return summary, nil
+}
+
+// DecryptTokens writes every user's tokens back as plaintext. It exists to
+// roll back migration 000015; the server encrypts them again on next write.
+func DecryptTokens(ctx context.Context, db *sql.DB, keys *tokencrypt.Keyring) (int, error) {
+	rows, err := loadTokenRows(ctx, db, `WHERE token_key_id != ''`)
+	if err != nil {
+		return 0, err
+	}
+
+	decrypted := 0
+	for _, r := range rows {
+		access, refresh, err := keys.Open(r.sealed)
+		if err != nil {
+			return decrypted, fmt.Errorf("decrypting tokens for user %d: %w", r.id, err)
+		}
+		updated, err := replaceTokens(ctx, db, r, tokencrypt.Sealed{AccessToken: access, RefreshToken: refresh})
+		if err != nil {
+			return decrypted, err
+		}
+		if updated {
+			decrypted++
+		}
+	}
+	return decrypted, nil
+}
+
+func loadTokenRows(ctx context.Context, db *sql.DB, where string, args ...interface{}) ([]tokenRow, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, access_token, refresh_token, token_key_id, token_data_key
+		FROM users `+where+`
+		ORDER BY id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing user tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var out []tokenRow
+	for rows.Next() {
+		var r tokenRow
+		if err := rows.Scan(&r.id, &r.sealed.AccessToken, &r.sealed.RefreshToken, &r.sealed.KeyID, &r.sealed.DataKey); err != nil {
+			return nil, fmt.Errorf("scanning user tokens: %w", err)
+		}
+		out = append(out, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating user tokens: %w", err)
+	}
+	return out, nil
+}
+
+// replaceTokens swaps a row's token columns from r's values to next, unless
+// the row changed in between. Reports whether it was updated.
+func replaceTokens(ctx context.Context, db *sql.DB, r tokenRow, next tokencrypt.Sealed) (bool, error) {
+	res, err := db.ExecContext(ctx, `
+		UPDATE users
+		SET access_token = $1, refresh_token = $2, token_key_id = $3, token_data_key = $4
+		WHERE id = $5 AND access_token = $6 AND refresh_token = $7 AND token_key_id = $8 AND token_data_key = $9`,
+		next.AccessToken, next.RefreshToken, next.KeyID, next.DataKey,
+		r.id, r.sealed.AccessToken, r.sealed.RefreshToken, r.sealed.KeyID, r.sealed.DataKey,
+	)
+	if err != nil {
+		return false, fmt.Errorf("updating tokens for user %d: %w", r.id, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("updating tokens for user %d: %w", r.id, err)
+	}
+	return n == 1, nil
+}
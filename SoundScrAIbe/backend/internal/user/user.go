@@ -4,7 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
 	"time"
+
+	"soundscraibe/internal/tokencrypt"
 )
 
 type User struct {
@@ -19,16 +22,24 @@ type User struct {
 	AccessToken   string
 	RefreshToken  string
 	TokenExpiry   time.Time
+	NeedsReauth   bool   // Spotify rejected the refresh token; the user must log in again
+	Timezone      string // IANA name stats are computed in, e.g. "Europe/Sofia"
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 }
 
-// Upsert creates or updates a user by spotify_id. Returns the user's ID.
-func Upsert(ctx context.Context, db *sql.DB, u *User) (int64, error) {
+// Upsert creates or updates a user by spotify_id, encrypting their tokens and
+// clearing any re-auth flag. Returns the user's ID.
+func Upsert(ctx context.Context, db *sql.DB, keys *tokencrypt.Keyring, u *User) (int64, error) {
+	sealed, err := keys.Seal(u.AccessToken, u.RefreshToken)
+	if err != nil {
+		return 0, fmt.Errorf("encrypting tokens: %w", err)
+	}
+
 	var id int64
-	err := db.QueryRowContext(ctx, `
-		INSERT INTO users (spotify_id, display_name, avatar_url, email, country, product, follower_count, access_token, refresh_token, token_expiry)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	err = db.QueryRowContext(ctx, `
+		INSERT INTO users (spotify_id, display_name, avatar_url, email, country, product, follower_count, access_token, refresh_token, token_key_id, token_data_key, token_expiry)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		ON CONFLICT (spotify_id) DO UPDATE SET
 			display_name   = EXCLUDED.display_name,
 			avatar_url     = EXCLUDED.avatar_url,
@@ -38,10 +49,13 @@ func Upsert(ctx context.Context, db *sql.DB, u *User) (int64, error) {
 			follower_count = EXCLUDED.follower_count,
 			access_token   = EXCLUDED.access_token,
 			refresh_token  = EXCLUDED.refresh_token,
+			token_key_id   = EXCLUDED.token_key_id,
+			token_data_key = EXCLUDED.token_data_key,
 			token_expiry   = EXCLUDED.token_expiry,
+			needs_reauth   = false,
 			updated_at     = now()
 		RETURNING id`,
-		u.SpotifyID, u.DisplayName, u.AvatarURL, u.Email, u.Country, u.Product, u.FollowerCount, u.AccessToken, u.RefreshToken, u.TokenExpiry,
+		u.SpotifyID, u.DisplayName, u.AvatarURL, u.Email, u.Country, u.Product, u.FollowerCount, sealed.AccessToken, sealed.RefreshToken, sealed.KeyID, sealed.DataKey, u.TokenExpiry,
 	).Scan(&id)
 	if err != nil {
 		return 0, fmt.Errorf("upserting user: %w", err)
@@ -49,28 +63,136 @@ func Upsert(ctx context.Context, db *sql.DB, u *User) (int64, error) {
 	return id, nil
 }
 
-// GetByID retrieves a user by primary key.
-func GetByID(ctx context.Context, db *sql.DB, id int64) (*User, error) {
-	u := &User{}
-	err := db.QueryRowContext(ctx, `
-		SELECT id, spotify_id, display_name, avatar_url, email, country, product, follower_count, access_token, refresh_token, token_expiry, created_at, updated_at
+// GetByID retrieves a user by primary key, decrypting their tokens.
+func GetByID(ctx context.Context, db *sql.DB, keys *tokencrypt.Keyring, id int64) (*User, error) {
+	return scanUser(keys, db.QueryRowContext(ctx, `
+		SELECT id, spotify_id, display_name, avatar_url, email, country, product, follower_count, access_token, refresh_token, token_key_id, token_data_key, token_expiry, needs_reauth, timezone, created_at, updated_at
 		FROM users WHERE id = $1`, id,
-	).Scan(&u.ID, &u.SpotifyID, &u.DisplayName, &u.AvatarURL, &u.Email, &u.Country, &u.Product, &u.FollowerCount, &u.AccessToken, &u.RefreshToken, &u.TokenExpiry, &u.CreatedAt, &u.UpdatedAt)
+	), "getting user by id")
+}
+
+// MarkNeedsReauth flags the user as having to log in again because Spotify
+// rejected their refresh token.
+func MarkNeedsReauth(ctx context.Context, db *sql.DB, userID int64) error {
+	_, err := db.ExecContext(ctx, `UPDATE users SET needs_reauth = true, updated_at = now() WHERE id = $1`, userID)
 	if err != nil {
-		return nil, fmt.Errorf("getting user by id: %w", err)
+		return fmt.Errorf("marking user %d for re-auth: %w", userID, err)
 	}
-	return u, nil
+	return nil
 }
 
-// UpdateTokens updates just the OAuth tokens for a user.
-func UpdateTokens(ctx context.Context, db *sql.DB, userID int64, accessToken, refreshToken string, expiry time.Time) error {
-	_, err := db.ExecContext(ctx, `
-		UPDATE users SET access_token = $1, refresh_token = $2, token_expiry = $3, updated_at = now()
-		WHERE id = $4`,
-		accessToken, refreshToken, expiry, userID,
+// SetTimezone stores the user's timezone, which must be a valid IANA name.
+func SetTimezone(ctx context.Context, db *sql.DB, userID int64, timezone string) error {
+	_, err := db.ExecContext(ctx, `UPDATE users SET timezone = $1, updated_at = now() WHERE id = $2`, timezone, userID)
+	if err != nil {
+		return fmt.Errorf("setting timezone for user %d: %w", userID, err)
+	}
+	return nil
+}
+
+// UpdateTokens encrypts and stores just the OAuth tokens for a user.
+func UpdateTokens(ctx context.Context, db *sql.DB, keys *tokencrypt.Keyring, userID int64, accessToken, refreshToken string, expiry time.Time) error {
+	sealed, err := keys.Seal(accessToken, refreshToken)
+	if err != nil {
+		return fmt.Errorf("encrypting tokens: %w", err)
+	}
+
+	_, err = db.ExecContext(ctx, `
+		UPDATE users SET access_token = $1, refresh_token = $2, token_key_id = $3, token_data_key = $4, token_expiry = $5, updated_at = now()
+		WHERE id = $6`,
+		sealed.AccessToken, sealed.RefreshToken, sealed.KeyID, sealed.DataKey, expiry, userID,
 	)
 	if err != nil {
 		return fmt.Errorf("updating tokens: %w", err)
 	}
 	return nil
 }
+
+// ListWithRefreshToken returns every user that has a usable Spotify refresh
+// token, i.e. every user the background sync can act on behalf of. Users
+// whose tokens can't be decrypted (e.g. wrapped by a master key that has
+// since been removed) are logged and left out rather than failing the list.
+func ListWithRefreshToken(ctx context.Context, db *sql.DB, keys *tokencrypt.Keyring) ([]*User, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, spotify_id, display_name, avatar_url, email, country, product, follower_count, access_token, refresh_token, token_key_id, token_data_key, token_expiry, needs_reauth, timezone, created_at, updated_at
+		FROM users WHERE refresh_token != '' AND NOT needs_reauth
+		ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("listing users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*User
+	for rows.Next() {
+		u, sealed, err := scanSealedUser(rows, "scanning user")
+		if err != nil {
+			return nil, err
+		}
+		if err := openTokens(keys, u, sealed); err != nil {
+			slog.ErrorContext(ctx, "skipping user with undecryptable spotify tokens",
+				"user_id", u.ID, "key_id", sealed.KeyID, "error", err)
+			continue
+		}
+		users = append(users, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating users: %w", err)
+	}
+	return users, nil
+}
+
+// GetBySpotifyID retrieves a user by their Spotify account ID, decrypting
+// their tokens.
+func GetBySpotifyID(ctx context.Context, db *sql.DB, keys *tokencrypt.Keyring, spotifyID string) (*User, error) {
+	return scanUser(keys, db.QueryRowContext(ctx, `
+		SELECT id, spotify_id, display_name, avatar_url, email, country, product, follower_count, access_token, refresh_token, token_key_id, token_data_key, token_expiry, needs_reauth, timezone, created_at, updated_at
+		FROM users WHERE spotify_id = $1`, spotifyID,
+	), "getting user by spotify id")
+}
+
+// rowScanner is the Scan method shared by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanUser scans a users row selected with the column list above and
+// decrypts its tokens. what prefixes errors.
+func scanUser(keys *tokencrypt.Keyring, row rowScanner, what string) (*User, error) {
+	u, sealed, err := scanSealedUser(row, what)
+	if err != nil {
+		return nil, err
+	}
+	if err := openTokens(keys, u, sealed); err != nil {
+		return nil, fmt.Errorf("%s: %w", what, err)
+	}
+	return u, nil
+}
+
+// scanSealedUser scans a users row selected with the column list above,
+// leaving its tokens sealed. what prefixes errors.
+func scanSealedUser(row rowScanner, what string) (*User, tokencrypt.Sealed, error) {
+	u := &User{}
+	var sealed tokencrypt.Sealed
+	err := row.Scan(&u.ID, &u.SpotifyID, &u.DisplayName, &u.AvatarURL, &u.Email, &u.Country, &u.Product, &u.FollowerCount,
+		&sealed.AccessToken, &sealed.RefreshToken, &sealed.KeyID, &sealed.DataKey, &u.TokenExpiry, &u.NeedsReauth, &u.Timezone, &u.CreatedAt, &u.UpdatedAt)
+	if err != nil {
+		return nil, sealed, fmt.Errorf("%s: %w", what, err)
+	}
+	return u, sealed, nil
+}
+
+// openTokens decrypts sealed into u's tokens.
+func openTokens(keys *tokencrypt.Keyring, u *User, sealed tokencrypt.Sealed) error {
+	// Rows written before token encryption have no key ID and hold plaintext
+	// until cmd/encrypt-tokens has run.
+	if sealed.KeyID == "" {
+		u.AccessToken, u.RefreshToken = sealed.AccessToken, sealed.RefreshToken
+		return nil
+	}
+	var err error
+	u.AccessToken, u.RefreshToken, err = keys.Open(sealed)
+	if err != nil {
+		return fmt.Errorf("decrypting tokens for user %d: %w", u.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+CREATE TABLE sync_state (
+    user_id        BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    last_cursor    BIGINT NOT NULL DEFAULT 0, -- unix ms of the newest synced play (Spotify "after" cursor)
+    last_synced_at TIMESTAMPTZ,
+    last_error     TEXT NOT NULL DEFAULT '',
+    last_error_at  TIMESTAMPTZ,
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Seed cursors from existing history so the first background sync doesn't re-walk old plays.
+INSERT INTO sync_state (user_id, last_cursor, last_synced_at)
+SELECT user_id, (EXTRACT(EPOCH FROM MAX(played_at)) * 1000)::bigint, MAX(created_at)
+FROM listening_history
+GROUP BY user_id;
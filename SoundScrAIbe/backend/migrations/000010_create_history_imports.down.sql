@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS history_imports;
+ALTER TABLE listening_history DROP COLUMN ms_played;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS track_name_cache;
+DROP INDEX IF EXISTS idx_lh_user_unresolved;
+ALTER TABLE listening_history DROP COLUMN IF EXISTS source;
@@ -0,0 +1,17 @@
+-- Where a play came from: 'spotify' (recently-played sync), 'spotify_export',
+-- 'lastfm', 'listenbrainz'. Plays that couldn't be matched to the Spotify
+-- catalog are stored with empty track_id/artist_id and keep their names.
+ALTER TABLE listening_history ADD COLUMN source TEXT NOT NULL DEFAULT 'spotify';
+
+-- Only streaming-history imports have filled in ms_played so far.
+UPDATE listening_history SET source = 'spotify_export' WHERE ms_played IS NOT NULL;
+
+CREATE INDEX idx_lh_user_unresolved ON listening_history (user_id, played_at DESC) WHERE track_id = '';
+
+-- Cache of artist/track name lookups against Spotify search, shared by all
+-- users. track_id is '' when the search found nothing.
+CREATE TABLE track_name_cache (
+    lookup_key  TEXT PRIMARY KEY, -- normalized "artist\x1ftrack"
+    track_id    TEXT NOT NULL DEFAULT '',
+    resolved_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
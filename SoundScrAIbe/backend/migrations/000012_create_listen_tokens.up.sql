@@ -0,0 +1,8 @@
+-- Per-user tokens for the ListenBrainz-compatible submission API. Only the
+-- SHA-256 of the token is stored; the token itself is shown once on creation.
+CREATE TABLE listen_tokens (
+    user_id      BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    token_hash   TEXT NOT NULL UNIQUE,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at TIMESTAMPTZ
+);
@@ -0,0 +1,17 @@
+CREATE TABLE recommendation_feedback (
+    recommendation_id BIGINT NOT NULL REFERENCES ai_recommendations(id) ON DELETE CASCADE,
+    item_index        INT NOT NULL,
+    user_id           BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    verdict           TEXT NOT NULL CHECK (verdict IN ('up', 'down', 'known', 'not_for_me')),
+    -- Snapshot of the recommended item, so the taste profile can use feedback
+    -- without unpacking results_json.
+    entity_type       TEXT NOT NULL,
+    spotify_id        TEXT NOT NULL DEFAULT '',
+    title             TEXT NOT NULL,
+    artist            TEXT NOT NULL DEFAULT '',
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (recommendation_id, item_index)
+);
+
+CREATE INDEX idx_rec_feedback_user ON recommendation_feedback (user_id, updated_at DESC);
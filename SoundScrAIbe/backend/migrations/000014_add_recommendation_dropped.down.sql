@@ -0,0 +1 @@
+ALTER TABLE ai_recommendations DROP COLUMN IF EXISTS dropped_json;
@@ -0,0 +1,2 @@
+-- Recommendations removed because the user already knew them, with the reason.
+ALTER TABLE ai_recommendations ADD COLUMN dropped_json JSONB NOT NULL DEFAULT '[]';
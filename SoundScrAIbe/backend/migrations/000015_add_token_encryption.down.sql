@@ -0,0 +1,5 @@
+-- Run `go run ./cmd/encrypt-tokens -decrypt` first, or encrypted tokens become
+-- unusable and every user has to log in again.
+ALTER TABLE users
+    DROP COLUMN IF EXISTS token_data_key,
+    DROP COLUMN IF EXISTS token_key_id;
@@ -0,0 +1,7 @@
+-- Spotify tokens are stored encrypted (envelope encryption, see internal/tokencrypt).
+-- token_key_id names the master key that wrapped token_data_key; '' marks a
+-- row still holding plaintext tokens from before this migration, until
+-- cmd/encrypt-tokens (or the next login/refresh) encrypts it.
+ALTER TABLE users
+    ADD COLUMN token_key_id   TEXT NOT NULL DEFAULT '',
+    ADD COLUMN token_data_key TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,9 @@
+-- Session metadata for the "active sessions" list. id identifies a session
+-- in the API without exposing its token.
+ALTER TABLE sessions
+    ADD COLUMN id           BIGSERIAL UNIQUE,
+    ADD COLUMN user_agent   TEXT NOT NULL DEFAULT '',
+    ADD COLUMN ip           TEXT NOT NULL DEFAULT '',
+    ADD COLUMN last_seen_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+UPDATE sessions SET last_seen_at = created_at;
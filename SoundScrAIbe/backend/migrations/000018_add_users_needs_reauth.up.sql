@@ -0,0 +1,3 @@
+-- Set when Spotify rejects the user's refresh token; cleared on the next login.
+-- Flagged users are skipped by the background sync.
+ALTER TABLE users ADD COLUMN needs_reauth BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS spotify_catalog_cache;
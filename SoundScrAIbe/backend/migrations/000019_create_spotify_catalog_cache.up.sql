@@ -0,0 +1,10 @@
+-- Shared cache of Spotify catalog lookups (artists, albums, tracks, audio
+-- features) as returned by the Web API. Rows older than the cache TTL are
+-- ignored on read and overwritten on the next fetch.
+CREATE TABLE spotify_catalog_cache (
+    kind       TEXT NOT NULL,
+    id         TEXT NOT NULL,
+    body       JSONB NOT NULL,
+    fetched_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (kind, id)
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_entity_metadata_refreshed_at;
+ALTER TABLE entity_metadata DROP COLUMN IF EXISTS refreshed_at;
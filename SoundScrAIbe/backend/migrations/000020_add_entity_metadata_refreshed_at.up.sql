@@ -0,0 +1,7 @@
+-- When the row was last fetched from Spotify by the server. NULL marks rows
+-- written from client-supplied names before this column existed, and
+-- placeholders stored while Spotify was unreachable; the metadata refresher
+-- fetches those first.
+ALTER TABLE entity_metadata ADD COLUMN refreshed_at TIMESTAMPTZ;
+
+CREATE INDEX idx_entity_metadata_refreshed_at ON entity_metadata (entity_type, refreshed_at NULLS FIRST);
@@ -0,0 +1,33 @@
+CREATE TABLE listening_history (
+    id               BIGSERIAL PRIMARY KEY,
+    user_id          BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    track_id         TEXT NOT NULL,
+    track_name       TEXT NOT NULL,
+    artist_id        TEXT NOT NULL,
+    artist_name      TEXT NOT NULL,
+    duration_ms      INTEGER NOT NULL,
+    played_at        TIMESTAMPTZ NOT NULL,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    album_id         TEXT NOT NULL DEFAULT '',
+    album_name       TEXT NOT NULL DEFAULT '',
+    ms_played        INTEGER,
+    source           TEXT NOT NULL DEFAULT 'spotify',
+
+    CONSTRAINT uq_listening_history UNIQUE (user_id, track_id, artist_id, played_at)
+);
+
+CREATE INDEX idx_lh_user_played ON listening_history (user_id, played_at DESC);
+CREATE INDEX idx_lh_user_artist ON listening_history (user_id, artist_id);
+CREATE INDEX idx_lh_user_album ON listening_history (user_id, album_id);
+CREATE INDEX idx_lh_user_track ON listening_history (user_id, track_id);
+CREATE INDEX idx_lh_user_unresolved ON listening_history (user_id, played_at DESC) WHERE track_id = '';
+
+INSERT INTO listening_history (user_id, track_id, track_name, artist_id, artist_name, duration_ms, played_at, created_at, album_id, album_name, ms_played, source)
+SELECT p.user_id, p.track_id, p.track_name, pa.artist_id, pa.artist_name, p.duration_ms, p.played_at, p.created_at, p.album_id, p.album_name, p.ms_played, p.source
+FROM plays p
+JOIN play_artists pa ON pa.play_id = p.id
+ORDER BY p.id, pa.position
+ON CONFLICT DO NOTHING;
+
+DROP TABLE IF EXISTS play_artists;
+DROP TABLE IF EXISTS plays;
@@ -0,0 +1,53 @@
+-- One row per play. listening_history stored one row per artist per play, so
+-- every query had to collapse multi-artist plays with DISTINCT ON.
+-- Unresolved plays (no Spotify match) have an empty track_id and keep the
+-- names from their source.
+CREATE TABLE plays (
+    id          BIGSERIAL PRIMARY KEY,
+    user_id     BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    source      TEXT NOT NULL DEFAULT 'spotify',
+    track_id    TEXT NOT NULL,
+    track_name  TEXT NOT NULL,
+    album_id    TEXT NOT NULL DEFAULT '',
+    album_name  TEXT NOT NULL DEFAULT '',
+    duration_ms INTEGER NOT NULL,
+    ms_played   INTEGER,
+    played_at   TIMESTAMPTZ NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    CONSTRAINT uq_plays UNIQUE (user_id, track_id, played_at)
+);
+
+CREATE INDEX idx_plays_user_played ON plays (user_id, played_at DESC);
+CREATE INDEX idx_plays_user_track ON plays (user_id, track_id);
+CREATE INDEX idx_plays_user_album ON plays (user_id, album_id);
+CREATE INDEX idx_plays_user_unresolved ON plays (user_id, played_at DESC) WHERE track_id = '';
+
+-- The artists credited on a play, in Spotify's order (position 0 is the
+-- primary artist). Unresolved plays have one row with an empty artist_id.
+CREATE TABLE play_artists (
+    play_id     BIGINT NOT NULL REFERENCES plays(id) ON DELETE CASCADE,
+    position    SMALLINT NOT NULL,
+    artist_id   TEXT NOT NULL,
+    artist_name TEXT NOT NULL,
+
+    PRIMARY KEY (play_id, position)
+);
+
+CREATE INDEX idx_play_artists_artist ON play_artists (artist_id, play_id);
+
+INSERT INTO plays (user_id, source, track_id, track_name, album_id, album_name, duration_ms, ms_played, played_at, created_at)
+SELECT DISTINCT ON (user_id, track_id, played_at)
+       user_id, source, track_id, track_name, album_id, album_name, duration_ms, ms_played, played_at, created_at
+FROM listening_history
+ORDER BY user_id, track_id, played_at, id;
+
+-- Rows of a play were written in artist order, so id order gives the position.
+INSERT INTO play_artists (play_id, position, artist_id, artist_name)
+SELECT p.id,
+       ROW_NUMBER() OVER (PARTITION BY p.id ORDER BY lh.id) - 1,
+       lh.artist_id, lh.artist_name
+FROM listening_history lh
+JOIN plays p ON p.user_id = lh.user_id AND p.track_id = lh.track_id AND p.played_at = lh.played_at;
+
+DROP TABLE listening_history;
@@ -0,0 +1,2 @@
+-- IANA timezone stats are computed in (day boundaries, hour buckets).
+ALTER TABLE users ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC';
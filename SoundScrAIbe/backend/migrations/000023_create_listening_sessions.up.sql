@@ -0,0 +1,18 @@
+-- Plays grouped into listening sessions: consecutive plays with less than
+-- history.SessionGap of silence between them. Derived from plays and rebuilt
+-- by the application (history.RebuildSessions) whenever plays are added;
+-- existing history is backfilled on each user's next sync.
+CREATE TABLE listening_sessions (
+    id                   BIGSERIAL PRIMARY KEY,
+    user_id              BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    started_at           TIMESTAMPTZ NOT NULL,
+    ended_at             TIMESTAMPTZ NOT NULL,
+    track_count          INTEGER NOT NULL,
+    listened_ms          BIGINT NOT NULL,
+    dominant_artist_id   TEXT NOT NULL DEFAULT '',
+    dominant_artist_name TEXT NOT NULL DEFAULT '',
+    dominant_genre       TEXT NOT NULL DEFAULT '', -- empty when no artist's genres are known
+    created_at           TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    CONSTRAINT uq_listening_sessions UNIQUE (user_id, started_at)
+);
@@ -0,0 +1 @@
+ALTER TABLE plays DROP COLUMN IF EXISTS completion;
@@ -0,0 +1,23 @@
+-- Estimated share of the track that was played, 0 to 1. Exports give it
+-- directly (ms_played / duration_ms); for synced plays it is capped by the
+-- time since the previous play ended (played_at is when a play ended), so
+-- a skip shows up as a short gap. NULL while not yet computed, and for plays
+-- without a known duration. Maintained by history.UpdateCompletion.
+ALTER TABLE plays ADD COLUMN completion REAL;
+
+UPDATE plays p SET completion = c.completion
+FROM (
+    SELECT id,
+        CASE
+            WHEN duration_ms <= 0 THEN NULL
+            WHEN ms_played IS NOT NULL THEN LEAST(1, ms_played::real / duration_ms)
+            WHEN prev_played_at IS NULL THEN 1
+            ELSE LEAST(1, GREATEST(0, EXTRACT(EPOCH FROM played_at - prev_played_at) * 1000 / duration_ms))
+        END::real AS completion
+    FROM (
+        SELECT id, duration_ms, ms_played, played_at,
+            LAG(played_at) OVER (PARTITION BY user_id ORDER BY played_at, id) AS prev_played_at
+        FROM plays
+    ) ordered
+) c
+WHERE p.id = c.id;
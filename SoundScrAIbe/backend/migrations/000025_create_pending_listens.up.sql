@@ -0,0 +1,18 @@
+-- Listens accepted by /1/submit-listens that haven't been matched to Spotify
+-- and written to plays yet. They're stored before the submission is
+-- acknowledged and deleted once imported; anything left behind by a restart,
+-- a database error or a user whose Spotify token is dead is retried after
+-- the user's next background sync.
+CREATE TABLE pending_listens (
+    id          BIGSERIAL PRIMARY KEY,
+    user_id     BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    artist_name TEXT NOT NULL,
+    track_name  TEXT NOT NULL,
+    album_name  TEXT NOT NULL DEFAULT '',
+    listened_at TIMESTAMPTZ NOT NULL,          -- when the listen started
+    duration_ms INTEGER NOT NULL DEFAULT 0,    -- 0 when the client didn't say
+    spotify_id  TEXT NOT NULL DEFAULT '',      -- track ID, when the client sent one
+    received_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_pending_listens_user ON pending_listens (user_id, id);
@@ -0,0 +1 @@
+ALTER TABLE pending_listens DROP COLUMN IF EXISTS claimed_until;
@@ -0,0 +1,5 @@
+-- Until when an import has claimed the listen. Claims are taken and released
+-- in short statements so the Spotify lookups in between don't hold row locks
+-- or a transaction open; a claim left behind by a crash simply expires. NULL
+-- while unclaimed.
+ALTER TABLE pending_listens ADD COLUMN claimed_until TIMESTAMPTZ;
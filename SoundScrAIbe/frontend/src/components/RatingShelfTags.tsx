@@ -14,8 +14,6 @@ import {
 interface RatingShelfTagsProps {
   entityType: 'track' | 'album' | 'artist'
   entityId: string
-  entityName: string
-  entityImageUrl: string
   initialRating: number | null
   initialShelf: string | null
   initialTags: string[]
@@ -24,8 +22,6 @@ interface RatingShelfTagsProps {
 export default function RatingShelfTags({
   entityType,
   entityId,
-  entityName,
-  entityImageUrl,
   initialRating,
   initialShelf,
   initialTags,
@@ -54,7 +50,7 @@ export default function RatingShelfTags({
       if (score === null) {
         await deleteRating(entityType, entityId)
       } else {
-        await setRating(entityType, entityId, score, entityName, entityImageUrl)
+        await setRating(entityType, entityId, score)
       }
     } catch {
       setRatingState(prev)
@@ -68,7 +64,7 @@ export default function RatingShelfTags({
       if (status === null) {
         await deleteShelf(entityType, entityId)
       } else {
-        await setShelf(entityType, entityId, status, entityName, entityImageUrl)
+        await setShelf(entityType, entityId, status)
       }
     } catch {
       setShelfState(prev)
@@ -79,7 +75,7 @@ export default function RatingShelfTags({
     const prev = tags
     setTagsState(newTags)
     try {
-      await setTags(entityType, entityId, newTags, entityName, entityImageUrl)
+      await setTags(entityType, entityId, newTags)
     } catch {
       setTagsState(prev)
     }
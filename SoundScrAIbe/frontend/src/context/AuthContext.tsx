@@ -1,10 +1,11 @@
 import { createContext, useContext, useState, useEffect, useCallback } from 'react'
-import { getMe, logout as apiLogout, type UserProfile } from '../lib/api'
+import { getMe, logout as apiLogout, REAUTH_REQUIRED_CODE, type UserProfile } from '../lib/api'
 
 interface AuthState {
   isLoggedIn: boolean
   user: UserProfile | null
   loading: boolean
+  reauthRequired: boolean
   checkAuth: () => Promise<void>
   logout: () => Promise<void>
 }
@@ -14,6 +15,27 @@ const AuthContext = createContext<AuthState | null>(null)
 export function AuthProvider({ children }: { children: React.ReactNode }) {
   const [user, setUser] = useState<UserProfile | null>(null)
   const [loading, setLoading] = useState(true)
+  const [reauthRequired, setReauthRequired] = useState(false)
+
+  // Any request can be the one to find out the Spotify connection was
+  // revoked; sign the user out so they land back on the login page.
+  useEffect(() => {
+    const originalFetch = window.fetch
+    window.fetch = async (...args) => {
+      const res = await originalFetch(...args)
+      if (res.status === 401) {
+        const data = await res.clone().json().catch(() => null)
+        if (data?.code === REAUTH_REQUIRED_CODE) {
+          setReauthRequired(true)
+          setUser(null)
+        }
+      }
+      return res
+    }
+    return () => {
+      window.fetch = originalFetch
+    }
+  }, [])
 
   const checkAuth = useCallback(async () => {
     try {
@@ -36,7 +58,7 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
   }, [checkAuth])
 
   return (
-    <AuthContext.Provider value={{ isLoggedIn: !!user, user, loading, checkAuth, logout }}>
+    <AuthContext.Provider value={{ isLoggedIn: !!user, user, loading, reauthRequired, checkAuth, logout }}>
       {children}
     </AuthContext.Provider>
   )
@@ -1,4 +1,5 @@
 export async function getSpotifyAuthConfig(): Promise<{
+  authorize_url?: string
   client_id: string
   redirect_uri: string
   scope: string
@@ -29,8 +30,13 @@ export interface UserProfile {
   country: string
   product: string
   follower_count: number
+  timezone: string
 }
 
+// Error code the backend returns (with a 401) when Spotify has rejected the
+// stored refresh token and the user has to sign in again.
+export const REAUTH_REQUIRED_CODE = 'spotify_reauth_required'
+
 export async function getMe(): Promise<UserProfile | null> {
   const res = await fetch('/api/me')
   if (res.status === 401) return null
@@ -38,6 +44,20 @@ export async function getMe(): Promise<UserProfile | null> {
   return res.json()
 }
 
+// Sets the IANA timezone (e.g. "Europe/Sofia") stats are computed in.
+export async function setTimezone(timezone: string): Promise<{ timezone: string }> {
+  const res = await fetch('/api/me/timezone', {
+    method: 'PUT',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ timezone }),
+  })
+  if (!res.ok) {
+    const data = await res.json().catch(() => null)
+    throw new Error(data?.error || 'Failed to save timezone')
+  }
+  return res.json()
+}
+
 export async function logout(): Promise<void> {
   await fetch('/api/auth/logout', { method: 'POST' })
 }
@@ -123,6 +143,11 @@ export interface ListeningStats {
   play_count: number
   first_played?: string
   last_played?: string
+  // Tracks only, once any play's completion is known.
+  avg_completion?: number // 0-1
+  skip_rate?: number // 0-1
+  skips?: number
+  real_listens?: number
 }
 
 export interface TrackDetail {
@@ -211,13 +236,12 @@ export async function getArtist(id: string): Promise<ArtistDetail> {
 }
 
 export async function setRating(
-  entityType: string, entityId: string, score: number,
-  name: string, imageUrl: string
+  entityType: string, entityId: string, score: number
 ): Promise<void> {
   const res = await fetch(`/api/ratings/${entityType}/${entityId}`, {
     method: 'PUT',
     headers: { 'Content-Type': 'application/json' },
-    body: JSON.stringify({ score, name, image_url: imageUrl }),
+    body: JSON.stringify({ score }),
   })
   if (!res.ok) throw new Error('Failed to set rating')
 }
@@ -228,13 +252,12 @@ export async function deleteRating(entityType: string, entityId: string): Promis
 }
 
 export async function setShelf(
-  entityType: string, entityId: string, status: string,
-  name: string, imageUrl: string
+  entityType: string, entityId: string, status: string
 ): Promise<void> {
   const res = await fetch(`/api/shelves/${entityType}/${entityId}`, {
     method: 'PUT',
     headers: { 'Content-Type': 'application/json' },
-    body: JSON.stringify({ status, name, image_url: imageUrl }),
+    body: JSON.stringify({ status }),
   })
   if (!res.ok) throw new Error('Failed to set shelf')
 }
@@ -245,13 +268,12 @@ export async function deleteShelf(entityType: string, entityId: string): Promise
 }
 
 export async function setTags(
-  entityType: string, entityId: string, tags: string[],
-  name: string, imageUrl: string
+  entityType: string, entityId: string, tags: string[]
 ): Promise<void> {
   const res = await fetch(`/api/tags/${entityType}/${entityId}`, {
     method: 'PUT',
     headers: { 'Content-Type': 'application/json' },
-    body: JSON.stringify({ tags, name, image_url: imageUrl }),
+    body: JSON.stringify({ tags }),
   })
   if (!res.ok) throw new Error('Failed to set tags')
 }
@@ -263,6 +285,17 @@ export async function getUserTags(): Promise<{ id: number; name: string }[]> {
   return data.tags
 }
 
+// Canonical metadata the server stores for rated/shelved/tagged entities.
+// Fields that don't apply to the entity type are omitted.
+export interface EntityExtra {
+  artist_name?: string
+  artists?: { id: string; name: string }[]
+  album?: { id: string; name: string }
+  release_date?: string
+  genres?: string[]
+  popularity?: number
+}
+
 export interface LibraryItem {
   entity_type: string
   entity_id: string
@@ -271,7 +304,7 @@ export interface LibraryItem {
   rating: number | null
   shelf: string | null
   tags: string[]
-  extra: Record<string, string>
+  extra: EntityExtra
 }
 
 export interface LibraryResponse {
@@ -377,7 +410,29 @@ export async function getFavorites(params: {
 
 // --- Stats types ---
 
-export type StatsPeriod = 'day' | 'week' | 'month' | 'year' | 'lifetime'
+export type StatsPeriod =
+  | 'day'
+  | 'week'
+  | 'month'
+  | 'year'
+  | 'lifetime'
+  | 'this_week'
+  | 'this_month'
+  | 'this_year'
+
+// A stats window as reported by the backend (RFC 3339, in the stats timezone).
+export interface StatsWindow {
+  from: string
+  to: string
+}
+
+// Custom stats range: YYYY-MM-DD dates (to is inclusive) or RFC 3339 times.
+export interface StatsRangeParams {
+  from: string
+  to?: string
+  compare_from?: string
+  compare_to?: string
+}
 
 export interface StatValue {
   value: number
@@ -385,7 +440,11 @@ export interface StatValue {
 }
 
 export interface StatsOverview {
-  period: StatsPeriod
+  period: StatsPeriod | 'custom'
+  timezone: string
+  range: StatsWindow
+  compare: StatsWindow | null
+  min_completion?: number // set when only real listens are counted
   stats: {
     streams: StatValue
     minutes: StatValue
@@ -421,14 +480,22 @@ export interface MyTopItem {
   image_url?: string
   play_count: number
   total_ms: number
+  avg_completion?: number // tracks only, 0-1
+  skip_rate?: number // tracks only, 0-1
 }
 
 export interface MyTopResponse {
   type: string
-  time_range: string
+  time_range?: string
+  period?: StatsPeriod | 'custom'
+  range: StatsWindow
+  min_completion?: number // set when only real listens are counted
   items: MyTopItem[]
 }
 
+// 'real' counts only plays with enough of the track played (LISTEN_THRESHOLD).
+export type ListenFilter = 'all' | 'real'
+
 export interface ClockHour {
   hour: number
   streams: number
@@ -436,14 +503,119 @@ export interface ClockHour {
 }
 
 export interface ListeningClock {
+  timezone: string
   hours: ClockHour[]
 }
 
+export interface CalendarDay {
+  date: string // YYYY-MM-DD
+  streams: number
+  minutes: number
+}
+
+export interface CalendarStreak {
+  days: number
+  from?: string
+  to?: string
+}
+
+export interface ListeningCalendar {
+  timezone: string
+  range: StatsWindow
+  days: CalendarDay[]
+  summary: {
+    streams: number
+    minutes: number
+    active_days: number
+    longest_streak: CalendarStreak
+    current_streak: CalendarStreak
+    most_active_day: CalendarDay | null
+  }
+}
+
+export interface WeekdayHours {
+  weekday: number // ISO: 1 = Monday ... 7 = Sunday
+  name: string
+  streams: number
+  minutes: number
+  hours?: ClockHour[]
+}
+
+export interface DayTypeTotals {
+  streams: number
+  minutes: number
+  avg_daily_streams: number
+  avg_daily_minutes: number
+}
+
+export interface WeekdayActivity {
+  period: StatsPeriod | 'custom'
+  timezone: string
+  range: StatsWindow
+  days: WeekdayHours[]
+  summary: {
+    most_active_weekday: WeekdayHours | null
+    most_active_hour: { weekday: number; name: string; hour: number; streams: number; minutes: number } | null
+    workweek: DayTypeTotals
+    weekend: DayTypeTotals
+  }
+}
+
+export interface ListeningSession {
+  id: number
+  started_at: string
+  ended_at: string
+  minutes: number // start to end, pauses included
+  listened_minutes: number
+  track_count: number
+  dominant_artist: { id: string; name: string } | null
+  dominant_genre: string
+}
+
+export interface ListeningSessionsPage {
+  items: ListeningSession[]
+  total: number
+  page: number
+  limit: number
+  period: StatsPeriod | 'custom'
+  timezone: string
+  range: StatsWindow
+}
+
+export interface SessionStats {
+  period: StatsPeriod | 'custom'
+  timezone: string
+  range: StatsWindow
+  gap_minutes: number
+  sessions: number
+  avg_minutes: number
+  avg_tracks: number
+  sessions_per_day: number
+  total_minutes: number
+  longest_session: ListeningSession | null
+  by_length: { label: string; min_minutes: number; max_minutes?: number; sessions: number }[]
+  by_start_hour: { hour: number; sessions: number }[]
+}
+
 // --- Stats fetch functions ---
 
-export async function getStatsOverview(period: StatsPeriod = 'week'): Promise<StatsOverview> {
-  const res = await fetch(`/api/stats/overview?period=${period}`)
-  if (!res.ok) throw new Error('Failed to fetch stats overview')
+// Passing range overrides period; its compare_* dates replace the default
+// comparison with the preceding window.
+export async function getStatsOverview(
+  period: StatsPeriod = 'week',
+  range?: StatsRangeParams,
+  listens: ListenFilter = 'all',
+): Promise<StatsOverview> {
+  const params = new URLSearchParams({ period })
+  Object.entries(range ?? {}).forEach(([k, v]) => {
+    if (v) params.set(k, v)
+  })
+  if (listens === 'real') params.set('real', 'true')
+  const res = await fetch(`/api/stats/overview?${params}`)
+  if (!res.ok) {
+    const data = await res.json().catch(() => null)
+    throw new Error(data?.error || 'Failed to fetch stats overview')
+  }
   return res.json()
 }
 
@@ -459,18 +631,55 @@ export async function getSpotifyTop(
   return res.json()
 }
 
+// range (a stats period or from/to dates) takes precedence over timeRange.
 export async function getMyTop(
   type: MyTopType,
   timeRange: TimeRange = 'medium_term',
   limit = 50,
+  range?: { period?: StatsPeriod; from?: string; to?: string },
+  listens: ListenFilter = 'all',
 ): Promise<MyTopResponse> {
-  const res = await fetch(`/api/stats/my-top?type=${type}&time_range=${timeRange}&limit=${limit}`, {
+  const params = new URLSearchParams({ type, time_range: timeRange, limit: String(limit) })
+  Object.entries(range ?? {}).forEach(([k, v]) => {
+    if (v) params.set(k, v)
+  })
+  if (listens === 'real') params.set('real', 'true')
+  const res = await fetch(`/api/stats/my-top?${params}`, {
     credentials: 'include',
   })
   if (!res.ok) throw new Error('Failed to fetch my top')
   return res.json()
 }
 
+// Per-day counts for a calendar year, or the year up to today by default.
+export async function getListeningCalendar(year?: number): Promise<ListeningCalendar> {
+  const res = await fetch(`/api/stats/calendar${year ? `?year=${year}` : ''}`)
+  if (!res.ok) throw new Error('Failed to fetch listening calendar')
+  return res.json()
+}
+
+export async function getWeekdayActivity(period: StatsPeriod = 'lifetime'): Promise<WeekdayActivity> {
+  const res = await fetch(`/api/stats/weekday-hours?period=${period}`)
+  if (!res.ok) throw new Error('Failed to fetch weekday activity')
+  return res.json()
+}
+
+export async function getSessionStats(period: StatsPeriod = 'month'): Promise<SessionStats> {
+  const res = await fetch(`/api/stats/sessions?period=${period}`)
+  if (!res.ok) throw new Error('Failed to fetch session stats')
+  return res.json()
+}
+
+export async function getListeningSessions(
+  period: StatsPeriod = 'lifetime',
+  page = 1,
+  limit = 20,
+): Promise<ListeningSessionsPage> {
+  const res = await fetch(`/api/listening-sessions?period=${period}&page=${page}&limit=${limit}`)
+  if (!res.ok) throw new Error('Failed to fetch listening sessions')
+  return res.json()
+}
+
 export async function getListeningClock(): Promise<ListeningClock> {
   const res = await fetch('/api/stats/clock')
   if (!res.ok) throw new Error('Failed to fetch listening clock')
@@ -519,32 +728,64 @@ export class RateLimitError extends Error {
   }
 }
 
-export async function getSmartRecommendations(): Promise<RecommendationResponse> {
-  const res = await fetch('/api/recommendations/smart', { method: 'POST' })
-  if (!res.ok) {
+// Events from the streaming recommendation endpoints, in the order they first
+// arrive. recommendation events can come out of order; index is the item's
+// position in the model's list. saved and error are final.
+export type RecommendationStreamEvent =
+  | { event: 'profile'; data: { top_artists: number; top_tracks: number; recent_plays: number; high_rated: number; top_genres: string[] } }
+  | { event: 'token'; data: { text: string } }
+  | { event: 'summary'; data: { taste_summary: string } }
+  | { event: 'recommendation'; data: { index: number; recommendation: ResolvedRecommendation } }
+  | { event: 'dropped'; data: { index: number; reason: string; recommendation: ResolvedRecommendation } }
+  | { event: 'saved'; data: { id: number } }
+  | { event: 'error'; data: { error: string } }
+
+// Streams recommendations over Server-Sent Events, calling onEvent for each
+// event. The endpoints are POST-only, so the stream is read with fetch rather
+// than EventSource. Resolves when the stream ends; rate limits and other
+// errors before the stream starts are thrown like the blocking calls.
+export async function streamRecommendations(
+  mode: 'smart' | 'prompt',
+  prompt: string,
+  onEvent: (ev: RecommendationStreamEvent) => void,
+  signal?: AbortSignal,
+): Promise<void> {
+  const res = await fetch(`/api/recommendations/${mode}/stream`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json', Accept: 'text/event-stream' },
+    body: JSON.stringify(mode === 'prompt' ? { prompt } : {}),
+    signal,
+  })
+  if (!res.ok || !res.body) {
     const data = await res.json().catch(() => ({}))
     if (data.retry_after) {
       throw new RateLimitError(data.error || 'Rate limit reached', data.retry_after)
     }
     throw new Error(data.error || 'Failed to get recommendations')
   }
-  return res.json()
-}
 
-export async function getPromptRecommendations(prompt: string): Promise<RecommendationResponse> {
-  const res = await fetch('/api/recommendations/prompt', {
-    method: 'POST',
-    headers: { 'Content-Type': 'application/json' },
-    body: JSON.stringify({ prompt }),
-  })
-  if (!res.ok) {
-    const data = await res.json().catch(() => ({}))
-    if (data.retry_after) {
-      throw new RateLimitError(data.error || 'Rate limit reached', data.retry_after)
+  const reader = res.body.pipeThrough(new TextDecoderStream()).getReader()
+  let buffer = ''
+  for (;;) {
+    const { value, done } = await reader.read()
+    if (done) break
+    buffer += value
+    // Events are separated by a blank line.
+    let end: number
+    while ((end = buffer.indexOf('\n\n')) >= 0) {
+      const block = buffer.slice(0, end)
+      buffer = buffer.slice(end + 2)
+      let event = 'message'
+      const data: string[] = []
+      for (const line of block.split('\n')) {
+        if (line.startsWith('event:')) event = line.slice(6).trim()
+        else if (line.startsWith('data:')) data.push(line.slice(5).replace(/^ /, ''))
+      }
+      if (data.length > 0) {
+        onEvent({ event, data: JSON.parse(data.join('\n')) } as RecommendationStreamEvent)
+      }
     }
-    throw new Error(data.error || 'Failed to get recommendations')
   }
-  return res.json()
 }
 
 export async function getRecommendationHistory(): Promise<RecommendationHistoryItem[]> {
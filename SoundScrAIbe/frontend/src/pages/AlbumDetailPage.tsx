@@ -86,8 +86,6 @@ export default function AlbumDetailPage() {
         <RatingShelfTags
           entityType="album"
           entityId={album.id}
-          entityName={album.name}
-          entityImageUrl={coverImage}
           initialRating={album.rating}
           initialShelf={album.shelf}
           initialTags={album.tags}
@@ -85,8 +85,6 @@ export default function ArtistDetailPage() {
         <RatingShelfTags
           entityType="artist"
           entityId={artist.id}
-          entityName={artist.name}
-          entityImageUrl={artistImage}
           initialRating={artist.rating}
           initialShelf={artist.shelf}
           initialTags={artist.tags}
@@ -4,10 +4,10 @@ import PageShell from '../components/PageShell'
 import PillGroup from '../components/PillGroup'
 import RecommendCard from '../components/RecommendCard'
 import {
-  getSmartRecommendations,
-  getPromptRecommendations,
+  streamRecommendations,
   RateLimitError,
   type RecommendationResponse,
+  type ResolvedRecommendation,
 } from '../lib/api'
 
 type Mode = 'smart' | 'prompt'
@@ -76,13 +76,11 @@ export default function DiscoverPage() {
   const [error, setError] = useState<string | null>(null)
   const [cooldown, setCooldown] = useState(0)
   const [maxCooldown, setMaxCooldown] = useState(0)
-  const timersRef = useRef<ReturnType<typeof setTimeout>[]>([])
+  const abortRef = useRef<AbortController | null>(null)
 
-  // Clean up timers on unmount
+  // Stop a running stream on unmount
   useEffect(() => {
-    return () => {
-      timersRef.current.forEach(clearTimeout)
-    }
+    return () => abortRef.current?.abort()
   }, [])
 
   // Cooldown countdown timer
@@ -100,27 +98,6 @@ export default function DiscoverPage() {
     return () => clearInterval(id)
   }, [cooldown > 0])
 
-  const startLoading = () => {
-    setLoading(true)
-    setLoadingStep(0)
-    setError(null)
-    setResult(null)
-
-    // Clear previous timers
-    timersRef.current.forEach(clearTimeout)
-    timersRef.current = []
-
-    const t1 = setTimeout(() => setLoadingStep(1), 2000)
-    const t2 = setTimeout(() => setLoadingStep(2), 6000)
-    timersRef.current = [t1, t2]
-  }
-
-  const stopLoading = () => {
-    timersRef.current.forEach(clearTimeout)
-    timersRef.current = []
-    setLoading(false)
-  }
-
   const extractRetryAfter = (err: unknown): number | null => {
     if (err instanceof RateLimitError) {
       return err.retryAfter
@@ -128,12 +105,66 @@ export default function DiscoverPage() {
     return null
   }
 
-  const handleSmartAnalyse = async () => {
-    startLoading()
+  // Runs a streaming request, advancing the loading steps as the pipeline
+  // reports progress and showing each recommendation as soon as it's resolved.
+  const runRecommendations = async (runMode: Mode, text: string) => {
+    abortRef.current?.abort()
+    const controller = new AbortController()
+    abortRef.current = controller
+
+    setLoading(true)
+    setLoadingStep(0)
+    setError(null)
+    setResult(null)
+
+    // Keyed by the item's position in the model's list, since lookups finish out of order.
+    const items = new Map<number, ResolvedRecommendation>()
+    let summary = ''
+    let finished = false
+    const publish = () =>
+      setResult({
+        taste_summary: summary,
+        recommendations: [...items.entries()].sort(([a], [b]) => a - b).map(([, rec]) => rec),
+        mode: runMode,
+        user_prompt: runMode === 'prompt' ? text : undefined,
+      })
+
     try {
-      const data = await getSmartRecommendations()
-      setResult(data)
+      await streamRecommendations(
+        runMode,
+        text,
+        (ev) => {
+          switch (ev.event) {
+            case 'profile':
+              setLoadingStep(1)
+              break
+            case 'summary':
+              summary = ev.data.taste_summary
+              setLoadingStep(2)
+              break
+            case 'recommendation':
+              items.set(ev.data.index, ev.data.recommendation)
+              publish()
+              break
+            case 'dropped':
+              items.delete(ev.data.index)
+              publish()
+              break
+            case 'saved':
+              finished = true
+              publish()
+              break
+            case 'error':
+              finished = true
+              setError(ev.data.error)
+              break
+          }
+        },
+        controller.signal,
+      )
+      if (!finished) setError('The connection was lost before all recommendations arrived')
     } catch (err) {
+      if (controller.signal.aborted) return
       const retryAfter = extractRetryAfter(err)
       if (retryAfter !== null) {
         setCooldown(retryAfter)
@@ -142,27 +173,18 @@ export default function DiscoverPage() {
         setError(err instanceof Error ? err.message : 'Something went wrong')
       }
     } finally {
-      stopLoading()
+      if (abortRef.current === controller) {
+        abortRef.current = null
+        setLoading(false)
+      }
     }
   }
 
-  const handlePromptSubmit = async () => {
+  const handleSmartAnalyse = () => runRecommendations('smart', '')
+
+  const handlePromptSubmit = () => {
     if (!prompt.trim()) return
-    startLoading()
-    try {
-      const data = await getPromptRecommendations(prompt.trim())
-      setResult(data)
-    } catch (err) {
-      const retryAfter = extractRetryAfter(err)
-      if (retryAfter !== null) {
-        setCooldown(retryAfter)
-        setMaxCooldown(retryAfter)
-      } else {
-        setError(err instanceof Error ? err.message : 'Something went wrong')
-      }
-    } finally {
-      stopLoading()
-    }
+    runRecommendations('prompt', prompt.trim())
   }
 
   const handleReset = () => {
@@ -186,8 +208,8 @@ export default function DiscoverPage() {
         </div>
       )}
 
-      {/* Loading state */}
-      {loading && <LoadingAnimation step={loadingStep} />}
+      {/* Loading state, until the first recommendation arrives */}
+      {loading && !result && <LoadingAnimation step={loadingStep} />}
 
       {/* Cooldown state */}
       {!loading && cooldown > 0 && (
@@ -223,8 +245,8 @@ export default function DiscoverPage() {
         </div>
       )}
 
-      {/* Results state */}
-      {!loading && result && (
+      {/* Results state, filled in as recommendations stream in */}
+      {result && !error && (
         <div>
           {/* Taste summary */}
           {result.taste_summary && (
@@ -240,21 +262,30 @@ export default function DiscoverPage() {
             ))}
           </div>
 
+          {loading && (
+            <div className="flex items-center gap-3 text-slate-400 text-sm mb-8">
+              <SpinnerIcon />
+              <span>Finding more on Spotify...</span>
+            </div>
+          )}
+
           {/* Actions */}
-          <div className="flex items-center justify-between">
-            <button
-              onClick={handleReset}
-              className="bg-indigo-600 hover:bg-indigo-500 text-white px-6 py-3 rounded-xl font-semibold transition-colors"
-            >
-              New Recommendations
-            </button>
-            <Link
-              to="/discover/history"
-              className="text-indigo-400 hover:text-indigo-300 hover:underline transition-colors text-sm"
-            >
-              View past recommendations
-            </Link>
-          </div>
+          {!loading && (
+            <div className="flex items-center justify-between">
+              <button
+                onClick={handleReset}
+                className="bg-indigo-600 hover:bg-indigo-500 text-white px-6 py-3 rounded-xl font-semibold transition-colors"
+              >
+                New Recommendations
+              </button>
+              <Link
+                to="/discover/history"
+                className="text-indigo-400 hover:text-indigo-300 hover:underline transition-colors text-sm"
+              >
+                View past recommendations
+              </Link>
+            </div>
+          )}
         </div>
       )}
 
@@ -47,7 +47,7 @@ const FEATURES = [
   },
 ]
 
-function LoggedOutHome() {
+function LoggedOutHome({ reauthRequired }: { reauthRequired: boolean }) {
   const handleLogin = async () => {
     const config = await getSpotifyAuthConfig()
     const codeVerifier = generateCodeVerifier()
@@ -65,7 +65,8 @@ function LoggedOutHome() {
       show_dialog: 'true',
     })
 
-    window.location.href = `https://accounts.spotify.com/authorize?${params.toString()}`
+    const authorizeUrl = config.authorize_url ?? 'https://accounts.spotify.com/authorize'
+    window.location.href = `${authorizeUrl}?${params.toString()}`
   }
 
   return (
@@ -91,6 +92,10 @@ function LoggedOutHome() {
           ))}
         </div>
 
+        {reauthRequired && (
+          <p className="text-amber-300 mb-4">Your Spotify connection has expired. Sign in again to continue.</p>
+        )}
+
         {/* CTA */}
         <button
           onClick={handleLogin}
@@ -104,10 +109,10 @@ function LoggedOutHome() {
 }
 
 export default function HomePage() {
-  const { isLoggedIn, loading } = useAuth()
+  const { isLoggedIn, loading, reauthRequired } = useAuth()
 
   if (loading) return <LoadingState />
 
   if (isLoggedIn) return <Navigate to="/dashboard" replace />
-  return <LoggedOutHome />
+  return <LoggedOutHome reauthRequired={reauthRequired} />
 }
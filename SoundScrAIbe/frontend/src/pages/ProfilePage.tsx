@@ -1,13 +1,36 @@
+import { useState } from 'react'
 import { Link } from 'react-router-dom'
 import { useAuth } from '../context/AuthContext'
+import { setTimezone } from '../lib/api'
 import LoadingState from '../components/LoadingState'
 import PageShell from '../components/PageShell'
 
+const TIMEZONES = Intl.supportedValuesOf('timeZone')
+const BROWSER_TIMEZONE = Intl.DateTimeFormat().resolvedOptions().timeZone
+
 export default function ProfilePage() {
-  const { user } = useAuth()
+  const { user, checkAuth } = useAuth()
+  const [savingTimezone, setSavingTimezone] = useState(false)
+  const [timezoneError, setTimezoneError] = useState<string | null>(null)
 
   if (!user) return <LoadingState />
 
+  const handleTimezone = async (timezone: string) => {
+    setSavingTimezone(true)
+    setTimezoneError(null)
+    try {
+      await setTimezone(timezone)
+      await checkAuth()
+    } catch (err) {
+      setTimezoneError(err instanceof Error ? err.message : 'Failed to save timezone')
+    } finally {
+      setSavingTimezone(false)
+    }
+  }
+
+  // The stored zone may be missing from the browser's list (e.g. "UTC").
+  const timezoneOptions = TIMEZONES.includes(user.timezone) ? TIMEZONES : [user.timezone, ...TIMEZONES]
+
   return (
     <PageShell narrow>
       {/* Hero */}
@@ -72,7 +95,33 @@ export default function ProfilePage() {
           <span>{user.email}</span>
           <span className="text-slate-400">Spotify ID</span>
           <span className="font-mono text-slate-300">{user.spotify_id}</span>
+          <span className="text-slate-400">Timezone</span>
+          <div className="flex flex-wrap items-center gap-3">
+            <select
+              value={user.timezone}
+              disabled={savingTimezone}
+              onChange={(e) => handleTimezone(e.target.value)}
+              className="bg-slate-800 text-slate-300 text-sm rounded-lg px-3 py-1.5 border border-slate-700 focus:outline-none focus:border-indigo-500"
+            >
+              {timezoneOptions.map((tz) => (
+                <option key={tz} value={tz}>
+                  {tz}
+                </option>
+              ))}
+            </select>
+            {BROWSER_TIMEZONE && BROWSER_TIMEZONE !== user.timezone && (
+              <button
+                onClick={() => handleTimezone(BROWSER_TIMEZONE)}
+                disabled={savingTimezone}
+                className="text-indigo-400 hover:text-indigo-300 hover:underline transition-colors"
+              >
+                Use {BROWSER_TIMEZONE}
+              </button>
+            )}
+            {timezoneError && <span className="text-red-400">{timezoneError}</span>}
+          </div>
         </div>
+        <p className="text-xs text-slate-500 mt-4">Stats days and the listening clock follow this timezone.</p>
       </div>
     </PageShell>
   )
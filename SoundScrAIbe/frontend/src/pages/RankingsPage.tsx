@@ -14,6 +14,7 @@ import {
   type SpotifyTopItem,
   type MyTopItem,
   type TimeRange,
+  type ListenFilter,
 } from '../lib/api'
 
 // --- Constants ---
@@ -37,6 +38,11 @@ const MY_TYPE_OPTIONS = [
   { value: 'genres' as const, label: 'Genres' },
 ]
 
+const LISTEN_FILTER_OPTIONS: { value: ListenFilter; label: string }[] = [
+  { value: 'all', label: 'All plays' },
+  { value: 'real', label: 'Real listens' },
+]
+
 const TIME_RANGE_OPTIONS: { value: TimeRange; label: string }[] = [
   { value: 'short_term', label: '4 weeks' },
   { value: 'medium_term', label: '6 months' },
@@ -145,6 +151,7 @@ function MyListeningSection() {
   const navigate = useNavigate()
   const [type, setType] = useState<MyTopType>('tracks')
   const [timeRange, setTimeRange] = useState<TimeRange>('medium_term')
+  const [listens, setListens] = useState<ListenFilter>('all')
   const [data, setData] = useState<MyTopResponse | null>(null)
   const [loading, setLoading] = useState(true)
   const [error, setError] = useState('')
@@ -152,11 +159,11 @@ function MyListeningSection() {
   useEffect(() => {
     setLoading(true)
     setError('')
-    getMyTop(type, timeRange)
+    getMyTop(type, timeRange, 50, undefined, listens)
       .then(setData)
       .catch(() => setError('Failed to load listening data'))
       .finally(() => setLoading(false))
-  }, [type, timeRange])
+  }, [type, timeRange, listens])
 
   const items = data?.items ?? []
   const isGenre = type === 'genres'
@@ -177,7 +184,10 @@ function MyListeningSection() {
 
       <div className="flex flex-wrap items-center justify-between gap-2 mb-4">
         <PillGroup options={MY_TYPE_OPTIONS} value={type} onChange={setType} size="sm" />
-        <PillGroup options={TIME_RANGE_OPTIONS} value={timeRange} onChange={setTimeRange} size="sm" />
+        <div className="flex flex-wrap items-center gap-2">
+          <PillGroup options={LISTEN_FILTER_OPTIONS} value={listens} onChange={setListens} size="sm" />
+          <PillGroup options={TIME_RANGE_OPTIONS} value={timeRange} onChange={setTimeRange} size="sm" />
+        </div>
       </div>
 
       {loading ? (
@@ -233,6 +243,11 @@ function MyListeningSection() {
                   {item.play_count} plays
                   <span className="mx-1">&middot;</span>
                   {formatMs(item.total_ms)}
+                  {item.skip_rate !== undefined && (
+                    <p className="text-xs text-slate-500" title="Plays with less than the listen threshold played">
+                      {Math.round(item.skip_rate * 100)}% skipped
+                    </p>
+                  )}
                 </div>
               </div>
             )
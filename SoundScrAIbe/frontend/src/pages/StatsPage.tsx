@@ -6,8 +6,18 @@ import { formatNumber } from '../lib/format'
 import {
   getStatsOverview,
   getListeningClock,
+  getListeningCalendar,
+  getWeekdayActivity,
+  getSessionStats,
+  getListeningSessions,
+  type ListeningSession,
+  type SessionStats,
+  type ListeningCalendar,
+  type WeekdayActivity,
   type StatsOverview,
   type StatsPeriod,
+  type StatsRangeParams,
+  type ListenFilter,
   type ListeningClock,
 } from '../lib/api'
 import {
@@ -34,14 +44,33 @@ function ChangeBadge({ pct }: { pct: number | null }) {
 
 // --- Constants ---
 
-const PERIOD_OPTIONS: { value: StatsPeriod; label: string }[] = [
+type PeriodOption = StatsPeriod | 'custom'
+
+const PERIOD_OPTIONS: { value: PeriodOption; label: string }[] = [
   { value: 'day', label: 'Day' },
   { value: 'week', label: 'Week' },
   { value: 'month', label: 'Month' },
   { value: 'year', label: 'Year' },
   { value: 'lifetime', label: 'Lifetime' },
+  { value: 'this_week', label: 'This week' },
+  { value: 'this_month', label: 'This month' },
+  { value: 'this_year', label: 'Year to date' },
+  { value: 'custom', label: 'Custom' },
+]
+
+const LISTEN_FILTER_OPTIONS: { value: ListenFilter; label: string }[] = [
+  { value: 'all', label: 'All plays' },
+  { value: 'real', label: 'Real listens' },
 ]
 
+const DATE_INPUT_CLASS =
+  'bg-slate-800 text-slate-300 text-sm rounded-lg px-3 py-1.5 border border-slate-700 focus:outline-none focus:border-indigo-500'
+
+// formatWindowDate shows an RFC 3339 bound from the backend as a date.
+function formatWindowDate(iso: string): string {
+  return new Date(iso).toLocaleDateString(undefined, { year: 'numeric', month: 'short', day: 'numeric' })
+}
+
 const STAT_LABELS: { key: keyof StatsOverview['stats']; label: string }[] = [
   { key: 'streams', label: 'streams' },
   { key: 'minutes', label: 'minutes streamed' },
@@ -58,17 +87,27 @@ function OverviewSection() {
   const [data, setData] = useState<StatsOverview | null>(null)
   const [loading, setLoading] = useState(true)
   const [error, setError] = useState('')
-  const [period, setPeriod] = useState<StatsPeriod>('week')
+  const [period, setPeriod] = useState<PeriodOption>('week')
+  const [range, setRange] = useState<StatsRangeParams>({ from: '', to: '', compare_from: '', compare_to: '' })
+  const [listens, setListens] = useState<ListenFilter>('all')
+
+  const isCustom = period === 'custom'
+  const customReady = !!range.from && !!range.to
 
   useEffect(() => {
     if (!isLoggedIn) return
+    if (isCustom && !customReady) return
     setLoading(true)
     setError('')
-    getStatsOverview(period)
+    const request = isCustom ? getStatsOverview('week', range, listens) : getStatsOverview(period, undefined, listens)
+    request
       .then(setData)
-      .catch(() => setError('Failed to load stats overview'))
+      .catch((err) => setError(err instanceof Error ? err.message : 'Failed to load stats overview'))
       .finally(() => setLoading(false))
-  }, [isLoggedIn, period])
+  }, [isLoggedIn, period, isCustom, customReady, range, listens])
+
+  const setRangeField = (key: keyof StatsRangeParams) => (e: React.ChangeEvent<HTMLInputElement>) =>
+    setRange((r) => ({ ...r, [key]: e.target.value }))
 
   const allZero = data
     ? STAT_LABELS.every((s) => data.stats[s.key].value === 0)
@@ -77,11 +116,47 @@ function OverviewSection() {
   return (
     <section>
       {/* Period tabs */}
-      <div className="mb-6">
+      <div className="flex flex-wrap items-center justify-between gap-3 mb-6">
         <PillGroup options={PERIOD_OPTIONS} value={period} onChange={setPeriod} size="md" />
+        <PillGroup options={LISTEN_FILTER_OPTIONS} value={listens} onChange={setListens} size="sm" />
       </div>
 
-      {loading ? (
+      {isCustom && (
+        <div className="flex flex-wrap items-center gap-3 mb-6 text-sm text-slate-400">
+          <input type="date" value={range.from} onChange={setRangeField('from')} className={DATE_INPUT_CLASS} />
+          <span>to</span>
+          <input type="date" value={range.to} onChange={setRangeField('to')} className={DATE_INPUT_CLASS} />
+          <span className="ml-2">compared with</span>
+          <input
+            type="date"
+            value={range.compare_from}
+            onChange={setRangeField('compare_from')}
+            className={DATE_INPUT_CLASS}
+          />
+          <span>to</span>
+          <input
+            type="date"
+            value={range.compare_to}
+            onChange={setRangeField('compare_to')}
+            className={DATE_INPUT_CLASS}
+          />
+        </div>
+      )}
+
+      {data && !loading && !error && (
+        <p className="text-xs text-slate-500 mb-4">
+          {formatWindowDate(data.range.from)} – {formatWindowDate(data.range.to)}
+          {data.compare && (
+            <>
+              {' '}vs {formatWindowDate(data.compare.from)} – {formatWindowDate(data.compare.to)}
+            </>
+          )}
+        </p>
+      )}
+
+      {isCustom && !customReady ? (
+        <p className="text-slate-400 text-center py-8">Pick a start and end date</p>
+      ) : loading ? (
         <p className="text-slate-400 text-center py-8">Loading...</p>
       ) : error ? (
         <p className="text-red-400 text-center py-8">{error}</p>
@@ -194,6 +269,347 @@ function ClockSection() {
   )
 }
 
+// Heat levels shared by the calendar and the weekday/hour grid.
+const HEAT_CLASSES = ['bg-slate-800', 'bg-indigo-900', 'bg-indigo-700', 'bg-indigo-500', 'bg-indigo-400']
+
+function heatClass(value: number, max: number): string {
+  if (value <= 0 || max <= 0) return HEAT_CLASSES[0]
+  return HEAT_CLASSES[Math.min(4, Math.ceil((value / max) * 4))]
+}
+
+// formatDay shows a YYYY-MM-DD date without shifting it through the browser timezone.
+function formatDay(date: string): string {
+  return new Date(`${date}T00:00:00Z`).toLocaleDateString(undefined, {
+    month: 'short',
+    day: 'numeric',
+    year: 'numeric',
+    timeZone: 'UTC',
+  })
+}
+
+function SummaryCard({ value, label }: { value: string; label: string }) {
+  return (
+    <div className="bg-slate-900 rounded-xl p-4">
+      <p className="text-xl font-bold text-indigo-400">{value}</p>
+      <p className="text-sm text-slate-400 mt-1">{label}</p>
+    </div>
+  )
+}
+
+function CalendarSection() {
+  const { isLoggedIn } = useAuth()
+  const [data, setData] = useState<ListeningCalendar | null>(null)
+  const [loading, setLoading] = useState(true)
+  const [error, setError] = useState('')
+  const thisYear = new Date().getFullYear()
+  const yearOptions = [
+    { value: 'recent', label: 'Past year' },
+    ...[thisYear, thisYear - 1, thisYear - 2].map((y) => ({ value: String(y), label: String(y) })),
+  ]
+  const [year, setYear] = useState('recent')
+
+  useEffect(() => {
+    if (!isLoggedIn) return
+    setLoading(true)
+    setError('')
+    getListeningCalendar(year === 'recent' ? undefined : Number(year))
+      .then(setData)
+      .catch(() => setError('Failed to load listening calendar'))
+      .finally(() => setLoading(false))
+  }, [isLoggedIn, year])
+
+  // Columns are weeks starting on Monday; the first column is padded so
+  // every day lands in its weekday row.
+  const weeks: ({ date: string; streams: number; minutes: number } | null)[][] = []
+  if (data) {
+    const firstWeekday = (new Date(`${data.days[0]?.date}T00:00:00Z`).getUTCDay() + 6) % 7
+    const cells = [...Array(firstWeekday).fill(null), ...data.days]
+    for (let i = 0; i < cells.length; i += 7) weeks.push(cells.slice(i, i + 7))
+  }
+  const maxStreams = data ? Math.max(0, ...data.days.map((d) => d.streams)) : 0
+  const summary = data?.summary
+
+  return (
+    <section className="mt-8">
+      <div className="flex flex-wrap items-center justify-between gap-3 mb-4">
+        <h3 className="text-xl font-bold text-white">Listening Calendar</h3>
+        <PillGroup options={yearOptions} value={year} onChange={setYear} size="sm" />
+      </div>
+
+      {loading ? (
+        <p className="text-slate-400 text-center py-8">Loading...</p>
+      ) : error ? (
+        <p className="text-red-400 text-center py-8">{error}</p>
+      ) : data && summary ? (
+        <>
+          <div className="bg-slate-900 rounded-xl p-4 overflow-x-auto mb-4">
+            <div className="flex gap-[3px] w-max">
+              {weeks.map((week, wi) => (
+                <div key={wi} className="flex flex-col gap-[3px]">
+                  {week.map((day, di) =>
+                    day ? (
+                      <div
+                        key={day.date}
+                        title={`${formatDay(day.date)}: ${day.streams} streams, ${Math.round(day.minutes)} min`}
+                        className={`w-3 h-3 rounded-sm ${heatClass(day.streams, maxStreams)}`}
+                      />
+                    ) : (
+                      <div key={`pad-${di}`} className="w-3 h-3" />
+                    ),
+                  )}
+                </div>
+              ))}
+            </div>
+          </div>
+          <div className="grid grid-cols-2 sm:grid-cols-4 gap-4">
+            <SummaryCard value={formatNumber(summary.active_days)} label="active days" />
+            <SummaryCard value={`${summary.longest_streak.days} days`} label="longest streak" />
+            <SummaryCard value={`${summary.current_streak.days} days`} label="current streak" />
+            <SummaryCard
+              value={summary.most_active_day ? formatDay(summary.most_active_day.date) : '—'}
+              label={
+                summary.most_active_day
+                  ? `most active day (${summary.most_active_day.streams} streams)`
+                  : 'most active day'
+              }
+            />
+          </div>
+        </>
+      ) : null}
+    </section>
+  )
+}
+
+const WEEKDAY_PERIOD_OPTIONS: { value: StatsPeriod; label: string }[] = [
+  { value: 'month', label: 'Month' },
+  { value: 'year', label: 'Year' },
+  { value: 'lifetime', label: 'Lifetime' },
+]
+
+function WeekdaySection() {
+  const { isLoggedIn } = useAuth()
+  const [data, setData] = useState<WeekdayActivity | null>(null)
+  const [loading, setLoading] = useState(true)
+  const [error, setError] = useState('')
+  const [period, setPeriod] = useState<StatsPeriod>('lifetime')
+
+  useEffect(() => {
+    if (!isLoggedIn) return
+    setLoading(true)
+    setError('')
+    getWeekdayActivity(period)
+      .then(setData)
+      .catch(() => setError('Failed to load weekday activity'))
+      .finally(() => setLoading(false))
+  }, [isLoggedIn, period])
+
+  const maxStreams = data
+    ? Math.max(0, ...data.days.flatMap((d) => (d.hours ?? []).map((h) => h.streams)))
+    : 0
+  const summary = data?.summary
+
+  return (
+    <section className="mt-8">
+      <div className="flex flex-wrap items-center justify-between gap-3 mb-4">
+        <h3 className="text-xl font-bold text-white">Week at a Glance</h3>
+        <PillGroup options={WEEKDAY_PERIOD_OPTIONS} value={period} onChange={setPeriod} size="sm" />
+      </div>
+
+      {loading ? (
+        <p className="text-slate-400 text-center py-8">Loading...</p>
+      ) : error ? (
+        <p className="text-red-400 text-center py-8">{error}</p>
+      ) : data && summary ? (
+        <>
+          <div className="bg-slate-900 rounded-xl p-4 overflow-x-auto mb-4">
+            <div className="grid grid-cols-[auto_repeat(24,minmax(0.75rem,1fr))] gap-[3px] min-w-[32rem] items-center">
+              <span />
+              {Array.from({ length: 24 }, (_, h) => (
+                <span key={h} className="text-[10px] text-slate-500 text-center">
+                  {h % 3 === 0 ? h : ''}
+                </span>
+              ))}
+              {data.days.map((day) => (
+                <div key={day.weekday} className="contents">
+                  <span className="text-xs text-slate-400 pr-2">{day.name.slice(0, 3)}</span>
+                  {(day.hours ?? []).map((h) => (
+                    <div
+                      key={h.hour}
+                      title={`${day.name} ${h.hour}:00: ${h.streams} streams, ${Math.round(h.minutes)} min`}
+                      className={`h-4 rounded-sm ${heatClass(h.streams, maxStreams)}`}
+                    />
+                  ))}
+                </div>
+              ))}
+            </div>
+          </div>
+          <div className="grid grid-cols-2 sm:grid-cols-4 gap-4">
+            <SummaryCard value={summary.most_active_weekday?.name ?? '—'} label="most active weekday" />
+            <SummaryCard
+              value={summary.most_active_hour ? `${summary.most_active_hour.name.slice(0, 3)} ${summary.most_active_hour.hour}:00` : '—'}
+              label="busiest hour"
+            />
+            <SummaryCard
+              value={`${formatNumber(Math.round(summary.workweek.avg_daily_minutes))} min`}
+              label="per work day"
+            />
+            <SummaryCard
+              value={`${formatNumber(Math.round(summary.weekend.avg_daily_minutes))} min`}
+              label="per weekend day"
+            />
+          </div>
+        </>
+      ) : null}
+    </section>
+  )
+}
+
+const SESSION_PERIOD_OPTIONS: { value: StatsPeriod; label: string }[] = [
+  { value: 'week', label: 'Week' },
+  { value: 'month', label: 'Month' },
+  { value: 'year', label: 'Year' },
+  { value: 'lifetime', label: 'Lifetime' },
+]
+
+function formatDuration(minutes: number): string {
+  if (minutes < 60) return `${Math.round(minutes)} min`
+  const h = Math.floor(minutes / 60)
+  const m = Math.round(minutes % 60)
+  return m ? `${h}h ${m}m` : `${h}h`
+}
+
+function formatSessionStart(session: ListeningSession, timeZone: string): string {
+  return new Date(session.started_at).toLocaleString(undefined, {
+    weekday: 'short',
+    month: 'short',
+    day: 'numeric',
+    hour: 'numeric',
+    minute: '2-digit',
+    timeZone,
+  })
+}
+
+function SessionsSection() {
+  const { isLoggedIn } = useAuth()
+  const [stats, setStats] = useState<SessionStats | null>(null)
+  const [recent, setRecent] = useState<ListeningSession[]>([])
+  const [loading, setLoading] = useState(true)
+  const [error, setError] = useState('')
+  const [period, setPeriod] = useState<StatsPeriod>('month')
+
+  useEffect(() => {
+    if (!isLoggedIn) return
+    setLoading(true)
+    setError('')
+    Promise.all([getSessionStats(period), getListeningSessions(period, 1, 10)])
+      .then(([s, page]) => {
+        setStats(s)
+        setRecent(page.items)
+      })
+      .catch(() => setError('Failed to load listening sessions'))
+      .finally(() => setLoading(false))
+  }, [isLoggedIn, period])
+
+  const maxBucket = stats ? Math.max(0, ...stats.by_length.map((b) => b.sessions)) : 0
+
+  return (
+    <section className="mt-8">
+      <div className="flex flex-wrap items-center justify-between gap-3 mb-4">
+        <h3 className="text-xl font-bold text-white">Listening Sessions</h3>
+        <PillGroup options={SESSION_PERIOD_OPTIONS} value={period} onChange={setPeriod} size="sm" />
+      </div>
+
+      {loading ? (
+        <p className="text-slate-400 text-center py-8">Loading...</p>
+      ) : error ? (
+        <p className="text-red-400 text-center py-8">{error}</p>
+      ) : stats && stats.sessions === 0 ? (
+        <p className="text-slate-400 text-center py-8">No listening sessions in this period yet.</p>
+      ) : stats ? (
+        <>
+          <div className="grid grid-cols-2 sm:grid-cols-4 gap-4 mb-4">
+            <SummaryCard value={formatNumber(stats.sessions)} label="sessions" />
+            <SummaryCard value={formatDuration(stats.avg_minutes)} label="average session" />
+            <SummaryCard value={stats.sessions_per_day.toFixed(1)} label="sessions per day" />
+            <SummaryCard
+              value={stats.longest_session ? formatDuration(stats.longest_session.minutes) : '—'}
+              label={
+                stats.longest_session
+                  ? `longest (${formatSessionStart(stats.longest_session, stats.timezone)})`
+                  : 'longest session'
+              }
+            />
+          </div>
+
+          <div className="grid grid-cols-1 sm:grid-cols-2 gap-4">
+            <div className="bg-slate-900 rounded-xl p-4">
+              <p className="text-sm font-medium text-slate-400 mb-3">Session length</p>
+              <div className="space-y-2">
+                {stats.by_length.map((b) => (
+                  <div key={b.label} className="flex items-center gap-3 text-sm">
+                    <span className="w-24 shrink-0 text-slate-400">{b.label}</span>
+                    <div className="flex-1 h-3 bg-slate-800 rounded-full overflow-hidden">
+                      <div
+                        className="h-full bg-indigo-500 rounded-full"
+                        style={{ width: maxBucket ? `${(b.sessions / maxBucket) * 100}%` : 0 }}
+                      />
+                    </div>
+                    <span className="w-10 text-right text-slate-300">{b.sessions}</span>
+                  </div>
+                ))}
+              </div>
+              <p className="text-xs text-slate-500 mt-3">
+                A session ends after {stats.gap_minutes} minutes without music.
+              </p>
+            </div>
+
+            <div className="bg-slate-900 rounded-xl p-4">
+              <p className="text-sm font-medium text-slate-400 mb-3">Sessions by start hour</p>
+              <ResponsiveContainer width="100%" height={160}>
+                <BarChart data={stats.by_start_hour.map((h) => ({ ...h, hourLabel: `${h.hour}` }))}>
+                  <XAxis
+                    dataKey="hourLabel"
+                    tick={{ fill: '#9CA3AF', fontSize: 10 }}
+                    axisLine={false}
+                    tickLine={false}
+                    interval={2}
+                  />
+                  <YAxis hide />
+                  <Tooltip
+                    contentStyle={{ backgroundColor: '#1e293b', border: '1px solid #334155', borderRadius: '8px' }}
+                    labelFormatter={(label) => `${label}:00`}
+                    formatter={(value) => [`${value} sessions`, '']}
+                    cursor={{ fill: 'rgba(255,255,255,0.05)' }}
+                  />
+                  <Bar dataKey="sessions" fill="#6366F1" radius={[2, 2, 0, 0]} />
+                </BarChart>
+              </ResponsiveContainer>
+            </div>
+          </div>
+
+          {recent.length > 0 && (
+            <div className="bg-slate-900 rounded-xl p-4 mt-4">
+              <p className="text-sm font-medium text-slate-400 mb-3">Recent sessions</p>
+              <ul className="divide-y divide-slate-800">
+                {recent.map((s) => (
+                  <li key={s.id} className="flex flex-wrap items-center justify-between gap-2 py-2 text-sm">
+                    <span className="text-slate-300">{formatSessionStart(s, stats.timezone)}</span>
+                    <span className="text-slate-400">
+                      {formatDuration(s.minutes)} · {s.track_count} tracks
+                      {s.dominant_artist && ` · mostly ${s.dominant_artist.name}`}
+                      {s.dominant_genre && ` · ${s.dominant_genre}`}
+                    </span>
+                  </li>
+                ))}
+              </ul>
+            </div>
+          )}
+        </>
+      ) : null}
+    </section>
+  )
+}
+
 // --- Main Page ---
 
 export default function StatsPage() {
@@ -201,6 +617,9 @@ export default function StatsPage() {
     <PageShell title="Stats">
         <OverviewSection />
         <ClockSection />
+        <WeekdaySection />
+        <CalendarSection />
+        <SessionsSection />
     </PageShell>
   )
 }
@@ -217,6 +217,20 @@ export default function TrackDetailPage() {
                   <p className="text-slate-400 text-sm">last played</p>
                 </div>
               )}
+              {stats.skip_rate !== undefined && (
+                <div className="text-center">
+                  <p className="text-lg font-semibold">{Math.round(stats.skip_rate * 100)}%</p>
+                  <p className="text-slate-400 text-sm">
+                    skipped ({stats.skips} {stats.skips === 1 ? 'time' : 'times'})
+                  </p>
+                </div>
+              )}
+              {stats.avg_completion !== undefined && (
+                <div className="text-center">
+                  <p className="text-lg font-semibold">{Math.round(stats.avg_completion * 100)}%</p>
+                  <p className="text-slate-400 text-sm">played on average</p>
+                </div>
+              )}
             </div>
           </div>
         )}
@@ -225,8 +239,6 @@ export default function TrackDetailPage() {
         <RatingShelfTags
           entityType="track"
           entityId={track.id}
-          entityName={track.name}
-          entityImageUrl={track.album_cover}
           initialRating={track.rating}
           initialShelf={track.shelf}
           initialTags={track.tags}